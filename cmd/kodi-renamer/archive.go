@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+
+	"kodi-renamer/internal/renamer"
+	"kodi-renamer/internal/scanner"
+)
+
+// pendingArchiveMembers counts the videos of each archive set (by first volume) still to be
+// extracted, so a set is only deleted once all its videos are out
+var pendingArchiveMembers = make(map[string]int)
+
+// filterArchived keeps the videos found inside archive sets only when -extract-archives is set and
+// they can be extracted natively, reporting the others
+func filterArchived(files []*scanner.MediaFile) []*scanner.MediaFile {
+	kept := make([]*scanner.MediaFile, 0, len(files))
+	archived := 0
+
+	for _, file := range files {
+		if file.Archive == nil {
+			kept = append(kept, file)
+			continue
+		}
+		if !extractArchives {
+			archived++
+			continue
+		}
+		if !file.Archive.Entry.Extractable {
+			interactive.PrintWarning(fmt.Sprintf("Cannot extract %s from %s (compressed or encrypted RAR): extract it manually",
+				file.Archive.Entry.Name, file.Archive.Set.Volumes[0]))
+			continue
+		}
+		pendingArchiveMembers[file.Archive.Set.Volumes[0]]++
+		kept = append(kept, file)
+	}
+
+	if archived > 0 {
+		interactive.PrintInfo(fmt.Sprintf("Found %d video(s) inside RAR/ZIP archives (use -extract-archives to extract them)", archived))
+	}
+	return kept
+}
+
+// extractArchived extracts an archived video to destDir/newFilename in place of moving it, then
+// deletes the archive set once all its videos are extracted, unless -keep-archives is set
+func extractArchived(file *scanner.MediaFile, destDir, newFilename string, fileRenamer *renamer.Renamer) error {
+	if err := fileRenamer.ExtractArchiveMember(file.Archive, destDir, newFilename); err != nil {
+		return err
+	}
+
+	firstVolume := file.Archive.Set.Volumes[0]
+	pendingArchiveMembers[firstVolume]--
+	if pendingArchiveMembers[firstVolume] > 0 || keepArchives {
+		return nil
+	}
+	if err := fileRenamer.RemoveArchiveSet(file.Archive.Set); err != nil {
+		interactive.PrintWarning(err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+
+	"kodi-renamer/internal/api"
+	"kodi-renamer/internal/ui"
+)
+
+// nextDetailRows returns how many results the selection table shows once `shown` are already shown:
+// -detail-rows more, or all of them when the option is 0
+func nextDetailRows(shown, total int) int {
+	if detailRows <= 0 {
+		return total
+	}
+	return min(shown+detailRows, total)
+}
+
+// fetchMovieOptions fetches the details of movie search results concurrently and returns their table
+// rows, with the details fetched (nil for the results that failed, shown from the search result)
+func fetchMovieOptions(ctx context.Context, props []api.UnifiedProposition, fileMinutes int, apiManager *api.Manager) ([]ui.MovieOption, []*api.UnifiedMovieProposition) {
+	progress := interactive.StartProgress("Fetching movie details", len(props))
+	results := apiManager.FetchMovieDetails(ctx, props, detailWorkers, progress.Update)
+	progress.Stop()
+
+	options := make([]ui.MovieOption, 0, len(props))
+	details := make([]*api.UnifiedMovieProposition, 0, len(props))
+	for i, prop := range props {
+		option := ui.MovieOption{
+			Title:       prop.Title,
+			Year:        prop.Year,
+			Genres:      []string{},
+			Source:      prop.SourceLabel(),
+			Score:       prop.Score,
+			ScoreDetail: prop.ScoreBreakdown.String(),
+		}
+		movie := results[i].Details
+		if results[i].Err != nil {
+			movie = nil
+		}
+		if movie != nil {
+			option.Title = movie.Title
+			option.Year = movie.Year
+			option.Runtime = movie.Runtime
+			option.RuntimeMatch = api.IsRuntimeMatch(fileMinutes, movie.Runtime)
+			option.Genres = movie.Genres
+		}
+		options = append(options, option)
+		details = append(details, movie)
+	}
+	return options, details
+}
+
+// fetchSeriesOptions fetches the details of series search results like fetchMovieOptions
+func fetchSeriesOptions(ctx context.Context, props []api.UnifiedProposition, apiManager *api.Manager) ([]ui.SeriesOption, []*api.UnifiedSeriesProposition) {
+	progress := interactive.StartProgress("Fetching series details", len(props))
+	results := apiManager.FetchSeriesDetails(ctx, props, detailWorkers, progress.Update)
+	progress.Stop()
+
+	options := make([]ui.SeriesOption, 0, len(props))
+	details := make([]*api.UnifiedSeriesProposition, 0, len(props))
+	for i, prop := range props {
+		option := ui.SeriesOption{
+			Name:        prop.Name,
+			Year:        prop.Year,
+			Source:      prop.SourceLabel(),
+			Score:       prop.Score,
+			ScoreDetail: prop.ScoreBreakdown.String(),
+		}
+		series := results[i].Details
+		if results[i].Err != nil {
+			series = nil
+		}
+		if series != nil {
+			option.Name = series.Name
+			option.Year = series.Year
+			option.Status = series.Status
+		}
+		options = append(options, option)
+		details = append(details, series)
+	}
+	return options, details
+}
@@ -1,46 +1,143 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"kodi-renamer/internal/api"
+	"kodi-renamer/internal/hashdb"
+	"kodi-renamer/internal/nfo"
 	"kodi-renamer/internal/renamer"
 	"kodi-renamer/internal/scanner"
+	"kodi-renamer/internal/scanstate"
+	"kodi-renamer/internal/tvdb"
 	"kodi-renamer/internal/ui"
+	"kodi-renamer/internal/utils"
 )
 
 var (
 	tvdbAPIKey       string
 	tmdbAPIKey       string
+	tvdbPIN          string
+	tvdbTokenCache   string
+	debugHTTP        bool
 	movieToRenameDir string
 	movieRenamedDir  string
 	serieToRenameDir string
 	serieRenamedDir  string
+	mixedToRenameDir string
 	dryRun           bool
 	autoMode         bool
+	extrasPolicy     string
+	sampleSizeMB     int64
+	hashDBPath       string
+	includeGlobs     stringListFlag
+	excludeGlobs     stringListFlag
+	includeRegexps   stringListFlag
+	excludeRegexps   stringListFlag
+	minSizeMB        int64
+	noIgnoreFiles    bool
+	showSkipped      bool
+	scanWorkers      int
+	detailRows       int
+	detailWorkers    int
+	writeNFO         bool
+	nfoCountry       string
+	collectionDirs   bool
+	followSymlinks   bool
+	extractArchives  bool
+	keepArchives     bool
+	symlinkPolicy    string
+	stateFile        string
+	stateList        bool
+	stateReset       bool
+	stateForget      string
+	rescan           bool
 	interactive      *ui.Interactive
+	hashDB           *hashdb.Store
+	scanState        *scanstate.Store
 )
 
+const (
+	// extrasPolicyKeep leaves trailers, featurettes and samples where they are
+	extrasPolicyKeep = "keep"
+	// extrasPolicyOrganize moves extras into Kodi-recognised extras/ and trailers/ subfolders
+	extrasPolicyOrganize = "organize"
+	// extrasPolicyDropSamples organizes extras and deletes release samples
+	extrasPolicyDropSamples = "drop-samples"
+)
+
+// stringListFlag is a command-line flag that can be repeated to collect several values
+type stringListFlag []string
+
+func (f *stringListFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *stringListFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
 func init() {
 	flag.StringVar(&tvdbAPIKey, "tvdb-key", "", "TVDB API Key")
 	flag.StringVar(&tmdbAPIKey, "tmdb-key", "", "TMDB API Key")
+	flag.StringVar(&tvdbPIN, "tvdb-pin", "", "TVDB subscriber PIN, required by user-supported API keys")
+	flag.StringVar(&tvdbTokenCache, "tvdb-token-cache", tvdb.DefaultTokenCachePath(), "File caching the TVDB token between runs (empty to disable)")
+	flag.BoolVar(&debugHTTP, "debug-http", false, "Log every TVDB/TMDB request and response status to stderr, with API keys redacted")
 	flag.StringVar(&movieToRenameDir, "movie-to-rename", "", "Directory containing movies to rename")
 	flag.StringVar(&movieRenamedDir, "movie-renamed", "", "Directory for renamed movies")
 	flag.StringVar(&serieToRenameDir, "serie-to-rename", "", "Directory containing series to rename")
 	flag.StringVar(&serieRenamedDir, "serie-renamed", "", "Directory for renamed series")
+	flag.StringVar(&mixedToRenameDir, "mixed-to-rename", "", "Directory containing both movies and series; they go to -movie-renamed and -serie-renamed")
 	flag.BoolVar(&dryRun, "dry-run", false, "Dry run mode - don't actually rename files")
 	flag.BoolVar(&autoMode, "auto", false, "Automatic mode - select first match")
+	flag.StringVar(&extrasPolicy, "extras", extrasPolicyOrganize, "Extras handling: keep, organize (extras/ and trailers/ subfolders) or drop-samples")
+	flag.Int64Var(&sampleSizeMB, "sample-size", scanner.DefaultSampleThreshold/(1024*1024), "Size in MB below which secondary videos in a movie folder are treated as samples")
+	flag.Var(&includeGlobs, "include", "Only scan video files matching this glob (repeatable)")
+	flag.Var(&excludeGlobs, "exclude", "Skip files and folders matching this glob (repeatable)")
+	flag.Var(&includeRegexps, "include-regex", "Only scan video files whose relative path matches this regex (repeatable)")
+	flag.Var(&excludeRegexps, "exclude-regex", "Skip files and folders whose relative path matches this regex (repeatable)")
+	flag.Int64Var(&minSizeMB, "min-size", 0, "Skip standalone video files smaller than this size in MB")
+	flag.BoolVar(&noIgnoreFiles, "no-ignore", false, "Disable the built-in junk folder list and "+scanner.IgnoreFileName+" files")
+	flag.BoolVar(&showSkipped, "show-skipped", false, "List the files and folders skipped by the scanner and why")
+	flag.StringVar(&stateFile, "state-file", scanstate.DefaultPath(), "Scan state file remembering skipped, failed and ignored items (empty to disable)")
+	flag.BoolVar(&rescan, "rescan", false, "Present items handled in previous runs again")
+	flag.BoolVar(&stateList, "state-list", false, "List the scan state entries and exit")
+	flag.BoolVar(&stateReset, "state-reset", false, "Forget all scan state entries and exit")
+	flag.StringVar(&stateForget, "state-forget", "", "Forget the scan state of a path (and everything below it) and exit")
+	flag.BoolVar(&extractArchives, "extract-archives", false, "Extract videos found inside RAR/ZIP archive sets to their renamed location")
+	flag.BoolVar(&keepArchives, "keep-archives", false, "Keep archive sets after extracting their videos (deleted by default)")
+	flag.BoolVar(&followSymlinks, "follow-symlinks", false, "Descend into symlinked directories (loops are detected by device and inode)")
+	flag.StringVar(&symlinkPolicy, "symlink-policy", string(renamer.LinkPolicyMoveLink), "What to move when renaming a symlink: link (the link itself) or target (the file it points to, removing the link)")
+	flag.IntVar(&scanWorkers, "scan-workers", scanner.DefaultScanConcurrency, "Number of directories read in parallel while scanning")
+	flag.IntVar(&detailRows, "detail-rows", 10, "Number of search results detailed in the selection tables before offering to show more (0 for all)")
+	flag.IntVar(&detailWorkers, "detail-workers", api.DefaultDetailWorkers, "Number of result details fetched in parallel for the selection tables")
+	flag.BoolVar(&writeNFO, "nfo", false, "Write a Kodi NFO next to renamed movies, with their IDs and collection (existing NFOs are kept)")
+	flag.StringVar(&nfoCountry, "nfo-country", "US", "Country (ISO 3166-1 code) whose certification and release date go into NFOs")
+	flag.BoolVar(&collectionDirs, "collection-folders", false, "Place movies of a collection in a folder named after it: <Collection>/<Title (Year)>/")
+	flag.StringVar(&hashDBPath, "hash-db", hashdb.DefaultPath(), "Database of file hashes from past renames, used to identify files without searching (empty to disable)")
 }
 
 func main() {
 	flag.Parse()
+	exitOnStateCommand()
 
 	if tvdbAPIKey == "" {
 		tvdbAPIKey = os.Getenv("TVDB_API_KEY")
 	}
+	if tvdbPIN == "" {
+		tvdbPIN = os.Getenv("TVDB_PIN")
+	}
 	if tmdbAPIKey == "" {
 		tmdbAPIKey = os.Getenv("TMDB_API_KEY")
 	}
@@ -56,6 +153,9 @@ func main() {
 	if serieRenamedDir == "" {
 		serieRenamedDir = os.Getenv("SERIE_RENAMED_DIR")
 	}
+	if mixedToRenameDir == "" {
+		mixedToRenameDir = os.Getenv("MIXED_TO_RENAME_DIR")
+	}
 
 	if tvdbAPIKey == "" && tmdbAPIKey == "" {
 		fmt.Fprintf(os.Stderr, "Error: At least one API key is required\n\n")
@@ -69,11 +169,24 @@ func main() {
 		os.Exit(1)
 	}
 
-	if movieToRenameDir == "" && serieToRenameDir == "" {
+	if movieToRenameDir == "" && serieToRenameDir == "" && mixedToRenameDir == "" {
 		fmt.Fprintf(os.Stderr, "Error: At least one input directory is required\n\n")
 		fmt.Fprintf(os.Stderr, "Provide via flags:\n")
 		fmt.Fprintf(os.Stderr, "  -movie-to-rename 'path/to/movies'\n")
-		fmt.Fprintf(os.Stderr, "  -serie-to-rename 'path/to/series'\n\n")
+		fmt.Fprintf(os.Stderr, "  -serie-to-rename 'path/to/series'\n")
+		fmt.Fprintf(os.Stderr, "  -mixed-to-rename 'path/to/inbox'\n\n")
+		os.Exit(1)
+	}
+
+	switch extrasPolicy {
+	case extrasPolicyKeep, extrasPolicyOrganize, extrasPolicyDropSamples:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid -extras value '%s' (expected keep, organize or drop-samples)\n", extrasPolicy)
+		os.Exit(1)
+	}
+
+	if _, err := renamer.ParseLinkPolicy(symlinkPolicy); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
@@ -84,165 +197,333 @@ func main() {
 }
 
 func run() error {
+	ctx, stop := interruptContext()
+	defer stop()
+
 	interactive = ui.NewInteractive()
-	apiManager := api.NewManager(tvdbAPIKey, tmdbAPIKey)
+	apiConfig := api.Config{
+		TVDBAPIKey:     tvdbAPIKey,
+		TVDBPIN:        tvdbPIN,
+		TVDBTokenCache: tvdbTokenCache,
+		TMDBAPIKey:     tmdbAPIKey,
+	}
+	if debugHTTP {
+		apiConfig.HTTPLogger = log.New(os.Stderr, "", log.LstdFlags|log.Lmicroseconds)
+	}
+	apiManager := api.NewManager(ctx, apiConfig)
 	fileRenamer := renamer.NewRenamer(dryRun)
+	linkPolicy, _ := renamer.ParseLinkPolicy(symlinkPolicy)
+	fileRenamer.SetLinkPolicy(linkPolicy)
 
 	if dryRun {
 		interactive.PrintInfo("Running in DRY RUN mode - no changes will be made")
 	}
 
 	configuredAPIs := apiManager.GetConfiguredAPIs()
-	interactive.PrintSuccess(fmt.Sprintf("Authenticated with: %v", configuredAPIs))
+	interactive.PrintSuccess(fmt.Sprintf("Configured APIs: %s", strings.Join(configuredAPIs, ", ")))
+
+	if hashDBPath != "" {
+		store, err := hashdb.Open(hashDBPath)
+		if err != nil {
+			interactive.PrintWarning(fmt.Sprintf("Hash database disabled: %v", err))
+		} else {
+			hashDB = store
+			apiManager.RegisterHashIdentifier(hashDB)
+			defer func() {
+				if err := hashDB.Save(); err != nil {
+					interactive.PrintWarning(fmt.Sprintf("Failed to save hash database: %v", err))
+				}
+			}()
+		}
+	}
+
+	openScanState()
+	defer saveScanState()
 
 	if movieToRenameDir != "" {
 		interactive.PrintHeader("Processing Movies")
-		movieScanner := scanner.NewScanner(movieToRenameDir)
-		mediaFiles, err := movieScanner.ScanDirectory()
+		mediaFiles, _, err := scanInbox(movieToRenameDir)
 		if err != nil {
 			return fmt.Errorf("failed to scan movie directory: %w", err)
 		}
 
-		movies := make([]*scanner.MediaFile, 0)
-		for i := range mediaFiles {
-			if mediaFiles[i].IsMovie {
-				movies = append(movies, &mediaFiles[i])
-			}
-		}
-
-		if len(movies) == 0 {
-			interactive.PrintInfo("No movies found in directory")
-		} else {
-			interactive.PrintInfo(fmt.Sprintf("Found %d movie(s)", len(movies)))
-
-			for _, movie := range movies {
-				if err := processMovie(movie, apiManager, interactive, fileRenamer, movieRenamedDir); err != nil {
-					interactive.PrintError(fmt.Sprintf("Failed to process %s: %v", movie.Name, err))
-					if !autoMode {
-						if !interactive.Confirm("Continue with next movie?") {
-							break
-						}
-					}
-				}
-			}
-		}
+		movies := filterArchived(filterHandled(filterKind(mediaFiles, scanner.KindMovie)))
+		processMovies(ctx, movies, apiManager, fileRenamer)
 	}
 
 	if serieToRenameDir != "" {
 		interactive.PrintHeader("Processing Series")
-		seriesScanner := scanner.NewScanner(serieToRenameDir)
-		mediaFiles, err := seriesScanner.ScanDirectory()
+		mediaFiles, _, err := scanInbox(serieToRenameDir)
 		if err != nil {
 			return fmt.Errorf("failed to scan series directory: %w", err)
 		}
 
-		series := make([]*scanner.MediaFile, 0)
-		for i := range mediaFiles {
-			if mediaFiles[i].IsSeries {
-				series = append(series, &mediaFiles[i])
-			}
-		}
+		series := filterArchived(filterHandled(filterKind(mediaFiles, scanner.KindSeries)))
+		processSeries(ctx, series, apiManager, fileRenamer)
+	}
 
-		if len(series) == 0 {
-			interactive.PrintInfo("No series found in directory")
-		} else {
-			interactive.PrintInfo(fmt.Sprintf("Found %d episode(s)", len(series)))
+	if mixedToRenameDir != "" {
+		interactive.PrintHeader("Processing Mixed Inbox")
+		mediaFiles, inboxScanner, err := scanInbox(mixedToRenameDir)
+		if err != nil {
+			return fmt.Errorf("failed to scan mixed directory: %w", err)
+		}
 
-			seriesMap := make(map[string][]*scanner.MediaFile)
-			for _, ep := range series {
-				parentDir := ep.ParentDir
-				seriesMap[parentDir] = append(seriesMap[parentDir], ep)
-			}
+		items := make([]*scanner.MediaFile, 0, len(mediaFiles))
+		for i := range mediaFiles {
+			items = append(items, &mediaFiles[i])
+		}
+		items = filterArchived(filterHandled(items))
+		if !autoMode && len(items) > 0 {
+			reviewMediaTypes(items, inboxScanner)
+		}
 
-			for parentDir, episodes := range seriesMap {
-				if err := processSeriesBatch(parentDir, episodes, apiManager, interactive, fileRenamer, serieRenamedDir); err != nil {
-					interactive.PrintError(fmt.Sprintf("Failed to process series %s: %v", parentDir, err))
-					if !autoMode {
-						if !interactive.Confirm("Continue with next series?") {
-							break
-						}
-					}
-				}
+		var movies, series []*scanner.MediaFile
+		for _, item := range items {
+			if item.IsSeries {
+				series = append(series, item)
+			} else {
+				movies = append(movies, item)
 			}
 		}
+		processMovies(ctx, movies, apiManager, fileRenamer)
+		processSeries(ctx, series, apiManager, fileRenamer)
 	}
 
+	if ctx.Err() != nil {
+		return fmt.Errorf("interrupted")
+	}
 	interactive.PrintSuccess("Processing complete!")
 	return nil
 }
 
-func processSeriesBatch(parentDir string, episodes []*scanner.MediaFile, apiManager *api.Manager, interactive *ui.Interactive, fileRenamer *renamer.Renamer, outputDir string) error {
-	if len(episodes) == 0 {
-		return nil
-	}
-
-	interactive.PrintHeader(fmt.Sprintf("Processing Series: %s", parentDir))
+// interruptContext returns a context cancelled by the first Ctrl-C or SIGTERM, which aborts the
+// provider requests in flight and stops after the current item so that state is still saved; the
+// signal handler is then removed, so a second Ctrl-C quits immediately
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-signals:
+			signal.Stop(signals)
+			fmt.Fprintln(os.Stderr, "\nInterrupted: stopping after the current item (press Ctrl-C again to quit immediately)")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
 
-	firstEpisode := episodes[0]
-	searchQuery := scanner.GetSeriesSearchQuery(parentDir)
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
 
-	fmt.Printf("Searching for series: '%s' (from folder: %s)\n", searchQuery, parentDir)
+// scanInbox scans an input directory with the configured scanner options and reports what was skipped
+func scanInbox(dir string) ([]scanner.MediaFile, *scanner.Scanner, error) {
+	inboxScanner := scanner.NewScanner(dir)
+	inboxScanner.SetSampleThreshold(sampleSizeMB * 1024 * 1024)
+	if err := configureScanner(inboxScanner); err != nil {
+		return nil, nil, err
+	}
 
-	propositions, err := apiManager.SearchSeries(searchQuery)
+	mediaFiles, err := inboxScanner.ScanDirectory()
 	if err != nil {
-		return fmt.Errorf("search failed: %w", err)
+		return nil, nil, err
 	}
+	reportSkipped(inboxScanner)
+	return mediaFiles, inboxScanner, nil
+}
 
-	if len(propositions) == 0 {
-		interactive.PrintWarning("No results found")
-		return nil
+// filterKind keeps the media files of one kind; the other kind is left out of a movie or series inbox
+func filterKind(mediaFiles []scanner.MediaFile, kind scanner.MediaKind) []*scanner.MediaFile {
+	kept := make([]*scanner.MediaFile, 0, len(mediaFiles))
+	for i := range mediaFiles {
+		if (kind == scanner.KindMovie && mediaFiles[i].IsMovie) || (kind == scanner.KindSeries && mediaFiles[i].IsSeries) {
+			kept = append(kept, &mediaFiles[i])
+		}
 	}
+	return kept
+}
 
-	var selectedIndex int
-	var seriesDetails *api.UnifiedSeriesProposition
+// processMovies identifies and renames the movies of an inbox into -movie-renamed
+func processMovies(ctx context.Context, movies []*scanner.MediaFile, apiManager *api.Manager, fileRenamer *renamer.Renamer) {
+	if len(movies) == 0 {
+		interactive.PrintInfo("No movies found in directory")
+		return
+	}
+	interactive.PrintInfo(fmt.Sprintf("Found %d movie(s)", len(movies)))
 
-	if autoMode {
-		selectedIndex = 0
-		interactive.PrintInfo(fmt.Sprintf("Auto-selecting first result from %s", propositions[0].Source))
-		seriesDetails, err = apiManager.GetSeries(propositions[selectedIndex].ID, propositions[selectedIndex].Source)
-		if err != nil {
-			return fmt.Errorf("failed to get series details: %w", err)
+	for _, movie := range movies {
+		if ctx.Err() != nil {
+			return
 		}
-	} else {
-		fmt.Println("Fetching series details...")
-		seriesOptions := make([]ui.SeriesOption, 0, len(propositions))
+		err := processMovie(ctx, movie, apiManager, interactive, fileRenamer, movieRenamedDir)
+		if err != nil && ctx.Err() != nil {
+			// Interrupted: the movie is presented again next run
+			return
+		}
+		recordScanDecision(movie, err)
+		if err != nil && !isSkip(err) {
+			interactive.PrintError(fmt.Sprintf("Failed to process %s: %v", movie.Name, err))
+			if !autoMode {
+				if !interactive.Confirm("Continue with next movie?") {
+					break
+				}
+			}
+		}
+	}
+}
 
-		for _, prop := range propositions {
-			details, err := apiManager.GetSeries(prop.ID, prop.Source)
-			if err != nil {
-				seriesOptions = append(seriesOptions, ui.SeriesOption{
-					Name:   prop.Name,
-					Year:   prop.Year,
-					Status: "",
-					Source: prop.Source,
-				})
+// processSeries groups the episodes of an inbox by folder, then identifies and renames each
+// series into -serie-renamed
+func processSeries(ctx context.Context, series []*scanner.MediaFile, apiManager *api.Manager, fileRenamer *renamer.Renamer) {
+	if len(series) == 0 {
+		interactive.PrintInfo("No series found in directory")
+		return
+	}
+	interactive.PrintInfo(fmt.Sprintf("Found %d episode(s)", len(series)))
+
+	seriesMap := make(map[string][]*scanner.MediaFile)
+	for _, ep := range series {
+		parentDir := ep.ParentDir
+		seriesMap[parentDir] = append(seriesMap[parentDir], ep)
+	}
+
+	for parentDir, episodes := range seriesMap {
+		if ctx.Err() != nil {
+			return
+		}
+		failures, err := processSeriesBatch(ctx, parentDir, episodes, apiManager, interactive, fileRenamer, serieRenamedDir)
+		if err != nil && ctx.Err() != nil {
+			// Interrupted: the series is presented again next run
+			return
+		}
+		for _, ep := range episodes {
+			if epErr, failed := failures[ep]; failed && err == nil {
+				recordScanDecision(ep, epErr)
 				continue
 			}
+			recordScanDecision(ep, err)
+		}
+		if err != nil && !isSkip(err) {
+			interactive.PrintError(fmt.Sprintf("Failed to process series %s: %v", parentDir, err))
+			if !autoMode {
+				if !interactive.Confirm("Continue with next series?") {
+					break
+				}
+			}
+		}
+	}
+}
 
-			seriesOptions = append(seriesOptions, ui.SeriesOption{
-				Name:   details.Name,
-				Year:   details.Year,
-				Status: details.Status,
-				Source: details.Source,
-			})
+// reviewMediaTypes shows how the items of a mixed inbox were classified and switches the items the
+// user picks between movie and series
+func reviewMediaTypes(items []*scanner.MediaFile, inboxScanner *scanner.Scanner) {
+	reviewItems := make([]ui.MediaTypeItem, 0, len(items))
+	for _, item := range items {
+		reviewItems = append(reviewItems, ui.MediaTypeItem{
+			Name:   item.Path,
+			Type:   string(mediaKind(item)),
+			Forced: item.TypeOverride != nil && item.TypeOverride.Origin != "",
+		})
+	}
+
+	for _, index := range interactive.ReviewMediaTypes(reviewItems) {
+		item := items[index]
+		kind := scanner.KindSeries
+		if item.IsSeries {
+			kind = scanner.KindMovie
 		}
+		if err := inboxScanner.Reclassify(item, kind); err != nil {
+			interactive.PrintWarning(fmt.Sprintf("Cannot switch %s to %s: %v", item.Name, kind, err))
+			continue
+		}
+		item.TypeOverride = &scanner.TypeOverride{Kind: kind}
+		interactive.PrintInfo(fmt.Sprintf("%s will be processed as %s", item.Name, kind))
+	}
+}
 
-		selectedIndex, err = interactive.SelectSeriesFromList(
-			fmt.Sprintf("Select series for '%s'", firstEpisode.CleanName),
-			seriesOptions,
-		)
-		if err != nil {
+// mediaKind returns whether a media file is processed as a movie or as an episode
+func mediaKind(file *scanner.MediaFile) scanner.MediaKind {
+	if file.IsSeries {
+		return scanner.KindSeries
+	}
+	return scanner.KindMovie
+}
+
+// configureScanner applies the parallelism, include/exclude, minimum size and ignore file options to a scanner
+func configureScanner(s *scanner.Scanner) error {
+	s.SetConcurrency(scanWorkers)
+	for _, glob := range includeGlobs {
+		s.AddIncludeGlob(glob)
+	}
+	for _, glob := range excludeGlobs {
+		s.AddExcludeGlob(glob)
+	}
+	for _, expr := range includeRegexps {
+		if err := s.AddIncludeRegex(expr); err != nil {
 			return err
 		}
-
-		if selectedIndex == -1 {
-			interactive.PrintInfo("Skipped")
-			return nil
+	}
+	for _, expr := range excludeRegexps {
+		if err := s.AddExcludeRegex(expr); err != nil {
+			return err
 		}
+	}
+	s.SetMinFileSize(minSizeMB * 1024 * 1024)
+	s.SetIgnoreFiles(!noIgnoreFiles)
+	s.SetFollowSymlinks(followSymlinks)
+	return nil
+}
+
+// reportSkipped prints what the last scan skipped: a count by default, every path with -show-skipped
+func reportSkipped(s *scanner.Scanner) {
+	for _, link := range s.DanglingLinks() {
+		interactive.PrintWarning(fmt.Sprintf("Dangling symlink: %s -> %s", link.Path, link.Target))
+	}
+
+	skipped := s.Skipped()
+	if len(skipped) == 0 {
+		return
+	}
+
+	if !showSkipped {
+		interactive.PrintInfo(fmt.Sprintf("Skipped %d file(s)/folder(s) (use -show-skipped for details)", len(skipped)))
+		return
+	}
+
+	interactive.PrintInfo(fmt.Sprintf("Skipped %d file(s)/folder(s):", len(skipped)))
+	for _, skip := range skipped {
+		fmt.Printf("  %s: %s\n", skip.Path, skip.Reason)
+	}
+}
+
+// processSeriesBatch identifies the series of a folder and renames its episodes. The error applies to the
+// whole batch; episodes that could not be renamed while the rest of the batch was are returned with
+// their own error
+func processSeriesBatch(ctx context.Context, parentDir string, episodes []*scanner.MediaFile, apiManager *api.Manager, interactive *ui.Interactive, fileRenamer *renamer.Renamer, outputDir string) (map[*scanner.MediaFile]error, error) {
+	if len(episodes) == 0 {
+		return nil, nil
+	}
+
+	interactive.PrintHeader(fmt.Sprintf("Processing Series: %s", parentDir))
 
-		seriesDetails, err = apiManager.GetSeries(propositions[selectedIndex].ID, propositions[selectedIndex].Source)
+	firstEpisode := episodes[0]
+	seriesDetails := seriesFromOverride(ctx, firstEpisode, apiManager)
+	if seriesDetails == nil {
+		seriesDetails = identifySeriesByHash(ctx, episodes, apiManager)
+	}
+	if seriesDetails == nil {
+		var err error
+		seriesDetails, err = searchAndSelectSeries(ctx, parentDir, firstEpisode, apiManager, interactive)
 		if err != nil {
-			return fmt.Errorf("failed to get series details: %w", err)
+			return nil, err
+		}
+		for _, ep := range episodes {
+			ep.SearchVariant = firstEpisode.SearchVariant
 		}
 	}
 
@@ -259,8 +540,12 @@ func processSeriesBatch(parentDir string, episodes []*scanner.MediaFile, apiMana
 	}
 
 	fmt.Println("\nFetching episode details...")
+	now := time.Now()
 	for _, ep := range episodes {
-		episodeDetails, err := apiManager.GetEpisode(seriesDetails.ID, seriesDetails.Source, ep.Season, ep.Episode)
+		episodeDetails, err := fetchEpisode(ctx, ep, seriesDetails, apiManager)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		if err != nil {
 			batch.Episodes = append(batch.Episodes, scanner.EpisodeRenameTask{
 				File:         ep,
@@ -274,6 +559,11 @@ func processSeriesBatch(parentDir string, episodes []*scanner.MediaFile, apiMana
 			continue
 		}
 
+		if !episodeDetails.HasAired(now) {
+			interactive.PrintWarning(fmt.Sprintf("S%02dE%02d has not aired yet (airs %s): %s is likely mislabelled",
+				ep.Season, ep.Episode, episodeDetails.AirDate, ep.Name))
+		}
+
 		newFilename := ep.GetEpisodeFilename(seriesDetails.Name, ep.Season, ep.Episode, episodeDetails.Name)
 		batch.Episodes = append(batch.Episodes, scanner.EpisodeRenameTask{
 			File:        ep,
@@ -307,22 +597,33 @@ func processSeriesBatch(parentDir string, episodes []*scanner.MediaFile, apiMana
 	if !autoMode && !dryRun {
 		if !interactive.Confirm("Proceed with renaming all episodes?") {
 			interactive.PrintInfo("Skipped")
-			return nil
+			return nil, errSkipped
 		}
 	}
 
+	failures := make(map[*scanner.MediaFile]error)
 	if outputDir != "" {
 		newFolderPath := filepath.Join(outputDir, batch.NewFolderName)
 
 		if !dryRun {
 			if err := os.MkdirAll(newFolderPath, 0755); err != nil {
-				return fmt.Errorf("failed to create output folder: %w", err)
+				return nil, fmt.Errorf("failed to create output folder: %w", err)
 			}
 		}
 
 		for _, task := range batch.Episodes {
 			if task.HasError {
 				interactive.PrintWarning(fmt.Sprintf("Skipping S%02dE%02d: %s", task.Season, task.Episode, task.ErrorMessage))
+				failures[task.File] = errors.New(task.ErrorMessage)
+				continue
+			}
+
+			if task.File.Archive != nil {
+				if err := extractArchived(task.File, newFolderPath, task.NewFilename, fileRenamer); err != nil {
+					interactive.PrintError(fmt.Sprintf("Failed to extract %s: %v", task.File.Name, err))
+					failures[task.File] = err
+				}
+				fmt.Println()
 				continue
 			}
 
@@ -330,21 +631,40 @@ func processSeriesBatch(parentDir string, episodes []*scanner.MediaFile, apiMana
 			newPath := filepath.Join(newFolderPath, task.NewFilename)
 
 			if dryRun {
-				fmt.Printf("[DRY RUN] Would move:\n  FROM: %s\n  TO:   %s\n\n", oldPath, newPath)
+				fmt.Printf("[DRY RUN] Would move:\n  FROM: %s\n  TO:   %s\n", oldPath, newPath)
+				fileRenamer.MoveCompanions(oldPath, newFolderPath, task.NewFilename)
+				fmt.Println()
 			} else {
-				if err := os.Rename(oldPath, newPath); err != nil {
+				if err := fileRenamer.Move(oldPath, newPath); err != nil {
 					interactive.PrintError(fmt.Sprintf("Failed to move %s: %v", task.File.Name, err))
+					failures[task.File] = err
 				} else {
-					fmt.Printf("Moved:\n  FROM: %s\n  TO:   %s\n\n", oldPath, newPath)
+					fmt.Printf("Moved:\n  FROM: %s\n  TO:   %s\n", oldPath, newPath)
+					fileRenamer.MoveCompanions(oldPath, newFolderPath, task.NewFilename)
+					fmt.Println()
+					task.File.RenamedPath = newPath
+					recordEpisodeHash(task, seriesDetails, newPath)
 				}
 			}
 		}
 	} else {
 		// First, rename all episode files while folder still has original name
 		successCount := 0
+		var renamed []scanner.EpisodeRenameTask
 		for _, task := range batch.Episodes {
 			if task.HasError {
 				interactive.PrintWarning(fmt.Sprintf("Skipping S%02dE%02d: %s", task.Season, task.Episode, task.ErrorMessage))
+				failures[task.File] = errors.New(task.ErrorMessage)
+				continue
+			}
+
+			if task.File.Archive != nil {
+				if err := extractArchived(task.File, filepath.Dir(task.File.Path), task.NewFilename, fileRenamer); err != nil {
+					interactive.PrintError(fmt.Sprintf("Failed to extract %s: %v", task.File.Name, err))
+					failures[task.File] = err
+				} else {
+					successCount++
+				}
 				continue
 			}
 
@@ -352,17 +672,22 @@ func processSeriesBatch(parentDir string, episodes []*scanner.MediaFile, apiMana
 			oldPath := task.File.Path
 			if _, err := os.Stat(oldPath); err != nil {
 				interactive.PrintError(fmt.Sprintf("File not found: %s - %v", oldPath, err))
+				failures[task.File] = err
 				continue
 			}
 
 			if err := fileRenamer.RenameFileSilent(oldPath, task.NewFilename); err != nil {
 				interactive.PrintError(fmt.Sprintf("Failed to rename %s: %v", task.File.Name, err))
+				failures[task.File] = err
 			} else {
 				successCount++
+				fileRenamer.MoveCompanions(oldPath, filepath.Dir(oldPath), task.NewFilename)
+				renamed = append(renamed, task)
 			}
 		}
 
 		// After all files are renamed, rename the folder if needed
+		finalFolder := batch.OriginalFolderPath
 		if batch.NeedsFolderRename {
 			if !dryRun {
 				newFolderPath, err := fileRenamer.RenameSeriesFolder(batch.OriginalFolderPath, batch.NewFolderName)
@@ -370,21 +695,175 @@ func processSeriesBatch(parentDir string, episodes []*scanner.MediaFile, apiMana
 					interactive.PrintError(fmt.Sprintf("Failed to rename series folder: %v", err))
 				} else {
 					fmt.Printf("Renamed series folder:\n  FROM: %s\n  TO:   %s\n\n", batch.OriginalFolderPath, newFolderPath)
+					finalFolder = newFolderPath
 				}
 			} else {
 				fmt.Printf("[DRY RUN] Would rename folder:\n  FROM: %s\n  TO:   %s\n\n", batch.OriginalFolderPath, filepath.Join(filepath.Dir(batch.OriginalFolderPath), batch.NewFolderName))
 			}
 		}
 
+		// Episodes are recorded where they ended up, in the original folder if it could not be renamed
+		for _, task := range renamed {
+			task.File.RenamedPath = filepath.Join(finalFolder, task.NewFilename)
+			recordEpisodeHash(task, seriesDetails, task.File.RenamedPath)
+		}
+
 		if !dryRun {
 			fmt.Printf("\nSuccessfully renamed %d/%d episode(s) in series '%s'\n\n", successCount, len(batch.Episodes), batch.SeriesName)
 		}
 	}
 
+	return failures, nil
+}
+
+// fetchEpisode retrieves the details of the episode a file claims. Daily episodes named by date are
+// looked up by air date, episodes aired the same day being told apart by title and runtime, and get
+// the season and episode numbers found.
+func fetchEpisode(ctx context.Context, ep *scanner.MediaFile, seriesDetails *api.UnifiedSeriesProposition, apiManager *api.Manager) (*api.UnifiedEpisodeInfo, error) {
+	if ep.AirDate == "" || ep.Season != 0 || ep.Episode != 0 {
+		return apiManager.GetEpisode(ctx, seriesDetails.ID, seriesDetails.Source, ep.Season, ep.Episode)
+	}
+
+	candidates, err := apiManager.GetEpisodesByAirDate(ctx, seriesDetails.ID, seriesDetails.Source, ep.AirDate)
+	if err != nil {
+		return nil, err
+	}
+	hint := api.EpisodeHint{Name: ep.CleanName}
+	if ep.MediaInfo != nil {
+		hint.Minutes = ep.MediaInfo.RuntimeMinutes()
+	}
+	episode := api.PickEpisode(candidates, hint)
+	if len(candidates) > 1 {
+		interactive.PrintInfo(fmt.Sprintf("%d episodes aired on %s, %s is S%02dE%02d - %s",
+			len(candidates), ep.AirDate, ep.Name, episode.SeasonNumber, episode.EpisodeNumber, episode.Name))
+	}
+	ep.Season, ep.Episode = episode.SeasonNumber, episode.EpisodeNumber
+	return episode, nil
+}
+
+// searchAndSelectSeries searches the configured APIs for the series of a folder and lets the user pick
+// the right result; it returns errSkipped when nothing was found or the series was skipped
+func searchAndSelectSeries(ctx context.Context, parentDir string, firstEpisode *scanner.MediaFile, apiManager *api.Manager, interactive *ui.Interactive) (*api.UnifiedSeriesProposition, error) {
+	searchQuery := scanner.GetSeriesSearchQuery(parentDir)
+
+	fmt.Printf("Searching for series: '%s' (from folder: %s)\n", searchQuery, parentDir)
+
+	result, err := apiManager.SearchSeriesWithFallback(ctx, []string{searchQuery, firstEpisode.CleanName})
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+
+	propositions := result.Propositions
+	if len(propositions) == 0 {
+		interactive.PrintWarning(fmt.Sprintf("No results found (tried %d queries)", len(result.Tried)))
+		return nil, errSkipped
+	}
+	noteSearchVariant(firstEpisode, result)
+
+	var selectedIndex int
+	var seriesDetails *api.UnifiedSeriesProposition
+
+	if autoMode {
+		selectedIndex = 0
+		interactive.PrintInfo(fmt.Sprintf("Auto-selecting first result from %s", propositions[0].SourceLabel()))
+		seriesDetails, err = apiManager.GetSeriesDetails(ctx, propositions[selectedIndex])
+		if err != nil {
+			return nil, fmt.Errorf("failed to get series details: %w", err)
+		}
+	} else {
+		var seriesOptions []ui.SeriesOption
+		var fetched []*api.UnifiedSeriesProposition
+
+		// Details are fetched -detail-rows at a time, when the user asks to see more
+		for {
+			shown := nextDetailRows(len(seriesOptions), len(propositions))
+			options, details := fetchSeriesOptions(ctx, propositions[len(seriesOptions):shown], apiManager)
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			seriesOptions = append(seriesOptions, options...)
+			fetched = append(fetched, details...)
+
+			selectedIndex, err = interactive.SelectSeriesFromListWithMore(
+				fmt.Sprintf("Select series for '%s'", firstEpisode.CleanName),
+				seriesOptions,
+				len(propositions)-len(seriesOptions),
+			)
+			if err != nil {
+				return nil, err
+			}
+			if selectedIndex != ui.ShowMore {
+				break
+			}
+		}
+
+		if selectedIndex == ui.IgnoreForever {
+			interactive.PrintInfo("Ignored - it will not be presented again")
+			return nil, errIgnored
+		}
+		if selectedIndex == -1 {
+			interactive.PrintInfo("Skipped")
+			return nil, errSkipped
+		}
+
+		seriesDetails = fetched[selectedIndex]
+		if seriesDetails == nil {
+			seriesDetails, err = apiManager.GetSeriesDetails(ctx, propositions[selectedIndex])
+			if err != nil {
+				return nil, fmt.Errorf("failed to get series details: %w", err)
+			}
+		}
+	}
+
+	return seriesDetails, nil
+}
+
+// identifySeriesByHash returns the series of the first episode known to the hash identifiers,
+// asking for confirmation in interactive mode
+func identifySeriesByHash(ctx context.Context, episodes []*scanner.MediaFile, apiManager *api.Manager) *api.UnifiedSeriesProposition {
+	for _, ep := range episodes {
+		match, _ := apiManager.IdentifyByHash(ep.Hash, ep.FileSize)
+		if match == nil || match.Type != "series" {
+			continue
+		}
+
+		seriesDetails, err := apiManager.GetSeries(ctx, match.ID, match.Source)
+		if err != nil {
+			interactive.PrintWarning(fmt.Sprintf("Failed to get series identified by hash: %v", err))
+			return nil
+		}
+
+		interactive.PrintInfo(fmt.Sprintf("Identified '%s' by file hash: %s (%s)", ep.Name, seriesDetails.GetFolderName(), seriesDetails.Source))
+		if !autoMode && !interactive.Confirm("Use this series?") {
+			return nil
+		}
+		return seriesDetails
+	}
+
 	return nil
 }
 
-func processMovie(file *scanner.MediaFile, apiManager *api.Manager, interactive *ui.Interactive, fileRenamer *renamer.Renamer, outputDir string) error {
+// recordEpisodeHash remembers the identification of a renamed episode in the hash database
+func recordEpisodeHash(task scanner.EpisodeRenameTask, seriesDetails *api.UnifiedSeriesProposition, newPath string) {
+	if hashDB == nil || dryRun {
+		return
+	}
+	hashDB.Record(hashdb.Entry{
+		Hash:    task.File.Hash,
+		Size:    task.File.FileSize,
+		ID:      seriesDetails.ID,
+		Source:  seriesDetails.Source,
+		IDs:     seriesDetails.UniqueIDs,
+		Type:    "series",
+		Title:   seriesDetails.Name,
+		Year:    seriesDetails.Year,
+		Season:  task.Season,
+		Episode: task.Episode,
+		Path:    newPath,
+	})
+}
+
+func processMovie(ctx context.Context, file *scanner.MediaFile, apiManager *api.Manager, interactive *ui.Interactive, fileRenamer *renamer.Renamer, outputDir string) error {
 	searchQuery := file.GetSearchQuery()
 	year := file.Year
 
@@ -397,82 +876,246 @@ func processMovie(file *scanner.MediaFile, apiManager *api.Manager, interactive
 			fmt.Printf("Processing movie folder: '%s' (%d video files, %d subtitles)\n",
 				file.Name, len(file.MovieFiles), len(file.SubtitleFiles))
 		}
+	} else if len(file.PartFiles) > 1 {
+		fmt.Printf("Processing multi-part movie: '%s' (%d parts)\n", file.Name, len(file.PartFiles))
+	}
+	if file.DiscTitle != "" {
+		fmt.Printf("Disc title: '%s'\n", file.DiscTitle)
+	}
+
+	fileMinutes := 0
+	if file.MediaInfo != nil {
+		fileMinutes = file.MediaInfo.RuntimeMinutes()
+	}
+
+	movieDetails := movieFromOverride(ctx, file, apiManager)
+	if movieDetails == nil {
+		movieDetails = identifyMovieByHash(ctx, file, apiManager)
+	}
+	if movieDetails == nil {
+		var err error
+		movieDetails, err = searchAndSelectMovie(ctx, file, searchQuery, year, fileMinutes, apiManager, interactive)
+		if err != nil {
+			return err
+		}
+	}
+
+	interactive.DisplayMovieInfo(movieDetails.Title, movieDetails.Year, movieDetails.Runtime, movieDetails.Genres)
+	if writeNFO {
+		if err := apiManager.FillMetadata(ctx, movieDetails); err != nil {
+			interactive.PrintWarning(fmt.Sprintf("Could not fetch the movie metadata: %v", err))
+		}
+	}
+	if writeNFO || collectionDirs {
+		if err := apiManager.FillCollection(ctx, movieDetails); err != nil {
+			interactive.PrintWarning(fmt.Sprintf("Could not look up the movie collection: %v", err))
+		} else if movieDetails.CollectionName != "" {
+			interactive.PrintInfo(fmt.Sprintf("Collection: %s", movieDetails.CollectionName))
+		}
+	}
+
+	if file.IsMovieFolder {
+		return processMovieFolder(file, movieDetails, fileRenamer, outputDir)
 	}
 
+	return processStandaloneMovie(file, movieDetails, fileRenamer, outputDir)
+}
+
+// searchAndSelectMovie searches the configured APIs for a movie and lets the user pick the right
+// result; it returns errSkipped when nothing was found or the movie was skipped
+func searchAndSelectMovie(ctx context.Context, file *scanner.MediaFile, searchQuery string, year int, fileMinutes int, apiManager *api.Manager, interactive *ui.Interactive) (*api.UnifiedMovieProposition, error) {
 	fmt.Printf("Searching for: '%s (%d)'\n", searchQuery, year)
 
-	propositions, err := apiManager.SearchMovies(searchQuery, year)
+	result, err := apiManager.SearchMoviesWithFallback(ctx, []string{searchQuery, file.AltSearchQuery}, year)
 	if err != nil {
-		return fmt.Errorf("search failed: %w", err)
+		return nil, fmt.Errorf("search failed: %w", err)
 	}
 
+	propositions := result.Propositions
 	if len(propositions) == 0 {
-		interactive.PrintWarning("No results found")
-		return nil
+		interactive.PrintWarning(fmt.Sprintf("No results found (tried %d queries)", len(result.Tried)))
+		return nil, errSkipped
 	}
+	noteSearchVariant(file, result)
 
 	var selectedIndex int
 	var movieDetails *api.UnifiedMovieProposition
 
 	if autoMode {
-		selectedIndex = 0
-		interactive.PrintInfo(fmt.Sprintf("Auto-selecting first result from %s", propositions[0].Source))
-		movieDetails, err = apiManager.GetMovie(propositions[0].ID, propositions[0].Source)
+		movieDetails, err = autoSelectMovie(ctx, propositions, fileMinutes, apiManager)
 		if err != nil {
-			return fmt.Errorf("failed to get movie details: %w", err)
+			return nil, fmt.Errorf("failed to get movie details: %w", err)
 		}
 	} else {
-		fmt.Println("Fetching movie details...")
-		movieOptions := make([]ui.MovieOption, 0, len(propositions))
-
-		for _, prop := range propositions {
-			details, err := apiManager.GetMovie(prop.ID, prop.Source)
+		var movieOptions []ui.MovieOption
+		var fetched []*api.UnifiedMovieProposition
+
+		// Details are fetched -detail-rows at a time, when the user asks to see more
+		for {
+			shown := nextDetailRows(len(movieOptions), len(propositions))
+			options, details := fetchMovieOptions(ctx, propositions[len(movieOptions):shown], fileMinutes, apiManager)
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			movieOptions = append(movieOptions, options...)
+			fetched = append(fetched, details...)
+
+			selectedIndex, err = interactive.SelectMovieFromListWithMore(
+				fmt.Sprintf("Select movie for '%s'", file.CleanName),
+				movieOptions,
+				len(propositions)-len(movieOptions),
+			)
 			if err != nil {
-				movieOptions = append(movieOptions, ui.MovieOption{
-					Title:   prop.Title,
-					Year:    prop.Year,
-					Runtime: 0,
-					Genres:  []string{},
-					Source:  prop.Source,
-				})
-				continue
+				return nil, err
+			}
+			if selectedIndex != ui.ShowMore {
+				break
 			}
-
-			movieOptions = append(movieOptions, ui.MovieOption{
-				Title:   details.Title,
-				Year:    details.Year,
-				Runtime: details.Runtime,
-				Genres:  details.Genres,
-				Source:  details.Source,
-			})
 		}
 
-		selectedIndex, err = interactive.SelectMovieFromList(
-			fmt.Sprintf("Select movie for '%s'", file.CleanName),
-			movieOptions,
-		)
-		if err != nil {
-			return err
+		if selectedIndex == ui.IgnoreForever {
+			interactive.PrintInfo("Ignored - it will not be presented again")
+			return nil, errIgnored
 		}
-
 		if selectedIndex == -1 {
 			interactive.PrintInfo("Skipped")
-			return nil
+			return nil, errSkipped
 		}
 
-		movieDetails, err = apiManager.GetMovie(propositions[selectedIndex].ID, propositions[selectedIndex].Source)
-		if err != nil {
-			return fmt.Errorf("failed to get movie details: %w", err)
+		movieDetails = fetched[selectedIndex]
+		if movieDetails == nil {
+			movieDetails, err = apiManager.GetMovieDetails(ctx, propositions[selectedIndex])
+			if err != nil {
+				return nil, fmt.Errorf("failed to get movie details: %w", err)
+			}
 		}
 	}
 
-	interactive.DisplayMovieInfo(movieDetails.Title, movieDetails.Year, movieDetails.Runtime, movieDetails.Genres)
+	return movieDetails, nil
+}
 
-	if file.IsMovieFolder {
-		return processMovieFolder(file, movieDetails, fileRenamer, outputDir)
+// noteSearchVariant reports a fallback query that found results and keeps it for the scan state
+func noteSearchVariant(file *scanner.MediaFile, result *api.FallbackResult) {
+	file.SearchVariant = ""
+	if !result.IsFallback() {
+		return
 	}
+	file.SearchVariant = result.Variant.String()
+	interactive.PrintInfo(fmt.Sprintf("Found with fallback search %s", file.SearchVariant))
+}
 
-	return processStandaloneMovie(file, movieDetails, fileRenamer, outputDir)
+// movieFromOverride returns the movie whose ID is given by a .kodi-type marker ("movie:tmdb=603"), or nil
+func movieFromOverride(ctx context.Context, file *scanner.MediaFile, apiManager *api.Manager) *api.UnifiedMovieProposition {
+	override := file.TypeOverride
+	if override == nil || override.ID == "" {
+		return nil
+	}
+
+	movieDetails, err := apiManager.GetMovie(ctx, override.ID, override.Source)
+	if err != nil {
+		interactive.PrintWarning(fmt.Sprintf("Failed to get movie %s=%s from %s: %v", override.Source, override.ID, override.Origin, err))
+		return nil
+	}
+	interactive.PrintInfo(fmt.Sprintf("Identified by %s: %s (%s)", override.Origin, movieDetails.Title, movieDetails.Year))
+	return movieDetails
+}
+
+// seriesFromOverride returns the series whose ID is given by a .kodi-type marker ("series:tvdb=121361"), or nil
+func seriesFromOverride(ctx context.Context, episode *scanner.MediaFile, apiManager *api.Manager) *api.UnifiedSeriesProposition {
+	override := episode.TypeOverride
+	if override == nil || override.ID == "" {
+		return nil
+	}
+
+	seriesDetails, err := apiManager.GetSeries(ctx, override.ID, override.Source)
+	if err != nil {
+		interactive.PrintWarning(fmt.Sprintf("Failed to get series %s=%s from %s: %v", override.Source, override.ID, override.Origin, err))
+		return nil
+	}
+	interactive.PrintInfo(fmt.Sprintf("Identified by %s: %s (%s)", override.Origin, seriesDetails.Name, seriesDetails.Year))
+	return seriesDetails
+}
+
+// identifyMovieByHash returns the movie known to the hash identifiers for this file,
+// asking for confirmation in interactive mode
+func identifyMovieByHash(ctx context.Context, file *scanner.MediaFile, apiManager *api.Manager) *api.UnifiedMovieProposition {
+	match, _ := apiManager.IdentifyByHash(file.Hash, file.FileSize)
+	if match == nil || match.Type != "movie" {
+		return nil
+	}
+
+	movieDetails, err := apiManager.GetMovie(ctx, match.ID, match.Source)
+	if err != nil {
+		interactive.PrintWarning(fmt.Sprintf("Failed to get movie identified by hash: %v", err))
+		return nil
+	}
+
+	interactive.PrintInfo(fmt.Sprintf("Identified by file hash: %s (%s) from %s", movieDetails.Title, movieDetails.Year, movieDetails.Source))
+	if !autoMode && !interactive.Confirm("Use this movie?") {
+		return nil
+	}
+	return movieDetails
+}
+
+// recordMovieHash remembers the identification of a renamed movie in the hash database
+func recordMovieHash(file *scanner.MediaFile, movieDetails *api.UnifiedMovieProposition, newPath string) {
+	if hashDB == nil || dryRun {
+		return
+	}
+	hashDB.Record(hashdb.Entry{
+		Hash:   file.Hash,
+		Size:   file.FileSize,
+		ID:     movieDetails.ID,
+		Source: movieDetails.Source,
+		IDs:    movieDetails.UniqueIDs,
+		Type:   "movie",
+		Title:  movieDetails.Title,
+		Year:   movieDetails.Year,
+		Path:   newPath,
+	})
+}
+
+// autoRuntimeCandidates is the number of top results whose runtime is compared with the file in auto mode
+const autoRuntimeCandidates = 3
+
+// autoSelectMovie picks the first result, unless the file duration is known and another of the
+// top results has a clearly closer runtime
+func autoSelectMovie(ctx context.Context, propositions []api.UnifiedProposition, fileMinutes int, apiManager *api.Manager) (*api.UnifiedMovieProposition, error) {
+	if fileMinutes <= 0 {
+		interactive.PrintInfo(fmt.Sprintf("Auto-selecting first result from %s", propositions[0].SourceLabel()))
+		return apiManager.GetMovieDetails(ctx, propositions[0])
+	}
+
+	var best *api.UnifiedMovieProposition
+	bestScore := -1.0
+	bestIndex := 0
+	var firstErr error
+
+	candidates := propositions[:min(autoRuntimeCandidates, len(propositions))]
+	for i, result := range apiManager.FetchMovieDetails(ctx, candidates, detailWorkers, nil) {
+		details, err := result.Details, result.Err
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if score := api.RuntimeMatchScore(fileMinutes, details.Runtime); score > bestScore {
+			best, bestScore, bestIndex = details, score, i
+		}
+	}
+
+	if best == nil {
+		return nil, firstErr
+	}
+
+	if bestIndex > 0 {
+		interactive.PrintInfo(fmt.Sprintf("Auto-selecting result #%d from %s (runtime %d min matches file duration %d min)",
+			bestIndex+1, best.Source, best.Runtime, fileMinutes))
+	} else {
+		interactive.PrintInfo(fmt.Sprintf("Auto-selecting first result from %s", best.Source))
+	}
+	return best, nil
 }
 
 func processMovieFolder(file *scanner.MediaFile, movieDetails *api.UnifiedMovieProposition, fileRenamer *renamer.Renamer, outputDir string) error {
@@ -485,6 +1128,7 @@ func processMovieFolder(file *scanner.MediaFile, movieDetails *api.UnifiedMovieP
 	if targetDir == "" {
 		targetDir = filepath.Dir(filepath.Dir(file.Path))
 	}
+	targetDir = collectionDir(targetDir, movieDetails)
 
 	var mainVideoFile string
 	if len(file.MovieFiles) > 0 {
@@ -494,7 +1138,7 @@ func processMovieFolder(file *scanner.MediaFile, movieDetails *api.UnifiedMovieP
 	if !autoMode && !dryRun {
 		if !interactive.Confirm(fmt.Sprintf("Move/rename folder to '%s'?", newFolderName)) {
 			interactive.PrintInfo("Skipped")
-			return nil
+			return errSkipped
 		}
 	}
 
@@ -502,13 +1146,74 @@ func processMovieFolder(file *scanner.MediaFile, movieDetails *api.UnifiedMovieP
 		return err
 	}
 
+	newFolderPath := filepath.Join(targetDir, newFolderName)
+	file.RenamedPath = newFolderPath
+
+	// The hash is the main video's: record where it ends up, or the folder for discs without one
+	hashedPath := newFolderPath
+	if mainVideoFile != "" {
+		hashedPath = filepath.Join(newFolderPath, mainVideoFile)
+	}
+	renameVideo := func(oldFileName, newFileName string) error {
+		err := fileRenamer.RenameMovieFileInFolder(newFolderPath, oldFileName, newFileName)
+		if err == nil && oldFileName == mainVideoFile {
+			hashedPath = filepath.Join(newFolderPath, newFileName)
+		}
+		return err
+	}
+	defer func() { recordMovieHash(file, movieDetails, hashedPath) }()
+
+	// Extras moved along with the folder: rebase their paths on the new folder location
+	extras := make([]scanner.ExtraFile, 0, len(file.Extras))
+	for _, extra := range file.Extras {
+		if relPath, err := filepath.Rel(file.Path, extra.Path); err == nil {
+			extra.Path = filepath.Join(newFolderPath, relPath)
+		}
+		extras = append(extras, extra)
+	}
+	organizeExtras(extras, newFolderPath, fileRenamer)
+
+	// Stacked multi-part movie: rename every part with its Kodi stacking suffix, all parts or none
+	if len(file.PartFiles) > 1 {
+		oldPaths := make([]string, 0, len(file.PartFiles))
+		for _, partFile := range file.PartFiles {
+			oldPaths = append(oldPaths, filepath.Join(newFolderPath, filepath.Base(partFile)))
+		}
+		label, newPaths := partPaths(file, title, year, oldPaths, newFolderPath)
+
+		if err := fileRenamer.MoveMovieParts(oldPaths, newPaths); err != nil {
+			interactive.PrintWarning(fmt.Sprintf("Failed to rename parts: %v", err))
+		} else if mainVideoFile != "" {
+			hashedPath = newPaths[0]
+		}
+		writeMovieNFO(movieDetails, filepath.Join(newFolderPath, file.GetMovieVersionFilename(title, year, label, ".nfo")))
+		return nil
+	}
+
+	// Several cuts or qualities of the movie: give each file its own multi-version name
+	if len(file.Versions) > 1 {
+		labels := scanner.AssignVersionLabels(file.Versions)
+		for i, version := range file.Versions {
+			oldFileName := filepath.Base(version.Path)
+			newFileName := file.GetMovieVersionFilename(title, year, labels[i], filepath.Ext(version.Path))
+
+			if err := renameVideo(oldFileName, newFileName); err != nil {
+				interactive.PrintWarning(fmt.Sprintf("Failed to rename video file %s: %v", oldFileName, err))
+			}
+			writeMovieNFO(movieDetails, nfo.PathFor(filepath.Join(newFolderPath, newFileName)))
+		}
+		return nil
+	}
+
 	if mainVideoFile != "" {
 		newFileName := file.GetMovieFilename(title, year)
-		newFolderPath := filepath.Join(targetDir, newFolderName)
 
-		if err := fileRenamer.RenameMovieFileInFolder(newFolderPath, mainVideoFile, newFileName); err != nil {
+		if err := renameVideo(mainVideoFile, newFileName); err != nil {
 			interactive.PrintWarning(fmt.Sprintf("Failed to rename video file: %v", err))
 		}
+		if !file.IsBluRay && !file.IsDVD {
+			writeMovieNFO(movieDetails, nfo.PathFor(filepath.Join(newFolderPath, newFileName)))
+		}
 	}
 
 	return nil
@@ -525,13 +1230,177 @@ func processStandaloneMovie(file *scanner.MediaFile, movieDetails *api.UnifiedMo
 	if targetDir == "" {
 		targetDir = filepath.Dir(file.Path)
 	}
+	targetDir = collectionDir(targetDir, movieDetails)
+
+	// Another copy of this movie already lives in the target folder: give this one a version name
+	if _, err := os.Stat(filepath.Join(targetDir, folderName, newFilename)); err == nil {
+		newFilename = freeVersionFilename(file, title, year, filepath.Join(targetDir, folderName))
+	}
+
+	// Stacked multi-part movie: every part gets its Kodi stacking suffix and the same version name
+	var partLabel string
+	var partNewPaths []string
+	if len(file.PartFiles) > 1 {
+		partLabel, partNewPaths = partPaths(file, title, year, file.PartFiles, filepath.Join(targetDir, folderName))
+		newFilename = filepath.Base(partNewPaths[0])
+	}
 
 	if !autoMode && !dryRun {
 		if !interactive.Confirm(fmt.Sprintf("Create folder '%s' and move/rename to '%s'?", folderName, newFilename)) {
 			interactive.PrintInfo("Skipped")
-			return nil
+			return errSkipped
+		}
+	}
+
+	// Video inside an archive set: extract it into the movie folder instead of moving it
+	if file.Archive != nil {
+		err := extractArchived(file, filepath.Join(targetDir, folderName), newFilename, fileRenamer)
+		fmt.Println()
+		if err == nil {
+			writeMovieNFO(movieDetails, nfo.PathFor(filepath.Join(targetDir, folderName, newFilename)))
+		}
+		return err
+	}
+
+	// Stacked multi-part movie: move every part into the same folder, all parts or none
+	if len(file.PartFiles) > 1 {
+		if err := fileRenamer.MoveMovieParts(file.PartFiles, partNewPaths); err != nil {
+			return err
 		}
+	} else if err := fileRenamer.MoveRenameMovieFile(file.Path, targetDir, folderName, newFilename); err != nil {
+		return err
+	}
+	// The movie now lives in its own folder, which the next scan finds as a movie folder
+	file.RenamedPath = filepath.Join(targetDir, folderName)
+	recordMovieHash(file, movieDetails, filepath.Join(targetDir, folderName, newFilename))
+	if len(file.PartFiles) > 1 {
+		writeMovieNFO(movieDetails, filepath.Join(targetDir, folderName, file.GetMovieVersionFilename(title, year, partLabel, ".nfo")))
+	} else {
+		writeMovieNFO(movieDetails, nfo.PathFor(filepath.Join(targetDir, folderName, newFilename)))
 	}
 
-	return fileRenamer.MoveRenameMovieFile(file.Path, targetDir, folderName, newFilename)
+	organizeExtras(file.Extras, filepath.Join(targetDir, folderName), fileRenamer)
+	return nil
+}
+
+// freeVersionFilename returns a multi-version name for a movie whose plain name is taken in dir: its
+// edition and quality label, numbered ("Version 2" when it has none) while that name is taken too
+func freeVersionFilename(file *scanner.MediaFile, title, year, dir string) string {
+	label := freeVersionLabel(file, func(label string) bool {
+		return !fileExists(filepath.Join(dir, file.GetMovieVersionFilename(title, year, label, file.Extension)))
+	})
+	return file.GetMovieVersionFilename(title, year, label, file.Extension)
+}
+
+// partPaths returns the version label and the destination in dir of each part of a multi-part movie,
+// given where the parts currently are. When one of the stacking names is taken by another copy, every
+// part gets the same free version label so that the parts never run into it.
+func partPaths(file *scanner.MediaFile, title, year string, parts []string, dir string) (string, []string) {
+	paths := func(label string) []string {
+		result := make([]string, 0, len(parts))
+		for i, part := range parts {
+			result = append(result, filepath.Join(dir, file.GetMovieVersionPartFilename(title, year, label, i+1, filepath.Ext(part))))
+		}
+		return result
+	}
+	free := func(label string) bool {
+		for i, path := range paths(label) {
+			// A part already carrying its name does not collide with itself
+			if path != parts[i] && fileExists(path) {
+				return false
+			}
+		}
+		return true
+	}
+
+	label := file.Edition
+	if !free(label) {
+		label = freeVersionLabel(file, free)
+	}
+	return label, paths(label)
+}
+
+// freeVersionLabel returns the first multi-version label of a movie accepted by free: its edition and
+// quality label, then that label numbered ("Version 2" when it has none)
+func freeVersionLabel(file *scanner.MediaFile, free func(label string) bool) string {
+	label := scanner.MovieVersion{Edition: file.Edition, Version: file.Version}.Label()
+	if label != "" {
+		if free(label) {
+			return label
+		}
+	} else {
+		label = "Version"
+	}
+	for n := 2; ; n++ {
+		if numbered := fmt.Sprintf("%s %d", label, n); free(numbered) {
+			return numbered
+		}
+	}
+}
+
+// fileExists reports whether a path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// collectionDir returns the folder a movie goes to with -collection-folders: a subfolder of targetDir
+// named after its collection, unless targetDir already is that folder
+func collectionDir(targetDir string, movieDetails *api.UnifiedMovieProposition) string {
+	if !collectionDirs || movieDetails.CollectionName == "" {
+		return targetDir
+	}
+	name := utils.SanitizeFilename(movieDetails.CollectionName)
+	if name == "" || filepath.Base(targetDir) == name {
+		return targetDir
+	}
+	return filepath.Join(targetDir, name)
+}
+
+// writeMovieNFO writes the NFO of a renamed movie when -nfo is set
+func writeMovieNFO(movieDetails *api.UnifiedMovieProposition, path string) {
+	if !writeNFO || dryRun {
+		return
+	}
+	written, err := nfo.WriteMovie(path, nfo.NewMovie(movieDetails, nfoCountry))
+	if err != nil {
+		interactive.PrintWarning(fmt.Sprintf("Failed to write NFO: %v", err))
+		return
+	}
+	if written {
+		interactive.PrintInfo(fmt.Sprintf("Wrote NFO: %s", filepath.Base(path)))
+	} else {
+		interactive.PrintInfo(fmt.Sprintf("Kept existing NFO: %s", filepath.Base(path)))
+	}
+}
+
+// organizeExtras applies the extras policy: extras go to Kodi-recognised subfolders of the movie folder
+// (trailers/ for trailers, extras/ for everything else) and samples are optionally deleted
+func organizeExtras(extras []scanner.ExtraFile, movieFolderPath string, fileRenamer *renamer.Renamer) {
+	if extrasPolicy == extrasPolicyKeep {
+		return
+	}
+
+	for _, extra := range extras {
+		if extra.Type == scanner.ExtraSample && extrasPolicy == extrasPolicyDropSamples {
+			if err := fileRenamer.RemoveFile(extra.Path); err != nil {
+				interactive.PrintWarning(fmt.Sprintf("Failed to delete sample %s: %v", extra.Path, err))
+			}
+			fileRenamer.RemoveEmptyDir(filepath.Dir(extra.Path))
+			continue
+		}
+
+		destDir := filepath.Join(movieFolderPath, extra.KodiFolder())
+		if filepath.Dir(extra.Path) == destDir {
+			continue
+		}
+
+		if err := fileRenamer.MoveFileToFolder(extra.Path, destDir, filepath.Base(extra.Path)); err != nil {
+			interactive.PrintWarning(fmt.Sprintf("Failed to move extra %s: %v", extra.Path, err))
+			continue
+		}
+		if filepath.Dir(extra.Path) != movieFolderPath {
+			fileRenamer.RemoveEmptyDir(filepath.Dir(extra.Path))
+		}
+	}
 }
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"kodi-renamer/internal/api"
+	"kodi-renamer/internal/apitest"
+	"kodi-renamer/internal/hashdb"
+	"kodi-renamer/internal/renamer"
+	"kodi-renamer/internal/scanner"
+	"kodi-renamer/internal/scanstate"
+	"kodi-renamer/internal/ui"
+)
+
+// setupFlow points the processing flows at a fake provider server, answering prompts with input
+// (auto mode when input is empty), and restores the global options when the test ends
+func setupFlow(t *testing.T, input string) (*apitest.Server, *api.Manager) {
+	t.Helper()
+
+	savedAuto, savedDryRun, savedInteractive := autoMode, dryRun, interactive
+	savedHashDB, savedScanState := hashDB, scanState
+	savedDetailRows, savedNFO, savedCollectionDirs := detailRows, writeNFO, collectionDirs
+	t.Cleanup(func() {
+		autoMode, dryRun, interactive = savedAuto, savedDryRun, savedInteractive
+		hashDB, scanState = savedHashDB, savedScanState
+		detailRows, writeNFO, collectionDirs = savedDetailRows, savedNFO, savedCollectionDirs
+	})
+
+	autoMode = input == ""
+	dryRun = false
+	interactive = ui.NewInteractiveWithInput(strings.NewReader(input))
+	hashDB = nil
+	scanState = nil
+
+	server := apitest.NewServer(t)
+	manager := api.NewManager(context.Background(), api.Config{
+		TVDBAPIKey:  apitest.TVDBAPIKey,
+		TMDBAPIKey:  apitest.TMDBAPIKey,
+		TVDBBaseURL: server.TVDBURL(),
+		TMDBBaseURL: server.TMDBURL(),
+	})
+	return server, manager
+}
+
+// writeFiles creates empty files at the given paths below root
+func writeFiles(t *testing.T, root string, paths ...string) {
+	t.Helper()
+	for _, p := range paths {
+		full := filepath.Join(root, p)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(p), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// scanKind scans an inbox and returns its media files of one kind
+func scanKind(t *testing.T, dir string, kind scanner.MediaKind) []*scanner.MediaFile {
+	t.Helper()
+	mediaFiles, _, err := scanInbox(dir)
+	if err != nil {
+		t.Fatalf("scanInbox() error = %v", err)
+	}
+	return filterKind(mediaFiles, kind)
+}
+
+// assertFiles fails unless every path exists below root
+func assertFiles(t *testing.T, root string, paths ...string) {
+	t.Helper()
+	for _, p := range paths {
+		if _, err := os.Stat(filepath.Join(root, p)); err != nil {
+			t.Errorf("expected %s: %v", p, err)
+		}
+	}
+}
+
+func TestProcessMovieAutoMode(t *testing.T) {
+	_, manager := setupFlow(t, "")
+	inbox, output := t.TempDir(), t.TempDir()
+	writeFiles(t, inbox, "The.Matrix.1999.1080p.BluRay.x264.mkv")
+
+	movies := scanKind(t, inbox, scanner.KindMovie)
+	if len(movies) != 1 {
+		t.Fatalf("scanned %d movie(s), want 1", len(movies))
+	}
+
+	if err := processMovie(context.Background(), movies[0], manager, interactive, renamer.NewRenamer(false), output); err != nil {
+		t.Fatalf("processMovie() error = %v", err)
+	}
+
+	assertFiles(t, output, filepath.Join("The Matrix (1999)", "The Matrix (1999).mkv"))
+	if _, err := os.Stat(movies[0].Path); !os.IsNotExist(err) {
+		t.Errorf("original file still present: %v", err)
+	}
+}
+
+func TestProcessMovieCopyWithoutVersionLabel(t *testing.T) {
+	_, manager := setupFlow(t, "")
+	inbox, output := t.TempDir(), t.TempDir()
+	existing := filepath.Join("The Matrix (1999)", "The Matrix (1999).mkv")
+	writeFiles(t, output, existing)
+	writeFiles(t, inbox, "The.Matrix.1999.mkv")
+
+	movies := scanKind(t, inbox, scanner.KindMovie)
+	if len(movies) != 1 || movies[0].Version != "" {
+		t.Fatalf("scanned %+v, want one movie without version label", movies)
+	}
+
+	if err := processMovie(context.Background(), movies[0], manager, interactive, renamer.NewRenamer(false), output); err != nil {
+		t.Fatalf("processMovie() error = %v", err)
+	}
+
+	assertFiles(t, output, existing, filepath.Join("The Matrix (1999)", "The Matrix (1999) - Version 2.mkv"))
+	if data, _ := os.ReadFile(filepath.Join(output, existing)); string(data) != existing {
+		t.Errorf("existing copy was overwritten: %q", data)
+	}
+}
+
+func TestProcessStackedMovieCopyGetsVersionName(t *testing.T) {
+	_, manager := setupFlow(t, "")
+	inbox, output := t.TempDir(), t.TempDir()
+	existing := filepath.Join("The Matrix (1999)", "The Matrix (1999)-cd1.avi")
+	writeFiles(t, output, existing)
+	writeFiles(t, inbox, "The.Matrix.1999.CD1.avi", "The.Matrix.1999.CD2.avi")
+
+	movies := scanKind(t, inbox, scanner.KindMovie)
+	if len(movies) != 1 || len(movies[0].PartFiles) != 2 {
+		t.Fatalf("scanned %+v, want one stacked movie", movies)
+	}
+
+	if err := processMovie(context.Background(), movies[0], manager, interactive, renamer.NewRenamer(false), output); err != nil {
+		t.Fatalf("processMovie() error = %v", err)
+	}
+
+	assertFiles(t, output, existing,
+		filepath.Join("The Matrix (1999)", "The Matrix (1999) - Version 2-cd1.avi"),
+		filepath.Join("The Matrix (1999)", "The Matrix (1999) - Version 2-cd2.avi"),
+	)
+}
+
+func TestRenamedMovieIsSkippedNextRun(t *testing.T) {
+	_, manager := setupFlow(t, "")
+	store, err := scanstate.Open(filepath.Join(t.TempDir(), "scan-state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanState = store
+	inbox := t.TempDir()
+	writeFiles(t, inbox, "The.Matrix.1999.1080p.BluRay.x264.mkv")
+
+	movies := scanKind(t, inbox, scanner.KindMovie)
+	if len(movies) != 1 {
+		t.Fatalf("scanned %d movie(s), want 1", len(movies))
+	}
+	// Renamed in place, into a movie folder of the inbox
+	err = processMovie(context.Background(), movies[0], manager, interactive, renamer.NewRenamer(false), "")
+	if err != nil {
+		t.Fatalf("processMovie() error = %v", err)
+	}
+	recordScanDecision(movies[0], err)
+
+	newPath := filepath.Join(inbox, "The Matrix (1999)")
+	if _, skip := scanState.ShouldSkip(newPath); !skip {
+		t.Errorf("ShouldSkip(%s) = false after the movie was renamed there", newPath)
+	}
+	if pending := filterHandled(scanKind(t, inbox, scanner.KindMovie)); len(pending) != 0 {
+		t.Errorf("next run presents %d renamed movie(s) again", len(pending))
+	}
+}
+
+func TestProcessMovieFolderRecordsVideoPath(t *testing.T) {
+	_, manager := setupFlow(t, "")
+	dbPath := filepath.Join(t.TempDir(), "hashes.json")
+	store, err := hashdb.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashDB = store
+	inbox, output := t.TempDir(), t.TempDir()
+	writeFiles(t, inbox, "The.Matrix.1999.1080p/The.Matrix.1999.1080p.mkv", "The.Matrix.1999.1080p/The.Matrix.1999.1080p.srt")
+
+	movies := scanKind(t, inbox, scanner.KindMovie)
+	if len(movies) != 1 || !movies[0].IsMovieFolder {
+		t.Fatalf("scanned %+v, want one movie folder", movies)
+	}
+	movies[0].Hash, movies[0].FileSize = "8e245d9679d31e12", 12909756
+	if err := processMovie(context.Background(), movies[0], manager, interactive, renamer.NewRenamer(false), output); err != nil {
+		t.Fatalf("processMovie() error = %v", err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []hashdb.Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(output, "The Matrix (1999)", "The Matrix (1999).mkv")
+	if len(entries) != 1 || entries[0].Path != want {
+		t.Errorf("hash database = %+v, want the path %s", entries, want)
+	}
+}
+
+func TestProcessMovieIntoCollectionFolder(t *testing.T) {
+	// TheTVDB details have no collection: it comes from the TMDB metadata through the remote ID of the result
+	_, manager := setupFlow(t, "")
+	writeNFO, collectionDirs = true, true
+	inbox, output := t.TempDir(), t.TempDir()
+	writeFiles(t, inbox, "The.Matrix.1999.1080p.BluRay.x264.mkv")
+
+	movies := scanKind(t, inbox, scanner.KindMovie)
+	if err := processMovie(context.Background(), movies[0], manager, interactive, renamer.NewRenamer(false), output); err != nil {
+		t.Fatalf("processMovie() error = %v", err)
+	}
+
+	movieDir := filepath.Join("The Matrix Collection", "The Matrix (1999)")
+	assertFiles(t, output,
+		filepath.Join(movieDir, "The Matrix (1999).mkv"),
+		filepath.Join(movieDir, "The Matrix (1999).nfo"))
+
+	data, err := os.ReadFile(filepath.Join(output, movieDir, "The Matrix (1999).nfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<name>The Matrix Collection</name>",
+		`<uniqueid type="tmdb">603</uniqueid>`,
+		"<mpaa>R</mpaa>",
+		"<director>Lana Wachowski</director>",
+		"<name>Keanu Reeves</name>",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("NFO lacks %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestProcessMovieInteractiveSelection(t *testing.T) {
+	// The Matrix is found by both providers and shown once: pick it and confirm the move
+	server, manager := setupFlow(t, "1\ny\n")
+	inbox := t.TempDir()
+	writeFiles(t, inbox, "The.Matrix.1999.mkv")
+
+	movies := scanKind(t, inbox, scanner.KindMovie)
+	if err := processMovie(context.Background(), movies[0], manager, interactive, renamer.NewRenamer(false), ""); err != nil {
+		t.Fatalf("processMovie() error = %v", err)
+	}
+
+	assertFiles(t, inbox, filepath.Join("The Matrix (1999)", "The Matrix (1999).mkv"))
+	if !slices.Contains(server.Requests(), "tvdb GET /movies/169") {
+		t.Errorf("TVDB movie 169 was never fetched: %v", server.Requests())
+	}
+}
+
+func TestProcessMovieShowsMoreRowsOnRequest(t *testing.T) {
+	// One row at a time: show a second row, then pick the first one
+	server, manager := setupFlow(t, "m\n1\ny\n")
+	detailRows = 1
+	inbox := t.TempDir()
+	writeFiles(t, inbox, "The.Matrix.1999.mkv")
+
+	movies := scanKind(t, inbox, scanner.KindMovie)
+	if err := processMovie(context.Background(), movies[0], manager, interactive, renamer.NewRenamer(false), ""); err != nil {
+		t.Fatalf("processMovie() error = %v", err)
+	}
+
+	assertFiles(t, inbox, filepath.Join("The Matrix (1999)", "The Matrix (1999).mkv"))
+	requests := server.Requests()
+	if !slices.Contains(requests, "tvdb GET /movies/553") {
+		t.Errorf("the second row was never detailed: %v", requests)
+	}
+	if slices.Contains(requests, "tmdb GET /movie/624860") {
+		t.Errorf("the third row was detailed without being asked for: %v", requests)
+	}
+}
+
+func TestProcessMovieWithoutResultsIsSkipped(t *testing.T) {
+	_, manager := setupFlow(t, "")
+	inbox, output := t.TempDir(), t.TempDir()
+	writeFiles(t, inbox, "Some.Unknown.Home.Video.2020.mkv")
+
+	movies := scanKind(t, inbox, scanner.KindMovie)
+	err := processMovie(context.Background(), movies[0], manager, interactive, renamer.NewRenamer(false), output)
+	if !errors.Is(err, errSkipped) {
+		t.Fatalf("processMovie() error = %v, want errSkipped", err)
+	}
+	assertFiles(t, inbox, "Some.Unknown.Home.Video.2020.mkv")
+}
+
+func TestProcessSeriesBatchToOutputDir(t *testing.T) {
+	_, manager := setupFlow(t, "")
+	inbox, output := t.TempDir(), t.TempDir()
+	writeFiles(t, inbox,
+		"Breaking.Bad/Breaking.Bad.S01E01.720p.HDTV.mkv",
+		"Breaking.Bad/Breaking.Bad.S01E02.720p.HDTV.mkv",
+	)
+
+	episodes := scanKind(t, inbox, scanner.KindSeries)
+	if len(episodes) != 2 {
+		t.Fatalf("scanned %d episode(s), want 2", len(episodes))
+	}
+
+	_, err := processSeriesBatch(context.Background(), episodes[0].ParentDir, episodes, manager, interactive, renamer.NewRenamer(false), output)
+	if err != nil {
+		t.Fatalf("processSeriesBatch() error = %v", err)
+	}
+
+	assertFiles(t, output,
+		filepath.Join("Breaking Bad (2008)", "Breaking Bad S01E01 - Pilot.mkv"),
+		filepath.Join("Breaking Bad (2008)", "Breaking Bad S01E02 - Cat's in the Bag.mkv"),
+	)
+}
+
+func TestProcessSeriesBatchInPlace(t *testing.T) {
+	_, manager := setupFlow(t, "")
+	inbox := t.TempDir()
+	writeFiles(t, inbox,
+		"Breaking.Bad/Breaking.Bad.S01E01.mkv",
+		"Breaking.Bad/Breaking.Bad.S01E03.mkv",
+	)
+
+	episodes := scanKind(t, inbox, scanner.KindSeries)
+	if len(episodes) != 2 {
+		t.Fatalf("scanned %d episode(s), want 2", len(episodes))
+	}
+	_, err := processSeriesBatch(context.Background(), episodes[0].ParentDir, episodes, manager, interactive, renamer.NewRenamer(false), "")
+	if err != nil {
+		t.Fatalf("processSeriesBatch() error = %v", err)
+	}
+
+	// Dots around episode titles are trimmed by SanitizeFilename
+	assertFiles(t, inbox,
+		filepath.Join("Breaking Bad (2008)", "Breaking Bad S01E01 - Pilot.mkv"),
+		filepath.Join("Breaking Bad (2008)", "Breaking Bad S01E03 - And the Bag's in the River.mkv"),
+	)
+	if _, err := os.Stat(filepath.Join(inbox, "Breaking.Bad")); !os.IsNotExist(err) {
+		t.Errorf("original series folder still present: %v", err)
+	}
+}
+
+func TestProcessSeriesRecordsFailedEpisodes(t *testing.T) {
+	_, manager := setupFlow(t, "")
+	store, err := scanstate.Open(filepath.Join(t.TempDir(), "scan-state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanState = store
+	inbox := t.TempDir()
+	writeFiles(t, inbox,
+		"Breaking.Bad/Breaking.Bad.S01E01.mkv",
+		"Breaking.Bad/Breaking.Bad.S01E99.mkv",
+		// Makes the series folder rename fail
+		"Breaking Bad (2008)/keep.txt",
+	)
+
+	episodes := scanKind(t, inbox, scanner.KindSeries)
+	if len(episodes) != 2 {
+		t.Fatalf("scanned %d episode(s), want 2", len(episodes))
+	}
+	processSeries(context.Background(), episodes, manager, renamer.NewRenamer(false))
+
+	renamedPath := filepath.Join(inbox, "Breaking.Bad", "Breaking Bad S01E01 - Pilot.mkv")
+	if entry, ok := scanState.Lookup(renamedPath); !ok || entry.Decision != scanstate.DecisionRenamed {
+		t.Errorf("Lookup(%s) = %+v, %v; want renamed in the original folder", renamedPath, entry, ok)
+	}
+	failedPath := filepath.Join(inbox, "Breaking.Bad", "Breaking.Bad.S01E99.mkv")
+	if entry, ok := scanState.Lookup(failedPath); !ok || entry.Decision != scanstate.DecisionFailed || entry.Error == "" {
+		t.Errorf("Lookup(%s) = %+v, %v; want failed with its error", failedPath, entry, ok)
+	}
+}
+
+func TestProcessSeriesBatchFallsBackToEpisodeName(t *testing.T) {
+	_, manager := setupFlow(t, "")
+	inbox, output := t.TempDir(), t.TempDir()
+	writeFiles(t, inbox,
+		"Breaking.Bad.S01/Breaking.Bad.S01E01.mkv",
+		"Breaking.Bad.S01/Breaking.Bad.S01E02.mkv",
+	)
+
+	// "Breaking Bad S01" finds nothing, the name of the episodes does
+	episodes := scanKind(t, inbox, scanner.KindSeries)
+	if len(episodes) != 2 {
+		t.Fatalf("scanned %d episode(s), want 2", len(episodes))
+	}
+	_, err := processSeriesBatch(context.Background(), episodes[0].ParentDir, episodes, manager, interactive, renamer.NewRenamer(false), output)
+	if err != nil {
+		t.Fatalf("processSeriesBatch() error = %v", err)
+	}
+
+	assertFiles(t, output, filepath.Join("Breaking Bad (2008)", "Breaking Bad S01E01 - Pilot.mkv"))
+	if episodes[0].SearchVariant != "'Breaking Bad' (alternative name)" {
+		t.Errorf("SearchVariant = %q, want the alternative name", episodes[0].SearchVariant)
+	}
+}
+
+func TestProcessSeriesBatchMatchesEpisodesByAirDate(t *testing.T) {
+	_, manager := setupFlow(t, "")
+	inbox, output := t.TempDir(), t.TempDir()
+	writeFiles(t, inbox,
+		"Breaking Bad/Breaking.Bad.2008.01.27.mkv",
+		"Breaking Bad/Breaking.Bad.S01E01.mkv",
+	)
+
+	episodes := scanKind(t, inbox, scanner.KindSeries)
+	if len(episodes) != 2 {
+		t.Fatalf("scanned %d episode(s), want 2", len(episodes))
+	}
+	_, err := processSeriesBatch(context.Background(), episodes[0].ParentDir, episodes, manager, interactive, renamer.NewRenamer(false), output)
+	if err != nil {
+		t.Fatalf("processSeriesBatch() error = %v", err)
+	}
+
+	assertFiles(t, output,
+		filepath.Join("Breaking Bad (2008)", "Breaking Bad S01E01 - Pilot.mkv"),
+		filepath.Join("Breaking Bad (2008)", "Breaking Bad S01E02 - Cat's in the Bag.mkv"))
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"kodi-renamer/internal/scanner"
+	"kodi-renamer/internal/scanstate"
+)
+
+var (
+	// errSkipped reports an item the user skipped or for which nothing was found
+	errSkipped = errors.New("skipped")
+	// errIgnored reports an item the user never wants to be asked about again
+	errIgnored = errors.New("ignored forever")
+)
+
+// isSkip reports whether a processing error is a user decision rather than a failure
+func isSkip(err error) bool {
+	return errors.Is(err, errSkipped) || errors.Is(err, errIgnored)
+}
+
+// runStateCommand runs the -state-list, -state-reset and -state-forget commands, returning
+// false when none was requested
+func runStateCommand() (bool, error) {
+	if !stateList && !stateReset && stateForget == "" {
+		return false, nil
+	}
+	if stateFile == "" {
+		return true, fmt.Errorf("no scan state file configured (-state-file)")
+	}
+
+	store, err := scanstate.Open(stateFile)
+	if err != nil {
+		return true, err
+	}
+
+	switch {
+	case stateReset:
+		fmt.Printf("Removed %d scan state entr(ies)\n", store.Reset())
+	case stateForget != "":
+		fmt.Printf("Removed %d scan state entr(ies) under %s\n", store.Forget(stateForget), stateForget)
+	}
+
+	if stateList {
+		entries := store.Entries()
+		if len(entries) == 0 {
+			fmt.Println("No scan state entries")
+		}
+		for _, entry := range entries {
+			fmt.Printf("%-8s  %-12s  %s  %s\n", entry.Decision, entry.Kind, entry.UpdatedAt.Format("2006-01-02 15:04"), entry.Path)
+			if entry.Error != "" {
+				fmt.Printf("          error: %s\n", entry.Error)
+			}
+			if entry.SearchVariant != "" {
+				fmt.Printf("          found by: %s\n", entry.SearchVariant)
+			}
+		}
+	}
+
+	return true, store.Save()
+}
+
+// openScanState loads the scan state used to skip items handled in previous runs
+func openScanState() {
+	if stateFile == "" {
+		return
+	}
+	store, err := scanstate.Open(stateFile)
+	if err != nil {
+		interactive.PrintWarning(fmt.Sprintf("Scan state disabled: %v", err))
+		return
+	}
+	scanState = store
+}
+
+// saveScanState writes the scan state back to disk
+func saveScanState() {
+	if scanState == nil {
+		return
+	}
+	if err := scanState.Save(); err != nil {
+		interactive.PrintWarning(fmt.Sprintf("Failed to save scan state: %v", err))
+	}
+}
+
+// filterHandled drops the items handled in a previous run that have not changed since
+// (or were ignored forever), unless -rescan is set
+func filterHandled(files []*scanner.MediaFile) []*scanner.MediaFile {
+	if scanState == nil || rescan {
+		return files
+	}
+
+	pending := make([]*scanner.MediaFile, 0, len(files))
+	handled := 0
+	for _, file := range files {
+		if _, skip := scanState.ShouldSkip(file.Path); skip {
+			handled++
+			continue
+		}
+		pending = append(pending, file)
+	}
+
+	if handled > 0 {
+		interactive.PrintInfo(fmt.Sprintf("Skipping %d item(s) handled in a previous run (use -rescan to include them)", handled))
+	}
+	return pending
+}
+
+// recordScanDecision stores the outcome of processing an item in the scan state
+func recordScanDecision(file *scanner.MediaFile, err error) {
+	if scanState == nil || dryRun {
+		return
+	}
+
+	kind := "episode"
+	if file.IsMovieFolder {
+		kind = "movie-folder"
+	} else if file.IsMovie {
+		kind = "movie"
+	}
+
+	// A renamed item is gone from its original path: remember it where the next scan finds it
+	path := file.Path
+	if err == nil && file.RenamedPath != "" {
+		path = file.RenamedPath
+	}
+
+	switch {
+	case err == nil:
+		scanState.Record(path, kind, scanstate.DecisionRenamed, "")
+	case errors.Is(err, errIgnored):
+		scanState.Record(path, kind, scanstate.DecisionIgnored, "")
+	case errors.Is(err, errSkipped):
+		scanState.Record(path, kind, scanstate.DecisionSkipped, "")
+	default:
+		scanState.Record(path, kind, scanstate.DecisionFailed, err.Error())
+	}
+	if file.SearchVariant != "" {
+		scanState.SetSearchVariant(path, file.SearchVariant)
+	}
+}
+
+// exitOnStateCommand runs a scan state command if one was requested and exits
+func exitOnStateCommand() {
+	ran, err := runStateCommand()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if ran {
+		os.Exit(0)
+	}
+}
@@ -10,18 +10,41 @@ import (
 
 func main() {
 	directory := flag.String("dir", ".", "Directory to scan")
+	followSymlinks := flag.Bool("follow-symlinks", false, "Descend into symlinked directories")
+	workers := flag.Int("workers", scanner.DefaultScanConcurrency, "Number of directories read in parallel")
 	flag.Parse()
 
 	fmt.Println("=== Kodi Renamer - Scanner Test ===")
 	fmt.Printf("Scanning: %s\n\n", *directory)
 
 	s := scanner.NewScanner(*directory)
+	s.SetConcurrency(*workers)
+	s.SetFollowSymlinks(*followSymlinks)
 	files, err := s.ScanDirectory()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
+	progress := s.Progress()
+	fmt.Printf("Read %d director(ies), %d file(s)\n\n", progress.Directories, progress.Files)
+
+	if skipped := s.Skipped(); len(skipped) > 0 {
+		fmt.Printf("Skipped %d file(s)/folder(s):\n", len(skipped))
+		for _, skip := range skipped {
+			fmt.Printf("  %s: %s\n", skip.Path, skip.Reason)
+		}
+		fmt.Println()
+	}
+
+	if dangling := s.DanglingLinks(); len(dangling) > 0 {
+		fmt.Printf("Dangling symlinks (%d):\n", len(dangling))
+		for _, link := range dangling {
+			fmt.Printf("  %s -> %s\n", link.Path, link.Target)
+		}
+		fmt.Println()
+	}
+
 	if len(files) == 0 {
 		fmt.Println("No media files found.")
 		return
@@ -41,10 +64,30 @@ func main() {
 			if file.Year > 0 {
 				fmt.Printf("    Year: %d\n", file.Year)
 			}
+			if file.Edition != "" {
+				fmt.Printf("    Edition: %s\n", file.Edition)
+			}
+			if len(file.PartFiles) > 1 {
+				fmt.Printf("    Parts: %d\n", len(file.PartFiles))
+			}
 		} else if file.IsSeries {
 			fmt.Printf("TV Series\n")
 			series++
-			fmt.Printf("    Season: %d, Episode: %d\n", file.Season, file.Episode)
+			if file.AirDate != "" {
+				fmt.Printf("    Air date: %s\n", file.AirDate)
+			} else {
+				fmt.Printf("    Season: %d, Episode: %d\n", file.Season, file.Episode)
+			}
+		}
+		if file.DiscTitle != "" {
+			fmt.Printf("    Disc Title: '%s'\n", file.DiscTitle)
+		}
+		if member := file.Archive; member != nil {
+			fmt.Printf("    Archive: %s (%d volume(s)), extractable: %v\n", member.Set.Volumes[0], len(member.Set.Volumes), member.Entry.Extractable)
+		}
+		if info := file.MediaInfo; info != nil {
+			fmt.Printf("    Media: %s %s %s, %d min, audio %v, subtitles %v\n",
+				info.Resolution(), info.VideoCodec, info.HDR, info.RuntimeMinutes(), info.AudioLanguages, info.SubtitleLanguages)
 		}
 		fmt.Printf("    Parsed Name: '%s'\n", file.CleanName)
 		fmt.Printf("    Search Query: '%s'\n", file.GetSearchQuery())
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultDetailWorkers is the default number of detail requests sent at once; each provider's rate
+// limiter still spaces the requests it receives
+const DefaultDetailWorkers = 4
+
+// DetailResult is the outcome of fetching the details of one search result
+type DetailResult[T any] struct {
+	Details T
+	Err     error
+}
+
+// FetchMovieDetails fetches the details of several search results with at most workers requests in
+// flight; results[i] belongs to props[i]. progress, when set, is called after each fetch from a single
+// goroutine with the number of fetches done so far.
+func (m *Manager) FetchMovieDetails(ctx context.Context, props []UnifiedProposition, workers int, progress func(done, total int)) []DetailResult[*UnifiedMovieProposition] {
+	return fetchAll(ctx, props, workers, progress, m.GetMovieDetails)
+}
+
+// FetchSeriesDetails fetches the details of several series search results like FetchMovieDetails
+func (m *Manager) FetchSeriesDetails(ctx context.Context, props []UnifiedProposition, workers int, progress func(done, total int)) []DetailResult[*UnifiedSeriesProposition] {
+	return fetchAll(ctx, props, workers, progress, m.GetSeriesDetails)
+}
+
+// fetchAll runs fetch for every result on a bounded pool of workers; results not fetched because the
+// context was cancelled carry its error
+func fetchAll[T any](ctx context.Context, props []UnifiedProposition, workers int, progress func(done, total int), fetch func(context.Context, UnifiedProposition) (T, error)) []DetailResult[T] {
+	results := make([]DetailResult[T], len(props))
+	if len(props) == 0 {
+		return results
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	workers = min(workers, len(props))
+
+	jobs := make(chan int)
+	done := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := ctx.Err(); err != nil {
+					results[i].Err = err
+				} else {
+					results[i].Details, results[i].Err = fetch(ctx, props[i])
+				}
+				done <- i
+			}
+		}()
+	}
+
+	go func() {
+		for i := range props {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(done)
+	}()
+
+	finished := 0
+	for range done {
+		finished++
+		if progress != nil {
+			progress(finished, len(props))
+		}
+	}
+	return results
+}
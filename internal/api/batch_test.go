@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchAllBoundsConcurrency(t *testing.T) {
+	props := make([]UnifiedProposition, 12)
+	for i := range props {
+		props[i].ID = strconv.Itoa(i)
+	}
+
+	var inFlight, peak atomic.Int32
+	var progressCalls []int
+	results := fetchAll(context.Background(), props, 3, func(done, total int) {
+		if total != len(props) {
+			t.Errorf("progress total = %d, want %d", total, len(props))
+		}
+		progressCalls = append(progressCalls, done)
+	}, func(_ context.Context, p UnifiedProposition) (string, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if p.ID == "7" {
+			return "", errors.New("not found")
+		}
+		return "details " + p.ID, nil
+	})
+
+	if peak.Load() > 3 {
+		t.Errorf("%d fetches ran at once, want at most 3", peak.Load())
+	}
+	for i, r := range results {
+		if i == 7 {
+			if r.Err == nil {
+				t.Error("result 7 has no error")
+			}
+			continue
+		}
+		if r.Err != nil || r.Details != "details "+strconv.Itoa(i) {
+			t.Errorf("result %d = %+v, want the details of result %d", i, r, i)
+		}
+	}
+	if len(progressCalls) != len(props) || progressCalls[len(progressCalls)-1] != len(props) {
+		t.Errorf("progress calls = %v, want 1 to %d", progressCalls, len(props))
+	}
+}
+
+func TestFetchAllStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	props := make([]UnifiedProposition, 10)
+
+	var calls atomic.Int32
+	results := fetchAll(ctx, props, 1, nil, func(context.Context, UnifiedProposition) (int, error) {
+		if calls.Add(1) == 2 {
+			cancel()
+		}
+		return 1, nil
+	})
+
+	if calls.Load() != 2 {
+		t.Errorf("fetch called %d times, want 2 (stopped once cancelled)", calls.Load())
+	}
+	if !errors.Is(results[9].Err, context.Canceled) {
+		t.Errorf("last result error = %v, want context.Canceled", results[9].Err)
+	}
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// FillCollection looks up the TMDB collection of a movie whose details came from another provider,
+// using its TMDB ID; movies without TMDB ID, or already with their collection, are left as they are
+func (m *Manager) FillCollection(ctx context.Context, movie *UnifiedMovieProposition) error {
+	if movie.Source == "tmdb" || movie.CollectionID != "" || !m.hasTMDB {
+		return nil
+	}
+	if movie.Metadata != nil && movie.Metadata.Source == "tmdb" {
+		// FillMetadata already asked TMDB: the movie has no collection
+		return nil
+	}
+	id := movie.UniqueIDs["tmdb"]
+	if id == "" {
+		return nil
+	}
+	movieID, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid TMDB ID: %w", err)
+	}
+
+	details, err := m.tmdbClient.GetMovie(ctx, movieID)
+	if err != nil {
+		return fmt.Errorf("failed to get TMDB collection: %w", err)
+	}
+	movie.CollectionID = formatOptionalID(details.CollectionID)
+	movie.CollectionName = details.CollectionName
+	return nil
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"kodi-renamer/internal/apitest"
+)
+
+func TestMovieCollection(t *testing.T) {
+	server := apitest.NewServer(t)
+	m := newTestManager(t, server)
+
+	movie, err := m.GetMovie(context.Background(), "603", "tmdb")
+	if err != nil {
+		t.Fatalf("GetMovie() error = %v", err)
+	}
+	if movie.CollectionID != "2344" || movie.CollectionName != "The Matrix Collection" {
+		t.Errorf("GetMovie() collection = %q %q, want 2344 The Matrix Collection", movie.CollectionID, movie.CollectionName)
+	}
+
+	// Details from TheTVDB: the collection is looked up on TMDB by the remote ID
+	tvdbMovie := &UnifiedMovieProposition{ID: "169", Source: "tvdb", UniqueIDs: map[string]string{"tvdb": "169", "tmdb": "603"}}
+	if err := m.FillCollection(context.Background(), tvdbMovie); err != nil {
+		t.Fatalf("FillCollection() error = %v", err)
+	}
+	if tvdbMovie.CollectionID != "2344" || tvdbMovie.CollectionName != "The Matrix Collection" {
+		t.Errorf("FillCollection() = %q %q, want 2344 The Matrix Collection", tvdbMovie.CollectionID, tvdbMovie.CollectionName)
+	}
+
+	// Without TMDB ID there is nothing to look up
+	requests := len(server.Requests())
+	unknown := &UnifiedMovieProposition{ID: "553", Source: "tvdb", UniqueIDs: map[string]string{"tvdb": "553"}}
+	if err := m.FillCollection(context.Background(), unknown); err != nil || unknown.CollectionName != "" {
+		t.Errorf("FillCollection() without TMDB ID = %q, %v", unknown.CollectionName, err)
+	}
+	if len(server.Requests()) != requests {
+		t.Error("FillCollection() without TMDB ID sent a request")
+	}
+}
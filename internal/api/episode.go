@@ -0,0 +1,153 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"kodi-renamer/internal/tmdb"
+	"kodi-renamer/internal/tvdb"
+)
+
+// tvdbEpisodeInfo converts a TheTVDB episode to the unified model
+func tvdbEpisodeInfo(seriesName string, ep tvdb.Episode) *UnifiedEpisodeInfo {
+	year := ep.Year
+	if year == "" && len(ep.Aired) >= 4 {
+		year = ep.Aired[:4]
+	}
+	return &UnifiedEpisodeInfo{
+		SeriesName:    seriesName,
+		SeasonNumber:  ep.SeasonNumber,
+		EpisodeNumber: ep.EpisodeNumber,
+		EpisodeName:   ep.Name,
+		Name:          ep.Name,
+		Year:          year,
+		AirDate:       ep.Aired,
+		Runtime:       ep.Runtime,
+		Overview:      ep.Overview,
+		Source:        "tvdb",
+	}
+}
+
+// tmdbEpisodeInfo converts a TMDB episode to the unified model
+func tmdbEpisodeInfo(ep *tmdb.EpisodeInfo) *UnifiedEpisodeInfo {
+	return &UnifiedEpisodeInfo{
+		SeriesName:    ep.SeriesName,
+		SeasonNumber:  ep.SeasonNumber,
+		EpisodeNumber: ep.EpisodeNumber,
+		EpisodeName:   ep.EpisodeName,
+		Name:          ep.EpisodeName,
+		Year:          ep.Year,
+		AirDate:       ep.AirDate,
+		Runtime:       ep.Runtime,
+		Overview:      ep.Overview,
+		Source:        "tmdb",
+	}
+}
+
+// HasAired reports whether the episode was broadcast by the day of now; episodes without air date
+// are assumed to have aired
+func (e *UnifiedEpisodeInfo) HasAired(now time.Time) bool {
+	return e.AirDate == "" || e.AirDate <= now.Format(time.DateOnly)
+}
+
+// GetEpisodesByAirDate retrieves the episodes of a series broadcast on a "YYYY-MM-DD" date, for daily
+// shows named by date; several episodes may share a date
+func (m *Manager) GetEpisodesByAirDate(ctx context.Context, id, source, airDate string) ([]UnifiedEpisodeInfo, error) {
+	var episodes []UnifiedEpisodeInfo
+	switch source {
+	case "tvdb":
+		if !m.hasTVDB {
+			return nil, fmt.Errorf("TVDB not configured")
+		}
+		found, err := m.tvdbClient.GetEpisodesByAirDate(ctx, id, airDate)
+		if err != nil {
+			return nil, err
+		}
+		if len(found) == 0 {
+			break
+		}
+		series, err := m.tvdbClient.GetSeries(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, ep := range found {
+			episodes = append(episodes, *tvdbEpisodeInfo(series.Name, ep))
+		}
+
+	case "tmdb":
+		if !m.hasTMDB {
+			return nil, fmt.Errorf("TMDB not configured")
+		}
+		seriesID, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TMDB ID: %w", err)
+		}
+		found, err := m.tmdbClient.GetEpisodesByAirDate(ctx, seriesID, airDate)
+		if err != nil {
+			return nil, err
+		}
+		for i := range found {
+			episodes = append(episodes, *tmdbEpisodeInfo(&found[i]))
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown source: %s", source)
+	}
+
+	if len(episodes) == 0 {
+		return nil, fmt.Errorf("no episode aired on %s", airDate)
+	}
+	return episodes, nil
+}
+
+// EpisodeHint describes a file claiming an episode, for telling apart episodes aired the same day
+type EpisodeHint struct {
+	// Name is the cleaned file name, which may hold the episode title
+	Name string
+	// Minutes is the duration of the file, 0 when unknown
+	Minutes int
+}
+
+// PickEpisode chooses among episodes aired the same day the one whose title appears in the file name,
+// then the one whose runtime is closest to the file duration, then the first one aired
+func PickEpisode(episodes []UnifiedEpisodeInfo, hint EpisodeHint) *UnifiedEpisodeInfo {
+	if len(episodes) == 0 {
+		return nil
+	}
+	fileWords := strings.Fields(normalizeTitle(hint.Name))
+
+	best := slices.MaxFunc(episodes, func(a, b UnifiedEpisodeInfo) int {
+		if c := cmp.Compare(titleCoverage(a.Name, fileWords), titleCoverage(b.Name, fileWords)); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(RuntimeMatchScore(hint.Minutes, a.Runtime), RuntimeMatchScore(hint.Minutes, b.Runtime)); c != 0 {
+			return c
+		}
+		// Earlier episodes win: compare b with a
+		if c := cmp.Compare(b.SeasonNumber, a.SeasonNumber); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.EpisodeNumber, a.EpisodeNumber)
+	})
+	return &best
+}
+
+// titleCoverage is the share of the words of an episode title found in the file name, between 0 and 1
+func titleCoverage(title string, fileWords []string) float64 {
+	words := strings.Fields(normalizeTitle(title))
+	if len(words) == 0 {
+		return 0
+	}
+	found := 0
+	for _, word := range words {
+		if slices.Contains(fileWords, word) {
+			found++
+		}
+	}
+	return float64(found) / float64(len(words))
+}
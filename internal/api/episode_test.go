@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kodi-renamer/internal/apitest"
+)
+
+func TestGetEpisodesByAirDate(t *testing.T) {
+	server := apitest.NewServer(t)
+	m := newTestManager(t, server)
+
+	for _, tt := range []struct{ id, source string }{{"81189", "tvdb"}, {"1396", "tmdb"}} {
+		episodes, err := m.GetEpisodesByAirDate(context.Background(), tt.id, tt.source, "2008-01-27")
+		if err != nil {
+			t.Errorf("GetEpisodesByAirDate(%s) error = %v", tt.source, err)
+			continue
+		}
+		if len(episodes) != 1 || episodes[0].SeasonNumber != 1 || episodes[0].EpisodeNumber != 2 || episodes[0].SeriesName != "Breaking Bad" {
+			t.Errorf("GetEpisodesByAirDate(%s) = %+v, want S01E02", tt.source, episodes)
+		}
+
+		if _, err := m.GetEpisodesByAirDate(context.Background(), tt.id, tt.source, "2008-01-28"); err == nil {
+			t.Errorf("GetEpisodesByAirDate(%s) of a day without episode succeeded, want an error", tt.source)
+		}
+	}
+}
+
+func TestPickEpisode(t *testing.T) {
+	// Two episodes of a daily show aired the same evening
+	episodes := []UnifiedEpisodeInfo{
+		{SeasonNumber: 2024, EpisodeNumber: 31, Name: "Jon Stewart", Runtime: 30},
+		{SeasonNumber: 2024, EpisodeNumber: 32, Name: "Extended Interview", Runtime: 60},
+	}
+
+	tests := []struct {
+		hint EpisodeHint
+		want int
+	}{
+		{EpisodeHint{Name: "The Daily Show"}, 31},
+		{EpisodeHint{Name: "The Daily Show Extended Interview"}, 32},
+		{EpisodeHint{Name: "The Daily Show", Minutes: 58}, 32},
+		// The title in the name wins over the runtime
+		{EpisodeHint{Name: "The Daily Show Jon Stewart", Minutes: 58}, 31},
+	}
+	for _, tt := range tests {
+		if got := PickEpisode(episodes, tt.hint); got.EpisodeNumber != tt.want {
+			t.Errorf("PickEpisode(%+v) = E%d, want E%d", tt.hint, got.EpisodeNumber, tt.want)
+		}
+	}
+	if PickEpisode(nil, EpisodeHint{}) != nil {
+		t.Error("PickEpisode() without episodes returned one")
+	}
+}
+
+func TestHasAired(t *testing.T) {
+	now := time.Date(2024, 3, 14, 20, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		airDate string
+		want    bool
+	}{
+		{"2024-03-13", true},
+		{"2024-03-14", true},
+		{"2024-03-15", false},
+		{"", true},
+	} {
+		episode := UnifiedEpisodeInfo{AirDate: tt.airDate}
+		if got := episode.HasAired(now); got != tt.want {
+			t.Errorf("HasAired() of an episode airing %q = %v, want %v", tt.airDate, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxSearchVariants bounds how many queries a fallback search sends before giving up
+const maxSearchVariants = 12
+
+// SearchVariant is one query of a fallback search chain
+type SearchVariant struct {
+	// Label tells how the query was derived, such as "without year"; empty for the original query
+	Label string
+	Query string
+	Year  int
+}
+
+// String describes the variant for display, like "'Matrix' (1999, without year)"
+func (v SearchVariant) String() string {
+	var details []string
+	if v.Year > 0 {
+		details = append(details, strconv.Itoa(v.Year))
+	}
+	if v.Label != "" {
+		details = append(details, v.Label)
+	}
+	if len(details) == 0 {
+		return fmt.Sprintf("'%s'", v.Query)
+	}
+	return fmt.Sprintf("'%s' (%s)", v.Query, strings.Join(details, ", "))
+}
+
+// FallbackResult holds the results of a fallback search and the variant that found them
+type FallbackResult struct {
+	Propositions []UnifiedProposition
+	Variant      SearchVariant
+	// Confident is set when a result has the variant's title and, when known, a year within one
+	Confident bool
+	// Tried lists every variant searched, in order
+	Tried []SearchVariant
+}
+
+// IsFallback reports whether the results came from another query than the original one
+func (r *FallbackResult) IsFallback() bool {
+	return r.Variant.Label != ""
+}
+
+// SearchMoviesWithFallback searches for a movie with the first query and year, then retries without
+// the year, with the year ±1, with the original-language titles of the results, with "&"/"and" and
+// roman/arabic numerals swapped, without leading article, and finally with the other queries (the
+// folder name instead of the file name, or vice versa). It stops at the first confident match; when
+// there is none, the results of the first variant that found anything are returned.
+func (m *Manager) SearchMoviesWithFallback(ctx context.Context, queries []string, year int) (*FallbackResult, error) {
+	return m.searchWithFallback(ctx, searchVariants(queries, year), m.SearchMovies)
+}
+
+// SearchSeriesWithFallback searches for a series like SearchMoviesWithFallback, without the year variants
+func (m *Manager) SearchSeriesWithFallback(ctx context.Context, queries []string) (*FallbackResult, error) {
+	return m.searchWithFallback(ctx, searchVariants(queries, 0), func(ctx context.Context, query string, _ int) ([]UnifiedProposition, error) {
+		return m.SearchSeries(ctx, query)
+	})
+}
+
+// searchWithFallback runs the variants in order until one has a confident match
+func (m *Manager) searchWithFallback(ctx context.Context, variants []SearchVariant, search func(context.Context, string, int) ([]UnifiedProposition, error)) (*FallbackResult, error) {
+	result := &FallbackResult{}
+	var first *FallbackResult
+	seen := make(map[string]bool)
+
+	for i := 0; i < len(variants) && len(result.Tried) < maxSearchVariants; i++ {
+		variant := variants[i]
+		key := strings.ToLower(variant.Query) + "|" + strconv.Itoa(variant.Year)
+		if strings.TrimSpace(variant.Query) == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		props, err := search(ctx, variant.Query, variant.Year)
+		if err != nil {
+			return nil, err
+		}
+		result.Tried = append(result.Tried, variant)
+		if len(props) == 0 {
+			continue
+		}
+
+		if isConfidentMatch(props, variant) {
+			result.Propositions = props
+			result.Variant = variant
+			result.Confident = true
+			return result, nil
+		}
+		if first == nil {
+			first = &FallbackResult{Propositions: props, Variant: variant}
+		}
+
+		// Results in another language may carry the title the file was named after
+		variants = insertVariants(variants, i+1, originalTitleVariants(props, variant))
+	}
+
+	if first != nil {
+		result.Propositions = first.Propositions
+		result.Variant = first.Variant
+	}
+	return result, nil
+}
+
+// insertVariants inserts extra variants at index i
+func insertVariants(variants []SearchVariant, i int, extra []SearchVariant) []SearchVariant {
+	if len(extra) == 0 {
+		return variants
+	}
+	out := make([]SearchVariant, 0, len(variants)+len(extra))
+	out = append(out, variants[:i]...)
+	out = append(out, extra...)
+	return append(out, variants[i:]...)
+}
+
+// originalTitleVariants returns a variant for each original title differing from the displayed one
+func originalTitleVariants(props []UnifiedProposition, variant SearchVariant) []SearchVariant {
+	var variants []SearchVariant
+	for _, p := range props {
+		if p.OriginalName == "" || normalizeTitle(p.OriginalName) == normalizeTitle(p.Title) {
+			continue
+		}
+		variants = append(variants, SearchVariant{Label: "original title", Query: p.OriginalName, Year: variant.Year})
+	}
+	return variants
+}
+
+// searchVariants lists the fallback chain of the queries; the year variants only apply to the first
+// query and only when the year is known
+func searchVariants(queries []string, year int) []SearchVariant {
+	var variants []SearchVariant
+	for i, query := range queries {
+		query = strings.TrimSpace(query)
+		if query == "" {
+			continue
+		}
+
+		label := ""
+		if i > 0 {
+			label = "alternative name"
+		}
+		variants = append(variants, SearchVariant{Label: label, Query: query, Year: year})
+
+		if i == 0 && year > 0 {
+			variants = append(variants,
+				SearchVariant{Label: "without year", Query: query},
+				SearchVariant{Label: "year -1", Query: query, Year: year - 1},
+				SearchVariant{Label: "year +1", Query: query, Year: year + 1},
+			)
+		}
+
+		for _, alt := range titleVariants(query) {
+			if label != "" {
+				alt.Label = label + ", " + alt.Label
+			}
+			alt.Year = year
+			variants = append(variants, alt)
+		}
+	}
+	return variants
+}
+
+// titleVariants returns the query with "&"/"and" swapped, with roman/arabic numerals swapped and
+// without leading article, for the variants that change anything
+func titleVariants(query string) []SearchVariant {
+	var variants []SearchVariant
+	add := func(label, alt string) {
+		if alt != "" && !strings.EqualFold(alt, query) {
+			variants = append(variants, SearchVariant{Label: label, Query: alt})
+		}
+	}
+
+	words := strings.Fields(query)
+	add("'&'/'and' swapped", swapAmpersand(words))
+	add("numerals swapped", swapNumerals(words))
+	add("without article", strings.Join(stripArticle(words), " "))
+	return variants
+}
+
+// swapAmpersand replaces "&" with "and", or "and" with "&" when there is no "&"
+func swapAmpersand(words []string) string {
+	out := make([]string, len(words))
+	changed := false
+	hasAmpersand := false
+	for _, w := range words {
+		if w == "&" {
+			hasAmpersand = true
+		}
+	}
+	for i, w := range words {
+		out[i] = w
+		switch {
+		case hasAmpersand && w == "&":
+			out[i], changed = "and", true
+		case !hasAmpersand && strings.EqualFold(w, "and") && i > 0:
+			out[i], changed = "&", true
+		}
+	}
+	if !changed {
+		return ""
+	}
+	return strings.Join(out, " ")
+}
+
+// romanNumerals maps the roman numerals of sequels to their value; "I" is left out since it is
+// usually the pronoun
+var romanNumerals = map[string]int{
+	"ii": 2, "iii": 3, "iv": 4, "v": 5, "vi": 6, "vii": 7, "viii": 8, "ix": 9, "x": 10,
+	"xi": 11, "xii": 12, "xiii": 13, "xiv": 14, "xv": 15, "xvi": 16, "xvii": 17, "xviii": 18, "xix": 19, "xx": 20,
+}
+
+// swapNumerals replaces roman numerals with arabic ones, or arabic numbers from 2 to 20 with roman
+// numerals when there is no roman numeral; the first word is never changed
+func swapNumerals(words []string) string {
+	toArabic := false
+	for _, w := range words[min(1, len(words)):] {
+		if _, ok := romanNumerals[strings.ToLower(w)]; ok {
+			toArabic = true
+		}
+	}
+
+	out := make([]string, len(words))
+	changed := false
+	for i, w := range words {
+		out[i] = w
+		if i == 0 {
+			continue
+		}
+		if toArabic {
+			if n, ok := romanNumerals[strings.ToLower(w)]; ok {
+				out[i], changed = strconv.Itoa(n), true
+			}
+			continue
+		}
+		if n, err := strconv.Atoi(w); err == nil && n >= 2 && n <= 20 {
+			out[i], changed = toRoman(n), true
+		}
+	}
+	if !changed {
+		return ""
+	}
+	return strings.Join(out, " ")
+}
+
+// toRoman formats a number from 2 to 20 as a roman numeral
+func toRoman(n int) string {
+	for roman, value := range romanNumerals {
+		if value == n {
+			return strings.ToUpper(roman)
+		}
+	}
+	return strconv.Itoa(n)
+}
+
+// leadingArticles are the articles dropped from the start of a title
+var leadingArticles = map[string]bool{
+	"the": true, "a": true, "an": true,
+	"le": true, "la": true, "les": true,
+	"der": true, "die": true, "das": true,
+	"el": true, "los": true, "las": true, "il": true,
+}
+
+// stripArticle drops the leading article of a title, unless it is the whole title
+func stripArticle(words []string) []string {
+	if len(words) > 1 && leadingArticles[strings.ToLower(words[0])] {
+		return words[1:]
+	}
+	return words
+}
+
+// normalizeTitle reduces a title to lower-case words without punctuation, with "&" as "and", roman
+// numerals as numbers and no leading article, so that variants of a title compare equal
+func normalizeTitle(title string) string {
+	title = strings.ToLower(strings.ReplaceAll(title, "&", " and "))
+	title = strings.NewReplacer("'", "", "’", "").Replace(title)
+	words := strings.FieldsFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		if n, ok := romanNumerals[w]; ok && i > 0 {
+			words[i] = strconv.Itoa(n)
+		}
+	}
+	return strings.Join(stripArticle(words), " ")
+}
+
+// isConfidentMatch reports whether a result has the variant's title, in any of its names, and a
+// year within one of the variant's year when both are known
+func isConfidentMatch(props []UnifiedProposition, variant SearchVariant) bool {
+	want := normalizeTitle(variant.Query)
+	if want == "" {
+		return false
+	}
+
+	for _, p := range props {
+		if normalizeTitle(p.Title) != want && normalizeTitle(p.Name) != want && normalizeTitle(p.OriginalName) != want {
+			continue
+		}
+		if variant.Year == 0 {
+			return true
+		}
+		year, err := strconv.Atoi(p.Year)
+		if err != nil || year == 0 || (year >= variant.Year-1 && year <= variant.Year+1) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"kodi-renamer/internal/apitest"
+)
+
+func TestSearchVariants(t *testing.T) {
+	variants := searchVariants([]string{"The Fast & the Furious 2", "2 Fast 2 Furious"}, 2003)
+
+	var got []string
+	for _, v := range variants {
+		got = append(got, v.String())
+	}
+	want := []string{
+		"'The Fast & the Furious 2' (2003)",
+		"'The Fast & the Furious 2' (without year)",
+		"'The Fast & the Furious 2' (2002, year -1)",
+		"'The Fast & the Furious 2' (2004, year +1)",
+		"'The Fast and the Furious 2' (2003, '&'/'and' swapped)",
+		"'The Fast & the Furious II' (2003, numerals swapped)",
+		"'Fast & the Furious 2' (2003, without article)",
+		"'2 Fast 2 Furious' (2003, alternative name)",
+		"'2 Fast II Furious' (2003, alternative name, numerals swapped)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("searchVariants() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"The Matrix", "Matrix"},
+		{"Rocky II", "Rocky 2"},
+		{"Fast & Furious", "Fast and Furious"},
+		{"Ocean's Eleven", "Oceans Eleven"},
+		{"Amélie", "amélie"},
+		{"Star Wars: Episode IV - A New Hope", "Star Wars Episode 4 A New Hope"},
+	}
+	for _, tt := range tests {
+		if normalizeTitle(tt.a) != normalizeTitle(tt.b) {
+			t.Errorf("normalizeTitle(%q) = %q, normalizeTitle(%q) = %q; want equal", tt.a, normalizeTitle(tt.a), tt.b, normalizeTitle(tt.b))
+		}
+	}
+	if normalizeTitle("The Matrix") == normalizeTitle("The Matrix Reloaded") {
+		t.Error("normalizeTitle() made different titles equal")
+	}
+}
+
+func TestSearchMoviesWithFallback(t *testing.T) {
+	server := apitest.NewServer(t)
+	m := newTestManager(t, server)
+
+	tests := []struct {
+		name      string
+		queries   []string
+		year      int
+		wantLabel string
+		wantTried int
+	}{
+		{"original query", []string{"The Matrix"}, 1999, "", 1},
+		{"wrong year", []string{"The Matrix"}, 2010, "without year", 2},
+		{"folder name", []string{"Matrix", "The Matrix"}, 1999, "alternative name", 5},
+	}
+
+	for _, tt := range tests {
+		result, err := m.SearchMoviesWithFallback(context.Background(), tt.queries, tt.year)
+		if err != nil {
+			t.Errorf("%s: SearchMoviesWithFallback() error = %v", tt.name, err)
+			continue
+		}
+		if !result.Confident || result.Variant.Label != tt.wantLabel || len(result.Tried) != tt.wantTried {
+			t.Errorf("%s: found by %s after %d queries (confident %v), want %q after %d",
+				tt.name, result.Variant, len(result.Tried), result.Confident, tt.wantLabel, tt.wantTried)
+		}
+		if len(result.Propositions) == 0 {
+			t.Errorf("%s: no results", tt.name)
+		}
+	}
+}
+
+func TestSearchSeriesWithFallback(t *testing.T) {
+	server := apitest.NewServer(t)
+	m := newTestManager(t, server)
+
+	result, err := m.SearchSeriesWithFallback(context.Background(), []string{"Breaking Bad S01", "Breaking Bad"})
+	if err != nil {
+		t.Fatalf("SearchSeriesWithFallback() error = %v", err)
+	}
+	if !result.IsFallback() || result.Variant.Query != "Breaking Bad" || len(result.Propositions) != 1 {
+		t.Errorf("SearchSeriesWithFallback() found %v by %s, want Breaking Bad by its alternative name",
+			resultKeys(result.Propositions), result.Variant)
+	}
+
+	result, err = m.SearchSeriesWithFallback(context.Background(), []string{"Nothing Matches This"})
+	if err != nil {
+		t.Fatalf("SearchSeriesWithFallback() error = %v", err)
+	}
+	if len(result.Propositions) != 0 || result.IsFallback() || len(result.Tried) != 1 {
+		t.Errorf("SearchSeriesWithFallback() = %+v, want no results after one query", result)
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import "fmt"
+
+// HashMatch is the media identified for a file hash
+type HashMatch struct {
+	ID      string
+	Source  string
+	Type    string // "movie" or "series"
+	Title   string
+	Year    string
+	Season  int
+	Episode int
+}
+
+// HashIdentifier identifies media from an OpenSubtitles movie hash and file size.
+// Implementations return a nil match (and nil error) when the hash is unknown.
+type HashIdentifier interface {
+	Name() string
+	IdentifyByHash(hash string, size int64) (*HashMatch, error)
+}
+
+// RegisterHashIdentifier adds a hash-based identification provider, queried in registration order
+func (m *Manager) RegisterHashIdentifier(identifier HashIdentifier) {
+	m.hashIdentifiers = append(m.hashIdentifiers, identifier)
+}
+
+// IdentifyByHash asks every registered hash identifier for the given file hash and returns the first match
+func (m *Manager) IdentifyByHash(hash string, size int64) (*HashMatch, error) {
+	if hash == "" {
+		return nil, nil
+	}
+
+	for _, identifier := range m.hashIdentifiers {
+		match, err := identifier.IdentifyByHash(hash, size)
+		if err != nil {
+			fmt.Printf("%s hash lookup warning: %v\n", identifier.Name(), err)
+			continue
+		}
+		if match != nil {
+			return match, nil
+		}
+	}
+
+	return nil, nil
+}
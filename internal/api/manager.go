@@ -1,9 +1,10 @@
 package api
 
 import (
+	"context"
 	"fmt"
-	"math"
-	"sort"
+	"log"
+	"net/http"
 	"strconv"
 
 	"kodi-renamer/internal/tmdb"
@@ -13,10 +14,30 @@ import (
 
 // Manager orchestrates multiple API clients (TVDB and TMDB) for media search
 type Manager struct {
-	tvdbClient *tvdb.Client
-	tmdbClient *tmdb.Client
-	hasTVDB    bool
-	hasTMDB    bool
+	tvdbClient      *tvdb.Client
+	tmdbClient      *tmdb.Client
+	hasTVDB         bool
+	hasTMDB         bool
+	tvdbAuthErr     error
+	hashIdentifiers []HashIdentifier
+	scorer          Scorer
+}
+
+// Config holds the credentials and settings used to create a Manager
+type Config struct {
+	TVDBAPIKey string
+	// TVDBPIN is the subscriber PIN required by user-supported TVDB keys
+	TVDBPIN string
+	// TVDBTokenCache is the file where the TVDB token is kept between runs (empty to disable)
+	TVDBTokenCache string
+	TMDBAPIKey     string
+	// HTTPLogger receives a line per provider request when set, with API keys redacted
+	HTTPLogger *log.Logger
+	// TVDBBaseURL and TMDBBaseURL replace the public API endpoints when set, for example with a fake server in tests
+	TVDBBaseURL string
+	TMDBBaseURL string
+	// HTTPClient replaces the default HTTP client of both providers when set
+	HTTPClient *http.Client
 }
 
 // UnifiedProposition represents a search result from any API source
@@ -29,13 +50,24 @@ type UnifiedProposition struct {
 	Year         string
 	Type         string
 	Source       string
-	Score        float64
+	// Popularity is the provider's popularity measure, 0 when the provider gives none
+	Popularity float64
+	// VoteAverage is the provider's average user rating out of 10, 0 when the provider gives none
+	VoteAverage float64
+	// IDs maps every site known to describe the result ("tvdb", "tmdb", "imdb") to its ID there
+	IDs map[string]string
+	// Sources lists the providers that returned the result once duplicates are merged, Source first
+	Sources []string
+	// Score is the ranking score between 0 and 1, explained by ScoreBreakdown
+	Score          float64
+	ScoreBreakdown ScoreBreakdown
 }
 
+// GetYearAsInt returns the year of the result, or 0 when it is unknown
 func (p *UnifiedProposition) GetYearAsInt() int {
 	year, err := strconv.Atoi(p.Year)
 	if err != nil {
-		fmt.Printf("Invalid year to format %s", p.Year)
+		return 0
 	}
 	return year
 }
@@ -49,6 +81,14 @@ type UnifiedMovieProposition struct {
 	Runtime  int
 	Genres   []string
 	Source   string
+	// UniqueIDs maps every site known to describe the movie ("tvdb", "tmdb", "imdb") to its ID there
+	UniqueIDs map[string]string
+	// CollectionID and CollectionName identify the franchise of the movie (a TMDB collection), empty
+	// when it has none or it is unknown
+	CollectionID   string
+	CollectionName string
+	// Metadata holds the credits, certifications and release dates once FillMetadata fetched them
+	Metadata *MovieMetadata
 }
 
 // UnifiedSeriesProposition represents detailed TV series information from any API source
@@ -61,6 +101,8 @@ type UnifiedSeriesProposition struct {
 	Status     string
 	Genres     []string
 	Source     string
+	// UniqueIDs maps every site known to describe the series ("tvdb", "tmdb", "imdb") to its ID there
+	UniqueIDs map[string]string
 }
 
 // GetFolderName returns the properly formatted folder name for the series
@@ -80,36 +122,55 @@ type UnifiedEpisodeInfo struct {
 	EpisodeName   string
 	Name          string // Episode name (alias for EpisodeName for consistency)
 	Year          string
+	AirDate       string // First broadcast as "YYYY-MM-DD", empty when unknown
+	Runtime       int    // Minutes, 0 when unknown
+	Overview      string
 	Source        string
 }
 
-// NewManager creates a new API manager with the provided API keys
-func NewManager(tvdbAPIKey, tmdbAPIKey string) *Manager {
-	m := &Manager{}
-
-	if tvdbAPIKey != "" {
-		m.tvdbClient = tvdb.NewClient(tvdbAPIKey)
+// NewManager creates a new API manager from the provided configuration
+func NewManager(ctx context.Context, cfg Config) *Manager {
+	m := &Manager{scorer: DefaultScorer()}
+
+	if cfg.TVDBAPIKey != "" {
+		m.tvdbClient = tvdb.NewClient(cfg.TVDBAPIKey)
+		m.tvdbClient.SetPIN(cfg.TVDBPIN)
+		m.tvdbClient.SetTokenCache(cfg.TVDBTokenCache)
+		m.tvdbClient.HTTP().SetLogger(cfg.HTTPLogger)
+		if cfg.TVDBBaseURL != "" {
+			m.tvdbClient.SetBaseURL(cfg.TVDBBaseURL)
+		}
+		if cfg.HTTPClient != nil {
+			m.tvdbClient.HTTP().SetHTTPClient(cfg.HTTPClient)
+		}
 		m.hasTVDB = true
-		// Login to TVDB if configured
-		if err := m.tvdbClient.Login(); err != nil {
+		// A failed login keeps TVDB enabled: every request logs in again before giving up
+		if err := m.tvdbClient.Authenticate(ctx); err != nil {
 			fmt.Printf("Warning: failed to authenticate with TVDB: %v\n", err)
-			m.hasTVDB = false
+			m.tvdbAuthErr = err
 		}
 	}
 
-	if tmdbAPIKey != "" {
-		m.tmdbClient = tmdb.NewClient(tmdbAPIKey)
+	if cfg.TMDBAPIKey != "" {
+		m.tmdbClient = tmdb.NewClient(cfg.TMDBAPIKey)
+		m.tmdbClient.HTTP().SetLogger(cfg.HTTPLogger)
+		if cfg.TMDBBaseURL != "" {
+			m.tmdbClient.SetBaseURL(cfg.TMDBBaseURL)
+		}
+		if cfg.HTTPClient != nil {
+			m.tmdbClient.HTTP().SetHTTPClient(cfg.HTTPClient)
+		}
 		m.hasTMDB = true
 	}
 
 	return m
 }
 
-// GetConfiguredAPIs returns a list of API names that are currently configured
+// GetConfiguredAPIs returns the configured API names with their authentication state
 func (m *Manager) GetConfiguredAPIs() []string {
 	apis := []string{}
 	if m.hasTVDB {
-		apis = append(apis, "TVDB")
+		apis = append(apis, m.tvdbStatus())
 	}
 	if m.hasTMDB {
 		apis = append(apis, "TMDB")
@@ -117,13 +178,24 @@ func (m *Manager) GetConfiguredAPIs() []string {
 	return apis
 }
 
+// tvdbStatus describes whether TVDB holds a token and until when it is valid
+func (m *Manager) tvdbStatus() string {
+	if !m.tvdbClient.Authenticated() {
+		if m.tvdbAuthErr != nil {
+			return "TVDB (not authenticated, will retry: " + m.tvdbAuthErr.Error() + ")"
+		}
+		return "TVDB (not authenticated)"
+	}
+	return "TVDB (authenticated, token expires " + m.tvdbClient.TokenExpiry().Format("2006-01-02") + ")"
+}
+
 // Search performs a general search across all configured APIs
-func (m *Manager) Search(query string) ([]UnifiedProposition, error) {
+func (m *Manager) Search(ctx context.Context, query string) ([]UnifiedProposition, error) {
 	var allProps []UnifiedProposition
 
 	// Search TVDB
 	if m.hasTVDB {
-		tvdbResults, err := m.tvdbClient.Search(query)
+		tvdbResults, err := m.tvdbClient.Search(ctx, query)
 		if err != nil {
 			// Don't fail completely, just log and continue
 			fmt.Printf("TVDB search warning: %v\n", err)
@@ -137,7 +209,7 @@ func (m *Manager) Search(query string) ([]UnifiedProposition, error) {
 					Year:         prop.Year,
 					Type:         prop.Type,
 					Source:       "tvdb",
-					Score:        0.0,
+					IDs:          prop.RemoteIDs,
 				})
 			}
 		}
@@ -145,7 +217,7 @@ func (m *Manager) Search(query string) ([]UnifiedProposition, error) {
 
 	// Search TMDB
 	if m.hasTMDB {
-		tmdbResults, err := m.tmdbClient.Search(query)
+		tmdbResults, err := m.tmdbClient.Search(ctx, query)
 		if err != nil {
 			fmt.Printf("TMDB search warning: %v\n", err)
 		} else {
@@ -158,33 +230,30 @@ func (m *Manager) Search(query string) ([]UnifiedProposition, error) {
 					Year:         prop.Year,
 					Type:         mapTMDBType(prop.Type),
 					Source:       "tmdb",
-					Score:        prop.Popularity + (prop.VoteAverage * 10),
+					Popularity:   prop.Popularity,
+					VoteAverage:  prop.VoteAverage,
 				})
 			}
 		}
 	}
 
-	// Sort by score (TMDB has popularity, TVDB results go first)
-	sort.SliceStable(allProps, func(i, j int) bool {
-		if allProps[i].Source == "tvdb" && allProps[j].Source == "tmdb" {
-			return true
-		}
-		if allProps[i].Source == "tmdb" && allProps[j].Source == "tvdb" {
-			return false
-		}
-		return allProps[i].Score > allProps[j].Score
-	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	allProps = mergeDuplicates(allProps)
+	m.rank(ScoreQuery{Query: query}, allProps)
 
 	return allProps, nil
 }
 
 // SearchMovies searches specifically for movies across all configured APIs
-func (m *Manager) SearchMovies(query string, year int) ([]UnifiedProposition, error) {
+func (m *Manager) SearchMovies(ctx context.Context, query string, year int) ([]UnifiedProposition, error) {
 	var allProps []UnifiedProposition
 
 	// Search TVDB
 	if m.hasTVDB {
-		tvdbResults, err := m.tvdbClient.Search(query)
+		tvdbResults, err := m.tvdbClient.Search(ctx, query)
 		if err != nil {
 			fmt.Printf("TVDB search warning: %v\n", err)
 		} else {
@@ -198,7 +267,7 @@ func (m *Manager) SearchMovies(query string, year int) ([]UnifiedProposition, er
 						Year:         prop.Year,
 						Type:         "movie",
 						Source:       "tvdb",
-						Score:        0.0,
+						IDs:          prop.RemoteIDs,
 					})
 				}
 			}
@@ -207,7 +276,7 @@ func (m *Manager) SearchMovies(query string, year int) ([]UnifiedProposition, er
 
 	// Search TMDB
 	if m.hasTMDB {
-		tmdbResults, err := m.tmdbClient.SearchMovie(query, year)
+		tmdbResults, err := m.tmdbClient.SearchMovie(ctx, query, year)
 		if err != nil {
 			fmt.Printf("TMDB search warning: %v\n", err)
 		} else {
@@ -220,35 +289,30 @@ func (m *Manager) SearchMovies(query string, year int) ([]UnifiedProposition, er
 					Year:         prop.Year,
 					Type:         "movie",
 					Source:       "tmdb",
-					Score:        prop.Popularity + (prop.VoteAverage * 10),
+					Popularity:   prop.Popularity,
+					VoteAverage:  prop.VoteAverage,
 				})
 			}
 		}
 	}
 
-	sort.SliceStable(allProps, func(i, j int) bool {
-		if allProps[i].Source == "tvdb" && allProps[j].Source == "tmdb" {
-			return true
-		}
-		if allProps[i].Source == "tmdb" && allProps[j].Source == "tvdb" {
-			return false
-		}
-		firstYear := allProps[i].GetYearAsInt() - year
-		nextYear := allProps[j].GetYearAsInt() - year
-		return math.Abs(float64(firstYear)) < math.Abs(float64(nextYear))
-		// return allProps[i].Score > allProps[j].Score
-	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	allProps = mergeDuplicates(allProps)
+	m.rank(ScoreQuery{Query: query, Year: year, Type: "movie"}, allProps)
 
 	return allProps, nil
 }
 
 // SearchSeries searches specifically for TV series across all configured APIs
-func (m *Manager) SearchSeries(query string) ([]UnifiedProposition, error) {
+func (m *Manager) SearchSeries(ctx context.Context, query string) ([]UnifiedProposition, error) {
 	var allProps []UnifiedProposition
 
 	// Search TVDB
 	if m.hasTVDB {
-		tvdbResults, err := m.tvdbClient.Search(query)
+		tvdbResults, err := m.tvdbClient.Search(ctx, query)
 		if err != nil {
 			fmt.Printf("TVDB search warning: %v\n", err)
 		} else {
@@ -263,7 +327,7 @@ func (m *Manager) SearchSeries(query string) ([]UnifiedProposition, error) {
 						Year:         prop.Year,
 						Type:         "series",
 						Source:       "tvdb",
-						Score:        0.0,
+						IDs:          prop.RemoteIDs,
 					})
 				}
 			}
@@ -272,7 +336,7 @@ func (m *Manager) SearchSeries(query string) ([]UnifiedProposition, error) {
 
 	// Search TMDB
 	if m.hasTMDB {
-		tmdbResults, err := m.tmdbClient.SearchTV(query, 0)
+		tmdbResults, err := m.tmdbClient.SearchTV(ctx, query, 0)
 		if err != nil {
 			fmt.Printf("TMDB search warning: %v\n", err)
 		} else {
@@ -286,44 +350,43 @@ func (m *Manager) SearchSeries(query string) ([]UnifiedProposition, error) {
 					Year:         prop.Year,
 					Type:         "series",
 					Source:       "tmdb",
-					Score:        prop.Popularity + (prop.VoteAverage * 10),
+					Popularity:   prop.Popularity,
+					VoteAverage:  prop.VoteAverage,
 				})
 			}
 		}
 	}
 
-	sort.SliceStable(allProps, func(i, j int) bool {
-		if allProps[i].Source == "tvdb" && allProps[j].Source == "tmdb" {
-			return true
-		}
-		if allProps[i].Source == "tmdb" && allProps[j].Source == "tvdb" {
-			return false
-		}
-		return allProps[i].Score > allProps[j].Score
-	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	allProps = mergeDuplicates(allProps)
+	m.rank(ScoreQuery{Query: query, Type: "series"}, allProps)
 
 	return allProps, nil
 }
 
 // GetMovie retrieves detailed movie information by ID from the specified API source
-func (m *Manager) GetMovie(id, source string) (*UnifiedMovieProposition, error) {
+func (m *Manager) GetMovie(ctx context.Context, id, source string) (*UnifiedMovieProposition, error) {
 	switch source {
 	case "tvdb":
 		if !m.hasTVDB {
 			return nil, fmt.Errorf("TVDB not configured")
 		}
-		movie, err := m.tvdbClient.GetMovie(id)
+		movie, err := m.tvdbClient.GetMovie(ctx, id)
 		if err != nil {
 			return nil, err
 		}
 		return &UnifiedMovieProposition{
-			ID:       strconv.FormatInt(movie.ID, 10),
-			Title:    movie.Title,
-			Overview: movie.Overview,
-			Year:     movie.Year,
-			Runtime:  movie.Runtime,
-			Genres:   movie.Genres,
-			Source:   "tvdb",
+			ID:        strconv.FormatInt(movie.ID, 10),
+			Title:     movie.Title,
+			Overview:  movie.Overview,
+			Year:      movie.Year,
+			Runtime:   movie.Runtime,
+			Genres:    movie.Genres,
+			Source:    "tvdb",
+			UniqueIDs: map[string]string{"tvdb": strconv.FormatInt(movie.ID, 10)},
 		}, nil
 
 	case "tmdb":
@@ -334,7 +397,7 @@ func (m *Manager) GetMovie(id, source string) (*UnifiedMovieProposition, error)
 		if err != nil {
 			return nil, fmt.Errorf("invalid TMDB ID: %w", err)
 		}
-		movie, err := m.tmdbClient.GetMovie(movieID)
+		movie, err := m.tmdbClient.GetMovie(ctx, movieID)
 		if err != nil {
 			return nil, err
 		}
@@ -346,6 +409,12 @@ func (m *Manager) GetMovie(id, source string) (*UnifiedMovieProposition, error)
 			Runtime:  movie.Runtime,
 			Genres:   movie.Genres,
 			Source:   "tmdb",
+			UniqueIDs: externalIDs(map[string]string{
+				"tmdb": strconv.Itoa(movie.ID),
+				"imdb": movie.ImdbID,
+			}),
+			CollectionID:   formatOptionalID(movie.CollectionID),
+			CollectionName: movie.CollectionName,
 		}, nil
 
 	default:
@@ -354,13 +423,13 @@ func (m *Manager) GetMovie(id, source string) (*UnifiedMovieProposition, error)
 }
 
 // GetSeries retrieves detailed TV series information by ID from the specified API source
-func (m *Manager) GetSeries(id, source string) (*UnifiedSeriesProposition, error) {
+func (m *Manager) GetSeries(ctx context.Context, id, source string) (*UnifiedSeriesProposition, error) {
 	switch source {
 	case "tvdb":
 		if !m.hasTVDB {
 			return nil, fmt.Errorf("TVDB not configured")
 		}
-		series, err := m.tvdbClient.GetSeries(id)
+		series, err := m.tvdbClient.GetSeries(ctx, id)
 		if err != nil {
 			return nil, err
 		}
@@ -373,6 +442,7 @@ func (m *Manager) GetSeries(id, source string) (*UnifiedSeriesProposition, error
 			Status:     series.Status,
 			Genres:     series.Genres,
 			Source:     "tvdb",
+			UniqueIDs:  map[string]string{"tvdb": strconv.FormatInt(series.ID, 10)},
 		}, nil
 
 	case "tmdb":
@@ -383,7 +453,7 @@ func (m *Manager) GetSeries(id, source string) (*UnifiedSeriesProposition, error
 		if err != nil {
 			return nil, fmt.Errorf("invalid TMDB ID: %w", err)
 		}
-		series, err := m.tmdbClient.GetTVShow(seriesID)
+		series, err := m.tmdbClient.GetTVShow(ctx, seriesID)
 		if err != nil {
 			return nil, err
 		}
@@ -396,6 +466,11 @@ func (m *Manager) GetSeries(id, source string) (*UnifiedSeriesProposition, error
 			Status:     series.Status,
 			Genres:     series.Genres,
 			Source:     "tmdb",
+			UniqueIDs: externalIDs(map[string]string{
+				"tmdb": strconv.Itoa(series.ID),
+				"tvdb": formatOptionalID(series.TVDBID),
+				"imdb": series.ImdbID,
+			}),
 		}, nil
 
 	default:
@@ -404,34 +479,26 @@ func (m *Manager) GetSeries(id, source string) (*UnifiedSeriesProposition, error
 }
 
 // GetEpisode retrieves specific episode information by series ID, season, and episode number from the specified API source
-func (m *Manager) GetEpisode(id, source string, season, episode int) (*UnifiedEpisodeInfo, error) {
+func (m *Manager) GetEpisode(ctx context.Context, id, source string, season, episode int) (*UnifiedEpisodeInfo, error) {
 	switch source {
 	case "tvdb":
 		if !m.hasTVDB {
 			return nil, fmt.Errorf("TVDB not configured")
 		}
-		episodes, err := m.tvdbClient.GetEpisodes(id, season)
+		episodes, err := m.tvdbClient.GetEpisodes(ctx, id, season)
 		if err != nil {
 			return nil, err
 		}
 
 		// Get series name
-		series, err := m.tvdbClient.GetSeries(id)
+		series, err := m.tvdbClient.GetSeries(ctx, id)
 		if err != nil {
 			return nil, err
 		}
 
 		for _, ep := range episodes {
 			if ep.SeasonNumber == season && ep.EpisodeNumber == episode {
-				return &UnifiedEpisodeInfo{
-					SeriesName:    series.Name,
-					SeasonNumber:  season,
-					EpisodeNumber: episode,
-					EpisodeName:   ep.Name,
-					Name:          ep.Name,
-					Year:          ep.Year,
-					Source:        "tvdb",
-				}, nil
+				return tvdbEpisodeInfo(series.Name, ep), nil
 			}
 		}
 		return nil, fmt.Errorf("episode S%02dE%02d not found", season, episode)
@@ -444,19 +511,11 @@ func (m *Manager) GetEpisode(id, source string, season, episode int) (*UnifiedEp
 		if err != nil {
 			return nil, fmt.Errorf("invalid TMDB ID: %w", err)
 		}
-		episodeInfo, err := m.tmdbClient.GetEpisode(seriesID, season, episode)
+		episodeInfo, err := m.tmdbClient.GetEpisode(ctx, seriesID, season, episode)
 		if err != nil {
 			return nil, err
 		}
-		return &UnifiedEpisodeInfo{
-			SeriesName:    episodeInfo.SeriesName,
-			SeasonNumber:  episodeInfo.SeasonNumber,
-			EpisodeNumber: episodeInfo.EpisodeNumber,
-			EpisodeName:   episodeInfo.EpisodeName,
-			Name:          episodeInfo.EpisodeName,
-			Year:          episodeInfo.Year,
-			Source:        "tmdb",
-		}, nil
+		return tmdbEpisodeInfo(episodeInfo), nil
 
 	default:
 		return nil, fmt.Errorf("unknown source: %s", source)
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"kodi-renamer/internal/apitest"
+)
+
+// newTestManager returns a manager using both providers of the fake server, with its own token cache
+func newTestManager(t *testing.T, server *apitest.Server) *Manager {
+	t.Helper()
+	return NewManager(context.Background(), Config{
+		TVDBAPIKey:     apitest.TVDBAPIKey,
+		TVDBTokenCache: filepath.Join(t.TempDir(), "tvdb-token.json"),
+		TMDBAPIKey:     apitest.TMDBAPIKey,
+		TVDBBaseURL:    server.TVDBURL(),
+		TMDBBaseURL:    server.TMDBURL(),
+	})
+}
+
+// resultKeys lists search results as "source:id" for comparison
+func resultKeys(props []UnifiedProposition) []string {
+	keys := make([]string, 0, len(props))
+	for _, p := range props {
+		keys = append(keys, p.Source+":"+p.ID)
+	}
+	return keys
+}
+
+func TestSearchMovies(t *testing.T) {
+	server := apitest.NewServer(t)
+	m := newTestManager(t, server)
+
+	props, err := m.SearchMovies(context.Background(), "The Matrix", 1999)
+	if err != nil {
+		t.Fatalf("SearchMovies() error = %v", err)
+	}
+
+	// The exact title and year match comes first, then the sequels; results found by both providers
+	// are merged, by TVDB remote ID (The Matrix) or by title and year (The Matrix Reloaded)
+	want := []string{"tvdb:169", "tvdb:553", "tmdb:624860"}
+	if got := resultKeys(props); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("SearchMovies() = %v, want %v", got, want)
+	}
+	if props[0].Title != "The Matrix" || props[0].Year != "1999" || props[0].Type != "movie" {
+		t.Errorf("first result = %+v, want The Matrix (1999) movie", props[0])
+	}
+	sources := []string{"tvdb+tmdb", "tvdb+tmdb", "tmdb"}
+	for i, p := range props {
+		if p.SourceLabel() != sources[i] {
+			t.Errorf("%s sources = %s, want %s", p.Title, p.SourceLabel(), sources[i])
+		}
+	}
+	if props[0].IDFor("tmdb") != "603" || props[0].IDFor("imdb") != "tt0133093" || props[1].IDFor("tmdb") != "604" {
+		t.Errorf("merged IDs = %v and %v, want the TMDB and IMDB IDs kept", props[0].IDs, props[1].IDs)
+	}
+}
+
+func TestSearchMoviesWithoutResults(t *testing.T) {
+	server := apitest.NewServer(t)
+	m := newTestManager(t, server)
+
+	props, err := m.SearchMovies(context.Background(), "Nothing Matches This", 0)
+	if err != nil {
+		t.Fatalf("SearchMovies() error = %v", err)
+	}
+	if len(props) != 0 {
+		t.Errorf("SearchMovies() = %v, want no results", resultKeys(props))
+	}
+}
+
+func TestSearchSeries(t *testing.T) {
+	server := apitest.NewServer(t)
+	m := newTestManager(t, server)
+
+	props, err := m.SearchSeries(context.Background(), "Breaking Bad")
+	if err != nil {
+		t.Fatalf("SearchSeries() error = %v", err)
+	}
+
+	want := []string{"tvdb:81189"}
+	if got := resultKeys(props); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("SearchSeries() = %v, want %v", got, want)
+	}
+	if props[0].IDFor("tmdb") != "1396" {
+		t.Errorf("IDs = %v, want the TMDB ID of the merged result", props[0].IDs)
+	}
+	for _, p := range props {
+		if p.Name != "Breaking Bad" || p.Year != "2008" || p.Type != "series" {
+			t.Errorf("result = %+v, want Breaking Bad (2008) series", p)
+		}
+	}
+}
+
+func TestGetEpisode(t *testing.T) {
+	server := apitest.NewServer(t)
+	m := newTestManager(t, server)
+
+	tests := []struct {
+		id, source      string
+		season, episode int
+		want            string
+	}{
+		{"81189", "tvdb", 1, 2, "Cat's in the Bag..."},
+		{"1396", "tmdb", 1, 3, "...And the Bag's in the River"},
+	}
+
+	for _, tt := range tests {
+		info, err := m.GetEpisode(context.Background(), tt.id, tt.source, tt.season, tt.episode)
+		if err != nil {
+			t.Errorf("GetEpisode(%s, %s) error = %v", tt.id, tt.source, err)
+			continue
+		}
+		if info.SeriesName != "Breaking Bad" || info.Name != tt.want || info.Source != tt.source {
+			t.Errorf("GetEpisode(%s, %s) = %+v, want Breaking Bad - %s", tt.id, tt.source, info, tt.want)
+		}
+		if info.AirDate == "" || info.Runtime != 48 || info.Overview == "" {
+			t.Errorf("GetEpisode(%s, %s) = %+v, want its air date, runtime and overview", tt.id, tt.source, info)
+		}
+	}
+
+	if _, err := m.GetEpisode(context.Background(), "81189", "tvdb", 1, 9); err == nil {
+		t.Error("GetEpisode() of a missing episode succeeded, want an error")
+	}
+}
+
+func TestTVDBLogsInAgainWhenTokenIsRejected(t *testing.T) {
+	server := apitest.NewServer(t)
+	m := newTestManager(t, server)
+	if server.Logins() != 1 {
+		t.Fatalf("logins after NewManager = %d, want 1", server.Logins())
+	}
+
+	server.ExpireTokens()
+	series, err := m.GetSeries(context.Background(), "81189", "tvdb")
+	if err != nil {
+		t.Fatalf("GetSeries() after token expiry error = %v", err)
+	}
+	if series.Name != "Breaking Bad" {
+		t.Errorf("GetSeries() = %+v, want Breaking Bad", series)
+	}
+	if server.Logins() != 2 {
+		t.Errorf("logins = %d, want 2 (one re-login)", server.Logins())
+	}
+}
+
+func TestTVDBLogsInOnceForConcurrentRejections(t *testing.T) {
+	server := apitest.NewServer(t)
+	m := newTestManager(t, server)
+
+	server.ExpireTokens()
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := m.GetSeries(context.Background(), "81189", "tvdb")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("GetSeries() after token expiry error = %v", err)
+		}
+	}
+	if server.Logins() != 2 {
+		t.Errorf("logins = %d, want 2 (a single re-login)", server.Logins())
+	}
+}
+
+func TestTVDBTokenIsReusedFromCache(t *testing.T) {
+	server := apitest.NewServer(t)
+	cfg := Config{
+		TVDBAPIKey:     apitest.TVDBAPIKey,
+		TVDBTokenCache: filepath.Join(t.TempDir(), "tvdb-token.json"),
+		TVDBBaseURL:    server.TVDBURL(),
+	}
+
+	NewManager(context.Background(), cfg)
+	m := NewManager(context.Background(), cfg)
+	if server.Logins() != 1 {
+		t.Errorf("logins = %d, want 1 (second run uses the cached token)", server.Logins())
+	}
+	if _, err := m.GetMovie(context.Background(), "169", "tvdb"); err != nil {
+		t.Errorf("GetMovie() with cached token error = %v", err)
+	}
+	if status := m.GetConfiguredAPIs(); len(status) != 1 || !strings.HasPrefix(status[0], "TVDB (authenticated") {
+		t.Errorf("GetConfiguredAPIs() = %v, want an authenticated TVDB", status)
+	}
+}
+
+func TestTVDBFailedLoginKeepsProviderConfigured(t *testing.T) {
+	server := apitest.NewServer(t)
+	m := NewManager(context.Background(), Config{
+		TVDBAPIKey:  "wrong-key",
+		TVDBBaseURL: server.TVDBURL(),
+	})
+
+	status := m.GetConfiguredAPIs()
+	if len(status) != 1 || !strings.Contains(status[0], "not authenticated") {
+		t.Errorf("GetConfiguredAPIs() = %v, want TVDB reported as not authenticated", status)
+	}
+	if _, err := m.GetSeries(context.Background(), "81189", "tvdb"); err == nil {
+		t.Error("GetSeries() with a wrong key succeeded, want an error")
+	}
+}
+
+func TestRequestsAreRetriedAfterServerErrors(t *testing.T) {
+	server := apitest.NewServer(t)
+	m := newTestManager(t, server)
+	server.FailNext("tmdb", "/movie/603", 1)
+
+	movie, err := m.GetMovie(context.Background(), "603", "tmdb")
+	if err != nil {
+		t.Fatalf("GetMovie() error = %v", err)
+	}
+	if movie.Title != "The Matrix" || movie.Runtime != 136 {
+		t.Errorf("GetMovie() = %+v, want The Matrix, 136 min", movie)
+	}
+}
@@ -0,0 +1,28 @@
+package api
+
+import "math"
+
+// runtimeTolerance is the relative runtime difference at which two runtimes stop being considered a match
+const runtimeTolerance = 0.15
+
+// RuntimeMatchScore compares a file runtime with a provider runtime (both in minutes) and returns
+// a score between 0 (unrelated or unknown) and 1 (identical). Small differences are expected since
+// provider runtimes are rounded and releases may include or cut intros and credits.
+func RuntimeMatchScore(fileMinutes, runtimeMinutes int) float64 {
+	if fileMinutes <= 0 || runtimeMinutes <= 0 {
+		return 0
+	}
+
+	diff := math.Abs(float64(fileMinutes - runtimeMinutes))
+	relative := diff / float64(runtimeMinutes)
+	if relative >= runtimeTolerance {
+		return 0
+	}
+
+	return 1 - relative/runtimeTolerance
+}
+
+// IsRuntimeMatch checks if a file runtime is close enough to a provider runtime to support a match
+func IsRuntimeMatch(fileMinutes, runtimeMinutes int) bool {
+	return RuntimeMatchScore(fileMinutes, runtimeMinutes) >= 0.5
+}
@@ -0,0 +1,62 @@
+package api
+
+import "testing"
+
+func TestRuntimeMatchScore(t *testing.T) {
+	tests := []struct {
+		name                 string
+		fileMinutes, runtime int
+		wantZero, wantOne    bool
+	}{
+		{name: "unknown file runtime", fileMinutes: 0, runtime: 136, wantZero: true},
+		{name: "unknown provider runtime", fileMinutes: 136, runtime: 0, wantZero: true},
+		{name: "negative runtime", fileMinutes: -1, runtime: 100, wantZero: true},
+		{name: "exact", fileMinutes: 136, runtime: 136, wantOne: true},
+		{name: "at tolerance above", fileMinutes: 115, runtime: 100, wantZero: true},
+		{name: "at tolerance below", fileMinutes: 85, runtime: 100, wantZero: true},
+		{name: "just within tolerance", fileMinutes: 114, runtime: 100},
+		{name: "far off", fileMinutes: 45, runtime: 136, wantZero: true},
+		// A stacked movie is probed as the sum of its parts; a single part is far from the full runtime
+		{name: "single part of a stack", fileMinutes: 68, runtime: 136, wantZero: true},
+		{name: "summed parts of a stack", fileMinutes: 68 + 68, runtime: 136, wantOne: true},
+	}
+
+	for _, tt := range tests {
+		got := RuntimeMatchScore(tt.fileMinutes, tt.runtime)
+		switch {
+		case tt.wantZero && got != 0:
+			t.Errorf("%s: RuntimeMatchScore(%d, %d) = %v, want 0", tt.name, tt.fileMinutes, tt.runtime, got)
+		case tt.wantOne && got != 1:
+			t.Errorf("%s: RuntimeMatchScore(%d, %d) = %v, want 1", tt.name, tt.fileMinutes, tt.runtime, got)
+		case !tt.wantZero && !tt.wantOne && (got <= 0 || got >= 1):
+			t.Errorf("%s: RuntimeMatchScore(%d, %d) = %v, want between 0 and 1", tt.name, tt.fileMinutes, tt.runtime, got)
+		}
+	}
+
+	if closer, further := RuntimeMatchScore(102, 100), RuntimeMatchScore(110, 100); closer <= further {
+		t.Errorf("RuntimeMatchScore(102, 100) = %v, not above RuntimeMatchScore(110, 100) = %v", closer, further)
+	}
+}
+
+func TestIsRuntimeMatch(t *testing.T) {
+	tests := []struct {
+		fileMinutes, runtime int
+		want                 bool
+	}{
+		{0, 100, false},
+		{100, 0, false},
+		{100, 100, true},
+		{107, 100, true},
+		{93, 100, true},
+		{108, 100, false},
+		{92, 100, false},
+		{68, 136, false},
+		{136, 136, true},
+	}
+
+	for _, tt := range tests {
+		if got := IsRuntimeMatch(tt.fileMinutes, tt.runtime); got != tt.want {
+			t.Errorf("IsRuntimeMatch(%d, %d) = %v, want %v", tt.fileMinutes, tt.runtime, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// detailSources are the providers details can be fetched from, in the order they are tried when a
+// result does not say otherwise
+var detailSources = []string{"tvdb", "tmdb"}
+
+// SourceLabel returns the providers that returned the result, like "tvdb+tmdb"
+func (p *UnifiedProposition) SourceLabel() string {
+	if len(p.Sources) == 0 {
+		return p.Source
+	}
+	return strings.Join(p.Sources, "+")
+}
+
+// IDFor returns the ID of the result on a site ("tvdb", "tmdb" or "imdb"), or "" when unknown
+func (p *UnifiedProposition) IDFor(source string) string {
+	if id := p.IDs[source]; id != "" {
+		return id
+	}
+	if source == p.Source {
+		return p.ID
+	}
+	return ""
+}
+
+// lookupSources lists the providers to fetch the details of a result from: the ones that returned it,
+// then the ones only known from remote IDs
+func (p *UnifiedProposition) lookupSources() []string {
+	sources := append([]string(nil), p.Sources...)
+	if len(sources) == 0 {
+		sources = append(sources, p.Source)
+	}
+	for _, source := range detailSources {
+		if p.IDFor(source) != "" && !slices.Contains(sources, source) {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// mergeDuplicates folds the results describing the same work into the first of them (TVDB results
+// come first): results of different providers sharing an ID, or with the same type, title and year.
+// The merged result keeps the IDs of every provider and lists them in Sources.
+func mergeDuplicates(props []UnifiedProposition) []UnifiedProposition {
+	merged := make([]UnifiedProposition, 0, len(props))
+	for _, p := range props {
+		p.IDs = withOwnID(p)
+		if len(p.Sources) == 0 {
+			p.Sources = []string{p.Source}
+		}
+
+		duplicate := false
+		for i := range merged {
+			if sameWork(merged[i], p) {
+				mergeInto(&merged[i], p)
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
+
+// withOwnID returns a copy of the IDs of a result including its own ID
+func withOwnID(p UnifiedProposition) map[string]string {
+	ids := make(map[string]string, len(p.IDs)+1)
+	for source, id := range p.IDs {
+		ids[source] = id
+	}
+	if p.ID != "" {
+		ids[p.Source] = p.ID
+	}
+	return ids
+}
+
+// sameWork reports whether two results of different providers describe the same work
+func sameWork(a, b UnifiedProposition) bool {
+	for _, source := range b.Sources {
+		if slices.Contains(a.Sources, source) {
+			return false
+		}
+	}
+	if a.Type != "" && b.Type != "" && a.Type != b.Type {
+		return false
+	}
+
+	sharedID := false
+	for source, id := range a.IDs {
+		other, ok := b.IDs[source]
+		if !ok || id == "" {
+			continue
+		}
+		if other != id {
+			// Both know the work on that site, and it is not the same one
+			return false
+		}
+		sharedID = true
+	}
+	if sharedID {
+		return true
+	}
+
+	return a.Year != "" && a.Year == b.Year && normalizeTitle(a.Title) != "" && normalizeTitle(a.Title) == normalizeTitle(b.Title)
+}
+
+// mergeInto adds the sources and IDs of a duplicate to a result and fills its missing fields
+func mergeInto(into *UnifiedProposition, dup UnifiedProposition) {
+	into.Sources = append(into.Sources, dup.Sources...)
+	for source, id := range dup.IDs {
+		if into.IDs[source] == "" {
+			into.IDs[source] = id
+		}
+	}
+	if into.Overview == "" {
+		into.Overview = dup.Overview
+	}
+	if into.Year == "" {
+		into.Year = dup.Year
+	}
+	if into.OriginalName == "" || into.OriginalName == into.Title {
+		if dup.OriginalName != "" {
+			into.OriginalName = dup.OriginalName
+		}
+	}
+	into.Popularity = max(into.Popularity, dup.Popularity)
+	if into.VoteAverage == 0 {
+		into.VoteAverage = dup.VoteAverage
+	}
+}
+
+// mergeIDs returns the IDs of both maps, the first one winning on conflicts
+func mergeIDs(first, second map[string]string) map[string]string {
+	ids := make(map[string]string, len(first)+len(second))
+	for source, id := range second {
+		if id != "" {
+			ids[source] = id
+		}
+	}
+	for source, id := range first {
+		if id != "" {
+			ids[source] = id
+		}
+	}
+	return ids
+}
+
+// externalIDs drops the unknown IDs of a UniqueIDs map
+func externalIDs(ids map[string]string) map[string]string {
+	for source, id := range ids {
+		if id == "" {
+			delete(ids, source)
+		}
+	}
+	return ids
+}
+
+// formatOptionalID formats a numeric ID, 0 meaning unknown
+func formatOptionalID(id int) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
+
+// GetMovieDetails retrieves the details of a movie search result from the first of its providers
+// that answers, with the IDs of every provider in UniqueIDs
+func (m *Manager) GetMovieDetails(ctx context.Context, prop UnifiedProposition) (*UnifiedMovieProposition, error) {
+	var firstErr error
+	for _, source := range prop.lookupSources() {
+		details, err := m.GetMovie(ctx, prop.IDFor(source), source)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		details.UniqueIDs = mergeIDs(details.UniqueIDs, prop.IDs)
+		return details, nil
+	}
+	return nil, firstErr
+}
+
+// GetSeriesDetails retrieves the details of a series search result like GetMovieDetails
+func (m *Manager) GetSeriesDetails(ctx context.Context, prop UnifiedProposition) (*UnifiedSeriesProposition, error) {
+	var firstErr error
+	for _, source := range prop.lookupSources() {
+		details, err := m.GetSeries(ctx, prop.IDFor(source), source)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		details.UniqueIDs = mergeIDs(details.UniqueIDs, prop.IDs)
+		return details, nil
+	}
+	return nil, firstErr
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"kodi-renamer/internal/apitest"
+)
+
+func TestMergeDuplicates(t *testing.T) {
+	props := []UnifiedProposition{
+		{ID: "1", Source: "tvdb", Title: "Dune", Year: "2021", Type: "movie", IDs: map[string]string{"tmdb": "438631"}},
+		{ID: "2", Source: "tvdb", Title: "Dune", Year: "1984", Type: "movie", IDs: map[string]string{"imdb": "tt0087182"}},
+		{ID: "438631", Source: "tmdb", Title: "Dune: Part One", Year: "2021", Type: "movie", Popularity: 90},
+		// Same title and year as TVDB 2, but TheTVDB knows TMDB 841 is another film
+		{ID: "841", Source: "tmdb", Title: "Dune", Year: "2021", Type: "movie"},
+		{ID: "9000", Source: "tmdb", Title: "Dune", Year: "1984", Type: "movie", Overview: "A Duke's son..."},
+		{ID: "3", Source: "tmdb", Title: "Dune", Year: "2000", Type: "series"},
+	}
+
+	merged := mergeDuplicates(props)
+
+	var got []string
+	for _, p := range merged {
+		got = append(got, p.ID+"@"+p.SourceLabel())
+	}
+	if want := "1@tvdb+tmdb,2@tvdb+tmdb,841@tmdb,3@tmdb"; strings.Join(got, ",") != want {
+		t.Fatalf("mergeDuplicates() = %s, want %s", strings.Join(got, ","), want)
+	}
+	if merged[0].Popularity != 90 || merged[0].IDFor("tvdb") != "1" || merged[0].IDFor("tmdb") != "438631" {
+		t.Errorf("merged by remote ID = %+v", merged[0])
+	}
+	if merged[1].IDFor("tmdb") != "9000" || merged[1].IDFor("imdb") != "tt0087182" || merged[1].Overview == "" {
+		t.Errorf("merged by title and year = %+v", merged[1])
+	}
+	if props[0].IDs["tvdb"] != "" {
+		t.Error("mergeDuplicates() modified the IDs of its input")
+	}
+}
+
+func TestGetDetailsKeepsEveryID(t *testing.T) {
+	server := apitest.NewServer(t)
+	m := newTestManager(t, server)
+
+	// TheTVDB does not know movie 999: the details come from TMDB, with both IDs kept
+	movie, err := m.GetMovieDetails(context.Background(), UnifiedProposition{
+		ID: "999", Source: "tvdb", Sources: []string{"tvdb"}, IDs: map[string]string{"tvdb": "999", "tmdb": "603"},
+	})
+	if err != nil {
+		t.Fatalf("GetMovieDetails() error = %v", err)
+	}
+	if movie.Source != "tmdb" || movie.UniqueIDs["tvdb"] != "999" || movie.UniqueIDs["tmdb"] != "603" || movie.UniqueIDs["imdb"] != "tt0133093" {
+		t.Errorf("GetMovieDetails() = %+v, want TMDB details with the TVDB, TMDB and IMDB IDs", movie)
+	}
+
+	series, err := m.GetSeries(context.Background(), "1396", "tmdb")
+	if err != nil {
+		t.Fatalf("GetSeries() error = %v", err)
+	}
+	if series.UniqueIDs["tvdb"] != "81189" || series.UniqueIDs["imdb"] != "tt0903747" {
+		t.Errorf("GetSeries() IDs = %v, want the TMDB external IDs", series.UniqueIDs)
+	}
+}
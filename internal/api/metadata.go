@@ -0,0 +1,259 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"kodi-renamer/internal/tmdb"
+	"kodi-renamer/internal/tvdb"
+)
+
+// Release types of MovieMetadata release dates; TheTVDB releases have no type
+const (
+	ReleasePremiere   = "premiere"
+	ReleaseLimited    = "limited"
+	ReleaseTheatrical = "theatrical"
+	ReleaseDigital    = "digital"
+	ReleasePhysical   = "physical"
+	ReleaseTV         = "tv"
+)
+
+// tmdbReleaseTypes maps the TMDB release types to ours
+var tmdbReleaseTypes = map[int]string{
+	1: ReleasePremiere,
+	2: ReleaseLimited,
+	3: ReleaseTheatrical,
+	4: ReleaseDigital,
+	5: ReleasePhysical,
+	6: ReleaseTV,
+}
+
+// MovieMetadata is the extended metadata of a movie, from either provider, for NFOs and reports.
+// Countries are ISO 3166-1 alpha-2 codes such as "US"; OriginalLanguage is given as the provider
+// does (ISO 639-1 on TMDB, ISO 639-2 on TheTVDB).
+type MovieMetadata struct {
+	// Source is the provider the metadata came from
+	Source           string
+	OriginalTitle    string
+	OriginalLanguage string
+	Tagline          string
+	Countries        []string
+	Directors        []string
+	Writers          []string
+	Cast             []CastMember
+	Keywords         []string
+	// Certifications maps countries to the certification of the movie there, like "US": "R"
+	Certifications map[string]string
+	ReleaseDates   []ReleaseDate
+}
+
+// CastMember is an actor of a movie and the character they play, Order 0 being top billed
+type CastMember struct {
+	Name  string
+	Role  string
+	Order int
+}
+
+// ReleaseDate is the release of a movie in a country on a "YYYY-MM-DD" date
+type ReleaseDate struct {
+	Country string
+	Date    string
+	// Type is one of the Release constants, empty when unknown
+	Type string
+}
+
+// Certification returns the certification of the movie in a country, or "" when unknown
+func (m *MovieMetadata) Certification(country string) string {
+	return m.Certifications[strings.ToUpper(country)]
+}
+
+// ReleaseDateIn returns the first release date of the movie in a country, preferring cinema
+// releases over digital, physical and TV ones, or "" when unknown
+func (m *MovieMetadata) ReleaseDateIn(country string) string {
+	country = strings.ToUpper(country)
+	first, firstAny := "", ""
+	for _, release := range m.ReleaseDates {
+		if release.Country != country || release.Date == "" {
+			continue
+		}
+		if firstAny == "" || release.Date < firstAny {
+			firstAny = release.Date
+		}
+		switch release.Type {
+		case "", ReleasePremiere, ReleaseLimited, ReleaseTheatrical:
+			if first == "" || release.Date < first {
+				first = release.Date
+			}
+		}
+	}
+	if first != "" {
+		return first
+	}
+	return firstAny
+}
+
+// GetMovieMetadata retrieves the extended metadata of a movie by ID from a source ("tvdb" or "tmdb"),
+// in a single request; the movie details come with it
+func (m *Manager) GetMovieMetadata(ctx context.Context, id, source string) (*UnifiedMovieProposition, error) {
+	switch source {
+	case "tvdb":
+		if !m.hasTVDB {
+			return nil, fmt.Errorf("TVDB not configured")
+		}
+		movie, err := m.tvdbClient.GetMovieMetadata(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return tvdbMovieMetadata(movie), nil
+
+	case "tmdb":
+		if !m.hasTMDB {
+			return nil, fmt.Errorf("TMDB not configured")
+		}
+		movieID, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TMDB ID: %w", err)
+		}
+		movie, err := m.tmdbClient.GetMovieMetadata(ctx, movieID)
+		if err != nil {
+			return nil, err
+		}
+		return tmdbMovieMetadata(movie), nil
+
+	default:
+		return nil, fmt.Errorf("unknown source: %s", source)
+	}
+}
+
+// FillMetadata fetches the extended metadata of a movie into its Metadata field. TMDB is asked first
+// when the movie has a TMDB ID, as it knows keywords and release types, then the source of the details;
+// the IDs and collection found on the way are kept.
+func (m *Manager) FillMetadata(ctx context.Context, movie *UnifiedMovieProposition) error {
+	sources := []string{movie.Source}
+	if movie.Source != "tmdb" && movie.UniqueIDs["tmdb"] != "" && m.hasTMDB {
+		sources = []string{"tmdb", movie.Source}
+	}
+
+	var firstErr error
+	for _, source := range sources {
+		id := movie.UniqueIDs[source]
+		if source == movie.Source {
+			id = movie.ID
+		}
+		details, err := m.GetMovieMetadata(ctx, id, source)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		movie.Metadata = details.Metadata
+		movie.UniqueIDs = mergeIDs(movie.UniqueIDs, details.UniqueIDs)
+		if movie.CollectionID == "" {
+			movie.CollectionID, movie.CollectionName = details.CollectionID, details.CollectionName
+		}
+		return nil
+	}
+	return fmt.Errorf("failed to get movie metadata: %w", firstErr)
+}
+
+// tmdbMovieMetadata converts TMDB metadata to the unified model
+func tmdbMovieMetadata(movie *tmdb.MovieMetadata) *UnifiedMovieProposition {
+	metadata := &MovieMetadata{
+		Source:           "tmdb",
+		OriginalTitle:    movie.OriginalTitle,
+		OriginalLanguage: movie.OriginalLanguage,
+		Tagline:          movie.Tagline,
+		Countries:        movie.Countries,
+		Directors:        movie.Directors,
+		Writers:          movie.Writers,
+		Keywords:         movie.Keywords,
+		Certifications:   make(map[string]string),
+	}
+	for _, cast := range movie.Cast {
+		metadata.Cast = append(metadata.Cast, CastMember{Name: cast.Name, Role: cast.Character, Order: cast.Order})
+	}
+	slices.SortStableFunc(metadata.Cast, func(a, b CastMember) int { return a.Order - b.Order })
+
+	countries := make([]string, 0, len(movie.Releases))
+	for country := range movie.Releases {
+		countries = append(countries, country)
+	}
+	slices.Sort(countries)
+	for _, country := range countries {
+		for _, release := range movie.Releases[country] {
+			if release.Certification != "" && metadata.Certifications[country] == "" {
+				metadata.Certifications[country] = release.Certification
+			}
+			metadata.ReleaseDates = append(metadata.ReleaseDates, ReleaseDate{
+				Country: country,
+				Date:    releaseDay(release.ReleaseDate),
+				Type:    tmdbReleaseTypes[release.Type],
+			})
+		}
+	}
+
+	return &UnifiedMovieProposition{
+		ID:       strconv.Itoa(movie.ID),
+		Title:    movie.Title,
+		Overview: movie.Overview,
+		Year:     movie.Year,
+		Runtime:  movie.Runtime,
+		Genres:   movie.Genres,
+		Source:   "tmdb",
+		UniqueIDs: externalIDs(map[string]string{
+			"tmdb": strconv.Itoa(movie.ID),
+			"imdb": movie.ImdbID,
+		}),
+		CollectionID:   formatOptionalID(movie.CollectionID),
+		CollectionName: movie.CollectionName,
+		Metadata:       metadata,
+	}
+}
+
+// tvdbMovieMetadata converts TheTVDB metadata to the unified model
+func tvdbMovieMetadata(movie *tvdb.MovieMetadata) *UnifiedMovieProposition {
+	metadata := &MovieMetadata{
+		Source:           "tvdb",
+		OriginalLanguage: movie.OriginalLanguage,
+		Countries:        movie.Countries,
+		Directors:        movie.Directors,
+		Writers:          movie.Writers,
+		Keywords:         movie.Keywords,
+		Certifications:   movie.Certifications,
+	}
+	for _, cast := range movie.Cast {
+		metadata.Cast = append(metadata.Cast, CastMember{Name: cast.Name, Role: cast.Role, Order: cast.Order})
+	}
+	for _, release := range movie.Releases {
+		metadata.ReleaseDates = append(metadata.ReleaseDates, ReleaseDate{Country: release.Country, Date: releaseDay(release.Date)})
+	}
+
+	id := strconv.FormatInt(movie.ID, 10)
+	return &UnifiedMovieProposition{
+		ID:        id,
+		Title:     movie.Title,
+		Overview:  movie.Overview,
+		Year:      movie.Year,
+		Runtime:   movie.Runtime,
+		Genres:    movie.Genres,
+		Source:    "tvdb",
+		UniqueIDs: mergeIDs(map[string]string{"tvdb": id}, movie.RemoteIDs),
+		Metadata:  metadata,
+	}
+}
+
+// releaseDay keeps the "YYYY-MM-DD" day of a release date, which TMDB gives as a timestamp
+func releaseDay(date string) string {
+	if len(date) > 10 {
+		return date[:10]
+	}
+	return date
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"kodi-renamer/internal/apitest"
+)
+
+func TestGetMovieMetadata(t *testing.T) {
+	server := apitest.NewServer(t)
+	m := newTestManager(t, server)
+
+	movie, err := m.GetMovieMetadata(context.Background(), "603", "tmdb")
+	if err != nil {
+		t.Fatalf("GetMovieMetadata(tmdb) error = %v", err)
+	}
+	meta := movie.Metadata
+	if meta == nil || meta.Source != "tmdb" {
+		t.Fatalf("GetMovieMetadata(tmdb) metadata = %+v", meta)
+	}
+	if got := strings.Join(meta.Directors, ", "); got != "Lilly Wachowski, Lana Wachowski" {
+		t.Errorf("directors = %q", got)
+	}
+	if got := strings.Join(meta.Writers, ", "); got != "Lilly Wachowski, Lana Wachowski" {
+		t.Errorf("writers = %q", got)
+	}
+	if len(meta.Cast) != 3 || meta.Cast[0].Name != "Keanu Reeves" || meta.Cast[1].Role != "Morpheus" {
+		t.Errorf("cast = %+v, want Keanu Reeves first", meta.Cast)
+	}
+	if meta.Certification("us") != "R" || meta.Certification("DE") != "16" || meta.Certification("FR") != "" {
+		t.Errorf("certifications = %v", meta.Certifications)
+	}
+	// The Los Angeles premiere comes before the theatrical release, the DVD after it
+	if got := meta.ReleaseDateIn("US"); got != "1999-03-24" {
+		t.Errorf("ReleaseDateIn(US) = %q, want 1999-03-24", got)
+	}
+	if meta.Tagline != "Welcome to the Real World." || meta.OriginalLanguage != "en" || !slices.Equal(meta.Countries, []string{"US"}) {
+		t.Errorf("metadata = %+v", meta)
+	}
+	if !slices.Equal(meta.Keywords, []string{"artificial intelligence", "dystopia"}) {
+		t.Errorf("keywords = %v", meta.Keywords)
+	}
+	if movie.UniqueIDs["imdb"] != "tt0133093" || movie.CollectionName != "The Matrix Collection" {
+		t.Errorf("GetMovieMetadata(tmdb) = %+v", movie)
+	}
+	if !slices.Contains(server.Requests(), "tmdb GET /movie/603?append_to_response=credits%2Crelease_dates%2Ckeywords%2Cexternal_ids") {
+		t.Errorf("metadata not fetched in a single request: %v", server.Requests())
+	}
+
+	movie, err = m.GetMovieMetadata(context.Background(), "169", "tvdb")
+	if err != nil {
+		t.Fatalf("GetMovieMetadata(tvdb) error = %v", err)
+	}
+	meta = movie.Metadata
+	if got := strings.Join(meta.Directors, ", "); got != "Lana Wachowski, Lilly Wachowski" {
+		t.Errorf("TVDB directors = %q", got)
+	}
+	if len(meta.Cast) != 2 || meta.Cast[0].Name != "Keanu Reeves" || meta.Cast[0].Role != "Neo" {
+		t.Errorf("TVDB cast = %+v", meta.Cast)
+	}
+	if meta.Certification("US") != "R" || meta.Certification("GB") != "15" || meta.ReleaseDateIn("GB") != "1999-06-11" {
+		t.Errorf("TVDB certifications = %v, releases = %v", meta.Certifications, meta.ReleaseDates)
+	}
+	if movie.UniqueIDs["tmdb"] != "603" || movie.UniqueIDs["imdb"] != "tt0133093" || !slices.Equal(meta.Countries, []string{"US"}) {
+		t.Errorf("GetMovieMetadata(tvdb) = %+v, %+v", movie, meta)
+	}
+}
+
+func TestFillMetadataPrefersTMDB(t *testing.T) {
+	server := apitest.NewServer(t)
+	m := newTestManager(t, server)
+
+	movie := &UnifiedMovieProposition{ID: "169", Source: "tvdb", Title: "The Matrix", UniqueIDs: map[string]string{"tvdb": "169", "tmdb": "603"}}
+	if err := m.FillMetadata(context.Background(), movie); err != nil {
+		t.Fatalf("FillMetadata() error = %v", err)
+	}
+	if movie.Metadata == nil || movie.Metadata.Source != "tmdb" || len(movie.Metadata.Keywords) == 0 {
+		t.Errorf("FillMetadata() metadata = %+v, want TMDB's", movie.Metadata)
+	}
+	if movie.Source != "tvdb" || movie.UniqueIDs["tvdb"] != "169" || movie.UniqueIDs["imdb"] != "tt0133093" {
+		t.Errorf("FillMetadata() = %+v, want the TVDB details with every ID", movie)
+	}
+	if movie.CollectionName != "The Matrix Collection" {
+		t.Errorf("FillMetadata() collection = %q", movie.CollectionName)
+	}
+
+	// Without TMDB ID the metadata comes from TheTVDB, which knows the remote IDs
+	movie = &UnifiedMovieProposition{ID: "169", Source: "tvdb", UniqueIDs: map[string]string{"tvdb": "169"}}
+	if err := m.FillMetadata(context.Background(), movie); err != nil {
+		t.Fatalf("FillMetadata() error = %v", err)
+	}
+	if movie.Metadata == nil || movie.Metadata.Source != "tvdb" || movie.UniqueIDs["tmdb"] != "603" {
+		t.Errorf("FillMetadata() = %+v, metadata %+v, want TheTVDB's", movie, movie.Metadata)
+	}
+}
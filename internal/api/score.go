@@ -0,0 +1,216 @@
+package api
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ScoreQuery describes what a search was looking for, for scoring its results
+type ScoreQuery struct {
+	Query string
+	// Year is the requested release year, 0 when unknown
+	Year int
+	// Type is "movie" or "series", empty when any type is acceptable
+	Type string
+}
+
+// ScoreBreakdown holds the components of a result's score, each between 0 and 1, and their weighted
+// total; components that do not apply to the query (the year when none is known) are left out
+type ScoreBreakdown struct {
+	Title      float64
+	Year       float64
+	HasYear    bool
+	Type       float64
+	HasType    bool
+	Popularity float64
+	Rating     float64
+	HasRating  bool
+	Provider   float64
+	Total      float64
+}
+
+// String formats the breakdown for the selection tables, like "title 1.00 year 0.67 type 1.00 pop 0.77 rate 0.81 src 0.50"
+func (b ScoreBreakdown) String() string {
+	parts := []string{"title " + formatScore(b.Title)}
+	if b.HasYear {
+		parts = append(parts, "year "+formatScore(b.Year))
+	}
+	if b.HasType {
+		parts = append(parts, "type "+formatScore(b.Type))
+	}
+	parts = append(parts, "pop "+formatScore(b.Popularity))
+	if b.HasRating {
+		parts = append(parts, "rate "+formatScore(b.Rating))
+	}
+	parts = append(parts, "src "+formatScore(b.Provider))
+	return strings.Join(parts, " ")
+}
+
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', 2, 64)
+}
+
+// Scorer scores search results against the query; results are ranked by descending total
+type Scorer interface {
+	Score(query ScoreQuery, prop UnifiedProposition) ScoreBreakdown
+}
+
+// WeightedScorer combines title similarity, year proximity, type, popularity, user rating and provider
+// priority with fixed weights
+type WeightedScorer struct {
+	TitleWeight      float64
+	YearWeight       float64
+	TypeWeight       float64
+	PopularityWeight float64
+	RatingWeight     float64
+	ProviderWeight   float64
+	// ProviderPriority lists the sources from the most to the least trusted
+	ProviderPriority []string
+}
+
+// DefaultScorer returns the scorer used unless the Manager is given another one: the title matters
+// most, then the year, while TVDB is still preferred over TMDB when everything else is equal
+func DefaultScorer() *WeightedScorer {
+	return &WeightedScorer{
+		TitleWeight:      0.5,
+		YearWeight:       0.2,
+		TypeWeight:       0.1,
+		PopularityWeight: 0.1,
+		RatingWeight:     0.05,
+		ProviderWeight:   0.1,
+		ProviderPriority: []string{"tvdb", "tmdb"},
+	}
+}
+
+// Score computes the breakdown of a result; the total is normalised by the weights that apply
+func (s *WeightedScorer) Score(query ScoreQuery, prop UnifiedProposition) ScoreBreakdown {
+	b := ScoreBreakdown{
+		Title:      titleSimilarity(query.Query, prop),
+		Popularity: popularityScore(prop.Popularity),
+		Provider:   s.providerScore(prop.Source),
+	}
+	total := s.TitleWeight*b.Title + s.PopularityWeight*b.Popularity + s.ProviderWeight*b.Provider
+	weights := s.TitleWeight + s.PopularityWeight + s.ProviderWeight
+
+	if query.Year > 0 {
+		b.HasYear = true
+		b.Year = yearProximity(query.Year, prop.GetYearAsInt())
+		total += s.YearWeight * b.Year
+		weights += s.YearWeight
+	}
+	if query.Type != "" {
+		b.HasType = true
+		if prop.Type == query.Type {
+			b.Type = 1
+		}
+		total += s.TypeWeight * b.Type
+		weights += s.TypeWeight
+	}
+	// Only TMDB rates its results: the rating counts when there is one, so unrated results are not penalised
+	if prop.VoteAverage > 0 {
+		b.HasRating = true
+		b.Rating = math.Min(prop.VoteAverage/10, 1)
+		total += s.RatingWeight * b.Rating
+		weights += s.RatingWeight
+	}
+
+	if weights > 0 {
+		b.Total = total / weights
+	}
+	return b
+}
+
+// providerScore is 1 for the first source of ProviderPriority, decreasing down the list, and 0 for
+// unlisted sources
+func (s *WeightedScorer) providerScore(source string) float64 {
+	for i, p := range s.ProviderPriority {
+		if p == source {
+			return 1 - float64(i)/float64(len(s.ProviderPriority))
+		}
+	}
+	return 0
+}
+
+// popularityScore maps the provider popularity onto 0..1; results without popularity (TVDB search
+// results have none) are neutral
+func popularityScore(popularity float64) float64 {
+	if popularity <= 0 {
+		return 0.5
+	}
+	return popularity / (popularity + 50)
+}
+
+// yearProximity is 1 for the requested year, decreasing by a third per year of difference, and 0
+// when the result has no year
+func yearProximity(want, got int) float64 {
+	if got <= 0 {
+		return 0
+	}
+	diff := math.Abs(float64(want - got))
+	return math.Max(0, 1-diff/3)
+}
+
+// titleSimilarity compares the query with the best matching name of a result, between 0 and 1
+func titleSimilarity(query string, prop UnifiedProposition) float64 {
+	want := normalizeTitle(query)
+	best := 0.0
+	for _, name := range []string{prop.Title, prop.Name, prop.OriginalName} {
+		if name == "" {
+			continue
+		}
+		best = math.Max(best, similarity(want, normalizeTitle(name)))
+	}
+	return best
+}
+
+// similarity is 1 minus the edit distance of two strings relative to the longest one
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein returns the number of rune insertions, deletions and substitutions turning a into b
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// SetScorer replaces the scorer ranking the results of every search
+func (m *Manager) SetScorer(scorer Scorer) {
+	m.scorer = scorer
+}
+
+// rank scores the results with the Manager's scorer and sorts them from the best match down
+func (m *Manager) rank(query ScoreQuery, props []UnifiedProposition) {
+	for i := range props {
+		props[i].ScoreBreakdown = m.scorer.Score(query, props[i])
+		props[i].Score = props[i].ScoreBreakdown.Total
+	}
+	sort.SliceStable(props, func(i, j int) bool {
+		return props[i].Score > props[j].Score
+	})
+}
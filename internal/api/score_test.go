@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"kodi-renamer/internal/apitest"
+)
+
+func TestWeightedScorer(t *testing.T) {
+	scorer := DefaultScorer()
+	matrix := UnifiedProposition{Title: "The Matrix", Year: "1999", Type: "movie", Source: "tmdb", Popularity: 50}
+
+	b := scorer.Score(ScoreQuery{Query: "Matrix", Year: 1999, Type: "movie"}, matrix)
+	if b.Title != 1 || b.Year != 1 || b.Type != 1 || b.Popularity != 0.5 || b.Provider != 0.5 {
+		t.Errorf("Score() = %+v, want full title, year and type matches", b)
+	}
+	if got, want := b.String(), "title 1.00 year 1.00 type 1.00 pop 0.50 src 0.50"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	// Without a requested year, the year neither counts nor shows
+	b = scorer.Score(ScoreQuery{Query: "The Matrix"}, matrix)
+	if b.HasYear || b.HasType || strings.Contains(b.String(), "year") {
+		t.Errorf("Score() without year = %+v (%s), want no year or type component", b, b)
+	}
+	if b.Total < 0.8 {
+		t.Errorf("Score() total = %.2f, want an exact title to dominate", b.Total)
+	}
+}
+
+func TestWeightedScorerRating(t *testing.T) {
+	scorer := DefaultScorer()
+	query := ScoreQuery{Query: "The Matrix"}
+	unrated := UnifiedProposition{Title: "The Matrix", Source: "tmdb", Popularity: 50}
+	rated := unrated
+	rated.VoteAverage = 8.2
+
+	b := scorer.Score(query, rated)
+	if !b.HasRating || b.Rating != 0.82 || b.Popularity != 0.5 {
+		t.Errorf("Score() = %+v, want the raw popularity and a separate 0.82 rating", b)
+	}
+	if got, want := b.String(), "title 1.00 pop 0.50 rate 0.82 src 0.50"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	// Without a rating the component neither counts nor shows
+	if b := scorer.Score(query, unrated); b.HasRating || strings.Contains(b.String(), "rate") {
+		t.Errorf("Score() without rating = %+v (%s), want no rating component", b, b)
+	}
+}
+
+func TestRankWithoutYearDoesNotFavourOldResults(t *testing.T) {
+	m := &Manager{scorer: DefaultScorer()}
+	props := []UnifiedProposition{
+		{ID: "1", Title: "Dune", Year: "1984", Source: "tmdb", Popularity: 10},
+		{ID: "2", Title: "Dune", Year: "", Source: "tmdb", Popularity: 5},
+		{ID: "3", Title: "Dune", Year: "2021", Source: "tmdb", Popularity: 200},
+		{ID: "4", Title: "Dune Drifter", Year: "2020", Source: "tmdb", Popularity: 300},
+	}
+
+	m.rank(ScoreQuery{Query: "Dune", Type: "movie"}, props)
+
+	if got := strings.Join(resultKeys(props), ","); got != "tmdb:3,tmdb:1,tmdb:2,tmdb:4" {
+		t.Errorf("rank() = %s, want the popular exact title first and the other title last", got)
+	}
+	for i := 1; i < len(props); i++ {
+		if props[i].Score > props[i-1].Score {
+			t.Errorf("rank() is not sorted by score: %v", props)
+		}
+	}
+}
+
+// sourceScorer ranks results by source only
+type sourceScorer string
+
+func (s sourceScorer) Score(_ ScoreQuery, prop UnifiedProposition) ScoreBreakdown {
+	if prop.Source == string(s) {
+		return ScoreBreakdown{Total: 1}
+	}
+	return ScoreBreakdown{}
+}
+
+func TestSetScorer(t *testing.T) {
+	server := apitest.NewServer(t)
+	m := newTestManager(t, server)
+	m.SetScorer(sourceScorer("tmdb"))
+
+	props, err := m.SearchMovies(context.Background(), "The Matrix", 1999)
+	if err != nil {
+		t.Fatalf("SearchMovies() error = %v", err)
+	}
+	if got := strings.Join(resultKeys(props), ","); got != "tmdb:624860,tvdb:169,tvdb:553" {
+		t.Errorf("SearchMovies() = %s, want the TMDB-only result first with the custom scorer", got)
+	}
+}
@@ -0,0 +1,247 @@
+// Package apitest serves recorded TheTVDB and TMDb responses from a local HTTP server, so that the
+// API clients and the renaming flows can be tested without the internet
+package apitest
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	// TVDBAPIKey is the only TheTVDB API key the fake server accepts
+	TVDBAPIKey = "test-tvdb-key"
+	// TMDBAPIKey is the only TMDb API key the fake server accepts
+	TMDBAPIKey = "test-tmdb-key"
+
+	tvdbPrefix = "/tvdb/v4"
+	tmdbPrefix = "/tmdb/3"
+)
+
+//go:embed testdata
+var fixtures embed.FS
+
+// route maps a request to a recorded response, listed in testdata/<provider>/routes.json
+type route struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Query lists the parameters the request must carry; others are ignored
+	Query  map[string]string `json:"query"`
+	Status int               `json:"status"`
+	File   string            `json:"file"`
+}
+
+// Server is a fake TheTVDB and TMDb API. TheTVDB requires a token from /login, which the server
+// issues and can expire; TMDb requires TMDBAPIKey as api_key. Unknown requests get a 404.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	routes   map[string][]route
+	tokens   map[string]bool
+	issued   int
+	logins   int
+	failures map[string]int
+	requests []string
+}
+
+// NewServer starts a fake server that is closed when the test ends
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{
+		routes:   make(map[string][]route),
+		tokens:   make(map[string]bool),
+		failures: make(map[string]int),
+	}
+	for _, provider := range []string{"tvdb", "tmdb"} {
+		data, err := fixtures.ReadFile(path.Join("testdata", provider, "routes.json"))
+		if err != nil {
+			t.Fatalf("apitest: failed to read %s routes: %v", provider, err)
+		}
+		var routes []route
+		if err := json.Unmarshal(data, &routes); err != nil {
+			t.Fatalf("apitest: failed to parse %s routes: %v", provider, err)
+		}
+		s.routes[provider] = routes
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(tvdbPrefix+"/", s.serveTVDB)
+	mux.HandleFunc(tmdbPrefix+"/", s.serveTMDB)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// TVDBURL returns the base URL to use instead of tvdb.BaseURL
+func (s *Server) TVDBURL() string {
+	return s.URL + tvdbPrefix
+}
+
+// TMDBURL returns the base URL to use instead of tmdb.BaseURL
+func (s *Server) TMDBURL() string {
+	return s.URL + tmdbPrefix
+}
+
+// Requests returns the requests served so far, as "provider METHOD /path?query"
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// Logins returns how many successful TheTVDB logins the server handled
+func (s *Server) Logins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+// ExpireTokens invalidates every TheTVDB token issued so far, as if they had expired
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = make(map[string]bool)
+}
+
+// FailNext makes the next times requests to a provider path ("/search/movie") answer 503
+func (s *Server) FailNext(provider, requestPath string, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[provider+" "+requestPath] = times
+}
+
+// serveTVDB handles /login and the token-protected TheTVDB endpoints
+func (s *Server) serveTVDB(w http.ResponseWriter, r *http.Request) {
+	requestPath := strings.TrimPrefix(r.URL.Path, tvdbPrefix)
+	if s.record("tvdb", r, requestPath) {
+		writeJSON(w, http.StatusServiceUnavailable, `{"status":"failure","message":"ServiceUnavailable"}`)
+		return
+	}
+
+	if requestPath == "/login" && r.Method == http.MethodPost {
+		s.login(w, r)
+		return
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	s.mu.Lock()
+	valid := s.tokens[token]
+	s.mu.Unlock()
+	if !valid {
+		writeJSON(w, http.StatusUnauthorized, `{"status":"failure","message":"Unauthorized","data":null}`)
+		return
+	}
+
+	s.serveFixture(w, r, "tvdb", requestPath, `{"status":"failure","message":"NotFound","data":null}`)
+}
+
+// login checks the API key and issues a new token
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		APIKey string `json:"apikey"`
+		PIN    string `json:"pin"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.APIKey != TVDBAPIKey {
+		writeJSON(w, http.StatusUnauthorized, `{"status":"failure","message":"InvalidAPIKey","data":null}`)
+		return
+	}
+
+	s.mu.Lock()
+	s.issued++
+	s.logins++
+	token := fmt.Sprintf("fake-token-%d", s.issued)
+	s.tokens[token] = true
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, fmt.Sprintf(`{"status":"success","data":{"token":%q}}`, token))
+}
+
+// serveTMDB handles the api_key-protected TMDb endpoints
+func (s *Server) serveTMDB(w http.ResponseWriter, r *http.Request) {
+	requestPath := strings.TrimPrefix(r.URL.Path, tmdbPrefix)
+	if s.record("tmdb", r, requestPath) {
+		writeJSON(w, http.StatusServiceUnavailable, `{"status_code":43,"status_message":"Service unavailable.","success":false}`)
+		return
+	}
+
+	if r.URL.Query().Get("api_key") != TMDBAPIKey {
+		writeJSON(w, http.StatusUnauthorized, `{"status_code":7,"status_message":"Invalid API key: You must be granted a valid key.","success":false}`)
+		return
+	}
+
+	s.serveFixture(w, r, "tmdb", requestPath, `{"status_code":34,"status_message":"The resource you requested could not be found.","success":false}`)
+}
+
+// record logs a request and reports whether it must fail because of FailNext
+func (s *Server) record(provider string, r *http.Request, requestPath string) bool {
+	query := r.URL.Query()
+	query.Del("api_key")
+	entry := provider + " " + r.Method + " " + requestPath
+	if encoded := query.Encode(); encoded != "" {
+		entry += "?" + encoded
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, entry)
+
+	key := provider + " " + requestPath
+	if s.failures[key] > 0 {
+		s.failures[key]--
+		return true
+	}
+	return false
+}
+
+// serveFixture answers with the first route matching the request, or notFound
+func (s *Server) serveFixture(w http.ResponseWriter, r *http.Request, provider, requestPath, notFound string) {
+	for _, rt := range s.routes[provider] {
+		if !rt.matches(r, requestPath) {
+			continue
+		}
+		data, err := fixtures.ReadFile(path.Join("testdata", provider, rt.File))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		status := rt.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		writeJSON(w, status, string(data))
+		return
+	}
+	writeJSON(w, http.StatusNotFound, notFound)
+}
+
+// matches reports whether the request has the route's method, path and query parameters
+func (rt route) matches(r *http.Request, requestPath string) bool {
+	method := rt.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	if r.Method != method || requestPath != rt.Path {
+		return false
+	}
+	query := r.URL.Query()
+	for name, value := range rt.Query {
+		if !strings.EqualFold(query.Get(name), value) {
+			return false
+		}
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprint(w, body)
+}
@@ -0,0 +1,228 @@
+package archive
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kind is the format of an archive set
+type Kind string
+
+const (
+	// KindZIP is a single ZIP file
+	KindZIP Kind = "zip"
+	// KindRAR is a RAR archive, possibly split in volumes (.rar/.r00 or .part1.rar)
+	KindRAR Kind = "rar"
+)
+
+var (
+	// partVolumePattern matches new-style RAR volumes: "name.part01.rar"
+	partVolumePattern = regexp.MustCompile(`(?i)^(.+)\.part(\d+)\.rar$`)
+	// oldVolumePattern matches old-style RAR continuation volumes: "name.r00", "name.s00"
+	oldVolumePattern = regexp.MustCompile(`(?i)^(.+)\.([r-z])(\d{2,3})$`)
+
+	// ErrNotExtractable is returned when an entry uses a compression or encryption that cannot be extracted natively
+	ErrNotExtractable = errors.New("entry cannot be extracted natively")
+)
+
+// Set is a ZIP file or a group of RAR volumes holding one archive
+type Set struct {
+	Kind    Kind
+	Name    string   // Base name shared by the volumes, without volume suffix
+	Volumes []string // Volume paths in reading order, the first volume first
+}
+
+// Entry is a file stored in an archive set
+type Entry struct {
+	Name        string // Path inside the archive, with forward slashes
+	Size        int64  // Uncompressed size in bytes
+	Extractable bool   // Stored (uncompressed) RAR data or a ZIP method supported natively, not encrypted
+}
+
+// Member is a file inside an archive set
+type Member struct {
+	Set   Set
+	Entry Entry
+}
+
+// volumeOrder gives the position of a volume name inside its set and the set it belongs to
+func volumeOrder(name string) (kind Kind, setName string, order int, ok bool) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return KindZIP, name[:len(name)-4], 0, true
+	case partVolumePattern.MatchString(name):
+		m := partVolumePattern.FindStringSubmatch(name)
+		n, _ := strconv.Atoi(m[2])
+		return KindRAR, m[1], n, true
+	case strings.HasSuffix(lower, ".rar"):
+		return KindRAR, name[:len(name)-4], -1, true
+	case oldVolumePattern.MatchString(name):
+		m := oldVolumePattern.FindStringSubmatch(name)
+		n, _ := strconv.Atoi(m[3])
+		// .r00-.r99 come first, then .s00-.s99 and so on
+		letter := int(strings.ToLower(m[2])[0] - 'r')
+		return KindRAR, m[1], letter*1000 + n, true
+	}
+	return "", "", 0, false
+}
+
+// IsVolume reports whether a file name is a ZIP file or a RAR volume
+func IsVolume(name string) bool {
+	_, _, _, ok := volumeOrder(name)
+	return ok
+}
+
+// FindSets groups the archive volumes among the file names of a directory into sets; old-style
+// continuation volumes (.r00) without their .rar first volume are left out
+func FindSets(dir string, names []string) []Set {
+	type volume struct {
+		name  string
+		order int
+	}
+	type key struct {
+		kind Kind
+		name string
+	}
+	groups := make(map[key][]volume)
+	var keys []key
+
+	for _, name := range names {
+		kind, setName, order, ok := volumeOrder(name)
+		if !ok {
+			continue
+		}
+		k := key{kind, setName}
+		if _, seen := groups[k]; !seen {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], volume{name, order})
+	}
+
+	var sets []Set
+	for _, k := range keys {
+		volumes := groups[k]
+		sort.Slice(volumes, func(i, j int) bool { return volumes[i].order < volumes[j].order })
+		if k.kind == KindRAR && oldVolumePattern.MatchString(volumes[0].name) {
+			continue
+		}
+
+		set := Set{Kind: k.kind, Name: k.name}
+		for _, v := range volumes {
+			set.Volumes = append(set.Volumes, filepath.Join(dir, v.name))
+		}
+		sets = append(sets, set)
+	}
+
+	sort.Slice(sets, func(i, j int) bool { return sets[i].Volumes[0] < sets[j].Volumes[0] })
+	return sets
+}
+
+// List returns the files of the archive set; directories are left out
+func (s Set) List() ([]Entry, error) {
+	switch s.Kind {
+	case KindZIP:
+		return listZIP(s.Volumes[0])
+	case KindRAR:
+		files, err := readRARFiles(s.Volumes[0])
+		if err != nil {
+			return nil, err
+		}
+		var entries []Entry
+		for _, file := range files {
+			if file.dir || file.splitBefore {
+				continue
+			}
+			entries = append(entries, Entry{Name: file.name, Size: file.size, Extractable: file.stored && !file.encrypted})
+		}
+		return entries, nil
+	}
+	return nil, fmt.Errorf("unknown archive kind %q", s.Kind)
+}
+
+// ExtractTo extracts an entry of the set to destPath; the file is written next to destPath first
+// and renamed once complete, so an interrupted extraction never leaves a truncated video
+func (s Set) ExtractTo(entryName, destPath string) error {
+	tmpPath := destPath + ".part"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmpPath, err)
+	}
+
+	switch s.Kind {
+	case KindZIP:
+		err = extractZIP(s.Volumes[0], entryName, out)
+	case KindRAR:
+		err = extractRAR(s.Volumes, entryName, out)
+	default:
+		err = fmt.Errorf("unknown archive kind %q", s.Kind)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to extract %s from %s: %w", entryName, s.Volumes[0], err)
+	}
+
+	return os.Rename(tmpPath, destPath)
+}
+
+// listZIP lists the files of a ZIP archive
+func listZIP(zipPath string) ([]Entry, error) {
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var entries []Entry
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		supported := file.Method == zip.Store || file.Method == zip.Deflate
+		encrypted := file.Flags&0x1 != 0
+		entries = append(entries, Entry{
+			Name:        path.Clean(strings.ReplaceAll(file.Name, `\`, "/")),
+			Size:        int64(file.UncompressedSize64),
+			Extractable: supported && !encrypted,
+		})
+	}
+	return entries, nil
+}
+
+// extractZIP copies the uncompressed content of a ZIP entry to w
+func extractZIP(zipPath, entryName string, w io.Writer) error {
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if path.Clean(strings.ReplaceAll(file.Name, `\`, "/")) != entryName {
+			continue
+		}
+		if file.Flags&0x1 != 0 {
+			return ErrNotExtractable
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		_, err = io.Copy(w, rc)
+		return err
+	}
+	return fmt.Errorf("entry %s not found", entryName)
+}
@@ -0,0 +1,183 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// rar4Volume builds a RAR 4 volume holding one file block with the given data and flags
+func rar4Volume(name string, size int64, data []byte, method byte, flags uint16) []byte {
+	var buf bytes.Buffer
+	buf.Write(rar4Signature)
+
+	// Main header
+	buf.Write([]byte{0, 0, 0x73, 0, 0, 13, 0})
+	buf.Write(make([]byte, 6))
+
+	body := make([]byte, 25)
+	binary.LittleEndian.PutUint32(body[0:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(body[4:], uint32(size))
+	body[18] = method
+	binary.LittleEndian.PutUint16(body[19:], uint16(len(name)))
+	body = append(body, name...)
+
+	header := []byte{0, 0, 0x74, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint16(header[3:], flags|0x8000)
+	binary.LittleEndian.PutUint16(header[5:], uint16(7+len(body)))
+	buf.Write(header)
+	buf.Write(body)
+	buf.Write(data)
+
+	// End of archive
+	buf.Write([]byte{0, 0, 0x7b, 0, 0, 7, 0})
+	return buf.Bytes()
+}
+
+// rar5Volume builds a RAR 5 archive holding one file with the given data and compression method
+func rar5Volume(name string, data []byte, method uint64) []byte {
+	vint := func(v uint64) []byte { return binary.AppendUvarint(nil, v) }
+	block := func(fields ...[]byte) []byte {
+		header := bytes.Join(fields, nil)
+		out := make([]byte, 4)
+		out = append(out, vint(uint64(len(header)))...)
+		return append(out, header...)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(rar5Signature)
+	buf.Write(block(vint(1), vint(0), vint(0)))
+	buf.Write(block(vint(2), vint(0x0002), vint(uint64(len(data))),
+		vint(0), vint(uint64(len(data))), vint(0), vint(method<<7), vint(0), vint(uint64(len(name))), []byte(name)))
+	buf.Write(data)
+	buf.Write(block(vint(5), vint(0), vint(0)))
+	return buf.Bytes()
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindSets(t *testing.T) {
+	names := []string{
+		"movie.r01", "movie.rar", "movie.r00", "movie.nfo",
+		"show.part02.rar", "show.part01.rar",
+		"extras.zip",
+		"orphan.r00",
+	}
+	sets := FindSets("/in", names)
+
+	want := []Set{
+		{Kind: KindZIP, Name: "extras", Volumes: []string{"/in/extras.zip"}},
+		{Kind: KindRAR, Name: "movie", Volumes: []string{"/in/movie.rar", "/in/movie.r00", "/in/movie.r01"}},
+		{Kind: KindRAR, Name: "show", Volumes: []string{"/in/show.part01.rar", "/in/show.part02.rar"}},
+	}
+	if !reflect.DeepEqual(sets, want) {
+		t.Errorf("FindSets() = %+v\nwant %+v", sets, want)
+	}
+}
+
+func TestRAR4MultiVolume(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "movie.rar"), rar4Volume(`Movie.2010\Movie.2010.mkv`, 10, []byte("hello"), 0x30, 0x0002))
+	writeFile(t, filepath.Join(dir, "movie.r00"), rar4Volume(`Movie.2010\Movie.2010.mkv`, 10, []byte("world"), 0x30, 0x0001))
+
+	set := FindSets(dir, []string{"movie.r00", "movie.rar"})[0]
+	entries, err := set.List()
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	want := []Entry{{Name: "Movie.2010/Movie.2010.mkv", Size: 10, Extractable: true}}
+	if !reflect.DeepEqual(entries, want) {
+		t.Fatalf("List() = %+v, want %+v", entries, want)
+	}
+
+	dest := filepath.Join(dir, "out.mkv")
+	if err := set.ExtractTo(entries[0].Name, dest); err != nil {
+		t.Fatalf("ExtractTo() error: %v", err)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "helloworld" {
+		t.Errorf("extracted %q", data)
+	}
+}
+
+func TestRAR4MissingVolume(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "movie.rar"), rar4Volume("movie.mkv", 10, []byte("hello"), 0x30, 0x0002))
+
+	set := FindSets(dir, []string{"movie.rar"})[0]
+	dest := filepath.Join(dir, "out.mkv")
+	if err := set.ExtractTo("movie.mkv", dest); err == nil {
+		t.Fatal("ExtractTo() succeeded with a missing volume")
+	}
+	if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+		t.Error("partial file left behind")
+	}
+}
+
+func TestRAR5(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "stored.rar"), rar5Volume("Show.S01E01.mkv", []byte("episode"), 0))
+	writeFile(t, filepath.Join(dir, "packed.rar"), rar5Volume("Show.S01E02.mkv", []byte("xx"), 3))
+
+	sets := FindSets(dir, []string{"stored.rar", "packed.rar"})
+	packed, err := sets[0].List()
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(packed) != 1 || packed[0].Extractable {
+		t.Errorf("compressed entry = %+v", packed)
+	}
+	if err := sets[0].ExtractTo("Show.S01E02.mkv", filepath.Join(dir, "e2.mkv")); err == nil {
+		t.Error("compressed RAR entry extracted")
+	}
+
+	stored, err := sets[1].List()
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(stored) != 1 || stored[0].Name != "Show.S01E01.mkv" || stored[0].Size != 7 || !stored[0].Extractable {
+		t.Fatalf("stored entry = %+v", stored)
+	}
+	dest := filepath.Join(dir, "e1.mkv")
+	if err := sets[1].ExtractTo(stored[0].Name, dest); err != nil {
+		t.Fatalf("ExtractTo() error: %v", err)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "episode" {
+		t.Errorf("extracted %q", data)
+	}
+}
+
+func TestZIP(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("Movie (2010)/Movie.2010.mkv")
+	w.Write([]byte("deflated video"))
+	zw.Close()
+	writeFile(t, filepath.Join(dir, "movie.zip"), buf.Bytes())
+
+	set := FindSets(dir, []string{"movie.zip"})[0]
+	entries, err := set.List()
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name != "Movie (2010)/Movie.2010.mkv" || !entries[0].Extractable {
+		t.Fatalf("List() = %+v", entries)
+	}
+
+	dest := filepath.Join(dir, "movie.mkv")
+	if err := set.ExtractTo(entries[0].Name, dest); err != nil {
+		t.Fatalf("ExtractTo() error: %v", err)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "deflated video" {
+		t.Errorf("extracted %q", data)
+	}
+}
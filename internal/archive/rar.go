@@ -0,0 +1,294 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var (
+	rar4Signature = []byte("Rar!\x1a\x07\x00")
+	rar5Signature = []byte("Rar!\x1a\x07\x01\x00")
+)
+
+// rarFile is a file header read from a RAR volume; data is the packed data stored in this volume
+type rarFile struct {
+	name        string
+	size        int64 // Unpacked size of the whole file
+	dataOffset  int64
+	dataSize    int64
+	stored      bool
+	encrypted   bool
+	dir         bool
+	splitBefore bool // Data continues from the previous volume
+	splitAfter  bool // Data continues in the next volume
+}
+
+// readRARFiles reads the file headers of a RAR 4 or RAR 5 volume without decompressing anything
+func readRARFiles(volumePath string) ([]rarFile, error) {
+	f, err := os.Open(volumePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	signature := make([]byte, len(rar5Signature))
+	n, _ := io.ReadFull(f, signature)
+	switch {
+	case n >= len(rar5Signature) && bytes.Equal(signature, rar5Signature):
+		return readRAR5Files(f, int64(len(rar5Signature)))
+	case n >= len(rar4Signature) && bytes.Equal(signature[:len(rar4Signature)], rar4Signature):
+		return readRAR4Files(f, int64(len(rar4Signature)))
+	}
+	return nil, fmt.Errorf("%s is not a RAR archive", volumePath)
+}
+
+// readRAR4Files walks the blocks of a RAR 4 volume
+func readRAR4Files(f io.ReaderAt, offset int64) ([]rarFile, error) {
+	var files []rarFile
+	for {
+		header := make([]byte, 7)
+		if _, err := f.ReadAt(header, offset); err != nil {
+			// A volume without end-of-archive block simply stops
+			return files, nil
+		}
+		blockType := header[2]
+		flags := binary.LittleEndian.Uint16(header[3:])
+		size := int64(binary.LittleEndian.Uint16(header[5:]))
+		if size < 7 {
+			return nil, errors.New("corrupt RAR block header")
+		}
+		body := make([]byte, size-7)
+		if _, err := f.ReadAt(body, offset+7); err != nil {
+			return nil, fmt.Errorf("truncated RAR block header: %w", err)
+		}
+
+		var dataSize int64
+		switch blockType {
+		case 0x73: // Main header
+			if flags&0x0080 != 0 {
+				return nil, fmt.Errorf("RAR headers are encrypted: %w", ErrNotExtractable)
+			}
+		case 0x74: // File header
+			if len(body) < 25 {
+				return nil, errors.New("corrupt RAR file header")
+			}
+			packSize := int64(binary.LittleEndian.Uint32(body[0:]))
+			unpackedSize := int64(binary.LittleEndian.Uint32(body[4:]))
+			method := body[18]
+			nameSize := int(binary.LittleEndian.Uint16(body[19:]))
+			pos := 25
+			if flags&0x0100 != 0 && len(body) >= 33 {
+				packSize |= int64(binary.LittleEndian.Uint32(body[25:])) << 32
+				unpackedSize |= int64(binary.LittleEndian.Uint32(body[29:])) << 32
+				pos = 33
+			}
+			if pos+nameSize > len(body) {
+				return nil, errors.New("corrupt RAR file name")
+			}
+			name := body[pos : pos+nameSize]
+			// Unicode names follow the ASCII name after a zero byte
+			if i := bytes.IndexByte(name, 0); i >= 0 {
+				name = name[:i]
+			}
+
+			dataSize = packSize
+			files = append(files, rarFile{
+				name:        strings.ReplaceAll(string(name), `\`, "/"),
+				size:        unpackedSize,
+				dataOffset:  offset + size,
+				dataSize:    packSize,
+				stored:      method == 0x30,
+				encrypted:   flags&0x0004 != 0,
+				dir:         flags&0x00e0 == 0x00e0,
+				splitBefore: flags&0x0001 != 0,
+				splitAfter:  flags&0x0002 != 0,
+			})
+		case 0x7b: // End of archive
+			return files, nil
+		default:
+			if flags&0x8000 != 0 && len(body) >= 4 {
+				dataSize = int64(binary.LittleEndian.Uint32(body[0:]))
+			}
+		}
+
+		offset += size + dataSize
+	}
+}
+
+// rar5Reader decodes the variable-length integers of a RAR 5 header
+type rar5Reader struct {
+	buf []byte
+	err error
+}
+
+func (r *rar5Reader) vint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = errors.New("corrupt RAR 5 header")
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *rar5Reader) bytes(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > uint64(len(r.buf)) {
+		r.err = errors.New("corrupt RAR 5 header")
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+// readRAR5Files walks the headers of a RAR 5 volume
+func readRAR5Files(f io.ReaderAt, offset int64) ([]rarFile, error) {
+	var files []rarFile
+	for {
+		// Header CRC32, then the header size as a variable-length integer
+		prefix := make([]byte, 4+binary.MaxVarintLen64)
+		n, _ := f.ReadAt(prefix, offset)
+		if n <= 4 {
+			return files, nil
+		}
+		headerSize, sizeLen := binary.Uvarint(prefix[4:n])
+		if sizeLen <= 0 || headerSize == 0 || headerSize > 2*1024*1024 {
+			return nil, errors.New("corrupt RAR 5 header size")
+		}
+		headerStart := offset + 4 + int64(sizeLen)
+		header := make([]byte, headerSize)
+		if _, err := f.ReadAt(header, headerStart); err != nil {
+			return nil, fmt.Errorf("truncated RAR 5 header: %w", err)
+		}
+
+		r := &rar5Reader{buf: header}
+		headerType := r.vint()
+		headerFlags := r.vint()
+		var extraSize, dataSize uint64
+		if headerFlags&0x0001 != 0 {
+			extraSize = r.vint()
+		}
+		if headerFlags&0x0002 != 0 {
+			dataSize = r.vint()
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+		dataOffset := headerStart + int64(headerSize)
+
+		switch headerType {
+		case 2: // File header
+			fileFlags := r.vint()
+			unpackedSize := r.vint()
+			r.vint() // Attributes
+			if fileFlags&0x0002 != 0 {
+				r.bytes(4) // Modification time
+			}
+			if fileFlags&0x0004 != 0 {
+				r.bytes(4) // CRC32
+			}
+			compression := r.vint()
+			r.vint() // Host OS
+			name := r.bytes(r.vint())
+			if r.err != nil {
+				return nil, r.err
+			}
+
+			files = append(files, rarFile{
+				name:        string(name),
+				size:        int64(unpackedSize),
+				dataOffset:  dataOffset,
+				dataSize:    int64(dataSize),
+				stored:      (compression>>7)&0x7 == 0,
+				encrypted:   rar5Encrypted(header, extraSize),
+				dir:         fileFlags&0x0001 != 0,
+				splitBefore: headerFlags&0x0008 != 0,
+				splitAfter:  headerFlags&0x0010 != 0,
+			})
+		case 4: // Archive encryption header
+			return nil, fmt.Errorf("RAR headers are encrypted: %w", ErrNotExtractable)
+		case 5: // End of archive
+			return files, nil
+		}
+
+		offset = dataOffset + int64(dataSize)
+	}
+}
+
+// rar5Encrypted reports whether the extra area at the end of a file header has an encryption record
+func rar5Encrypted(header []byte, extraSize uint64) bool {
+	if extraSize == 0 || extraSize > uint64(len(header)) {
+		return false
+	}
+	r := &rar5Reader{buf: header[uint64(len(header))-extraSize:]}
+	for len(r.buf) > 0 && r.err == nil {
+		record := &rar5Reader{buf: r.bytes(r.vint())}
+		if record.vint() == 0x01 && record.err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// extractRAR copies a stored RAR entry to w, following its data across the volumes
+func extractRAR(volumes []string, entryName string, w io.Writer) error {
+	started := false
+	for _, volumePath := range volumes {
+		files, err := readRARFiles(volumePath)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			if file.name != entryName || file.splitBefore != started {
+				continue
+			}
+			if !file.stored || file.encrypted {
+				return ErrNotExtractable
+			}
+
+			if err := copyRange(volumePath, file.dataOffset, file.dataSize, w); err != nil {
+				return err
+			}
+			if !file.splitAfter {
+				return nil
+			}
+			started = true
+			break
+		}
+	}
+
+	if started {
+		return fmt.Errorf("missing volume after %s", volumes[len(volumes)-1])
+	}
+	return fmt.Errorf("entry %s not found", entryName)
+}
+
+// copyRange copies size bytes at offset of a file to w
+func copyRange(filePath string, offset, size int64, w io.Writer) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n, err := io.Copy(w, io.NewSectionReader(f, offset, size))
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("truncated volume %s", filePath)
+	}
+	return nil
+}
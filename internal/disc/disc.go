@@ -0,0 +1,283 @@
+package disc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// sectorSize is the logical sector size of ISO 9660 and UDF disc images
+const sectorSize = 2048
+
+// Kind is the type of disc a structure or image holds
+type Kind string
+
+const (
+	// KindBluray is a BDMV folder structure
+	KindBluray Kind = "bluray"
+	// KindDVD is a VIDEO_TS folder structure
+	KindDVD Kind = "dvd"
+	// KindImage is an ISO 9660 or UDF disc image
+	KindImage Kind = "image"
+)
+
+var (
+	// discSuffixPattern matches the disc number and format markers at the end of a volume label or disc title
+	discSuffixPattern = regexp.MustCompile(`(?i)(?:^|[\s_-]+)(?:blu[\s-]?ray(?:\s*disc)?|dis[ck]\s*\d+|d\d)$`)
+	// trademarkReplacer removes the trademark signs disc titles carry
+	trademarkReplacer = strings.NewReplacer("™", "", "®", "", "©", "")
+)
+
+// Info is the metadata read from a disc structure or disc image
+type Info struct {
+	Kind     Kind
+	Title    string // Disc title from the disc metadata (Blu-ray bdmt_*.xml), empty if none
+	Label    string // Volume label of a disc image, empty if none
+	Provider string // DVD provider identifier, often the studio rather than the title
+	Titles   int    // Number of playable titles, 0 if unknown
+}
+
+// Query returns the best search query the disc metadata offers: the disc title, else the volume
+// label turned into words ("THE_MATRIX_DISC_1" gives "The Matrix"); the DVD provider is never used
+// as it usually names the studio
+func (i Info) Query() string {
+	if title := cleanDiscName(i.Title); title != "" {
+		return title
+	}
+
+	label := cleanDiscName(strings.ReplaceAll(i.Label, "_", " "))
+	if label == "" {
+		return ""
+	}
+	if strings.ToUpper(label) == label {
+		label = titleCase(label)
+	}
+	return label
+}
+
+// cleanDiscName removes trademark signs, disc numbers and format markers from a disc name
+func cleanDiscName(name string) string {
+	name = strings.TrimSpace(trademarkReplacer.Replace(name))
+	for {
+		trimmed := strings.TrimSpace(discSuffixPattern.ReplaceAllString(name, ""))
+		if trimmed == name {
+			return strings.Join(strings.Fields(name), " ")
+		}
+		name = trimmed
+	}
+}
+
+// titleCase capitalises the first letter of every word of an upper-case label
+func titleCase(s string) string {
+	words := strings.Fields(strings.ToLower(s))
+	for i, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
+
+// ReadBluray reads the disc title of a Blu-ray folder (the folder holding BDMV) from
+// BDMV/META/DL/bdmt_*.xml, preferring English, and the number of titles from BDMV/index.bdmv
+func ReadBluray(dir string) (Info, error) {
+	info := Info{Kind: KindBluray}
+	bdmvDir := filepath.Join(dir, "BDMV")
+
+	titles, err := readIndexBDMV(filepath.Join(bdmvDir, "index.bdmv"))
+	if err != nil {
+		return info, err
+	}
+	info.Titles = titles
+
+	matches, _ := filepath.Glob(filepath.Join(bdmvDir, "META", "DL", "bdmt_*.xml"))
+	sort.Slice(matches, func(i, j int) bool {
+		// English first, then alphabetical
+		iEng := strings.HasSuffix(strings.ToLower(matches[i]), "bdmt_eng.xml")
+		jEng := strings.HasSuffix(strings.ToLower(matches[j]), "bdmt_eng.xml")
+		if iEng != jEng {
+			return iEng
+		}
+		return matches[i] < matches[j]
+	})
+	for _, path := range matches {
+		if title, err := readBDMT(path); err == nil && title != "" {
+			info.Title = title
+			break
+		}
+	}
+
+	return info, nil
+}
+
+// readIndexBDMV validates a Blu-ray index.bdmv and returns its number of titles
+func readIndexBDMV(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) < 40 || string(data[0:4]) != "INDX" {
+		return 0, fmt.Errorf("%s is not a Blu-ray index", path)
+	}
+
+	// Indexes: length (4), first playback (12), top menu (12), number of titles (2)
+	indexesStart := int(binary.BigEndian.Uint32(data[8:12]))
+	countOffset := indexesStart + 4 + 12 + 12
+	if countOffset+2 > len(data) {
+		return 0, fmt.Errorf("truncated Blu-ray index %s", path)
+	}
+	return int(binary.BigEndian.Uint16(data[countOffset:])), nil
+}
+
+// bdmtDocument is the part of a Blu-ray disc library metadata file holding the disc title
+type bdmtDocument struct {
+	Name string `xml:"discinfo>title>name"`
+}
+
+// readBDMT reads the disc title of a bdmt_*.xml disc library metadata file
+func readBDMT(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var doc bdmtDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return strings.TrimSpace(doc.Name), nil
+}
+
+// ReadDVD reads VIDEO_TS/VIDEO_TS.IFO of a DVD folder (the folder holding VIDEO_TS): the number
+// of title sets and the provider identifier; DVDs carry no title outside their volume label
+func ReadDVD(dir string) (Info, error) {
+	info := Info{Kind: KindDVD}
+	path := filepath.Join(dir, "VIDEO_TS", "VIDEO_TS.IFO")
+
+	f, err := os.Open(path)
+	if err != nil {
+		return info, err
+	}
+	defer f.Close()
+
+	header := make([]byte, 0x60)
+	if _, err := io.ReadFull(f, header); err != nil || string(header[0:12]) != "DVDVIDEO-VMG" {
+		return info, fmt.Errorf("%s is not a DVD video manager", path)
+	}
+
+	info.Titles = int(binary.BigEndian.Uint16(header[0x3e:]))
+	info.Provider = strings.TrimSpace(string(bytes.TrimRight(header[0x40:0x60], "\x00 ")))
+	return info, nil
+}
+
+// ReadImage reads the volume label of an ISO disc image, preferring the UDF logical volume
+// identifier (Blu-ray and most DVD images) over the ISO 9660 volume identifier
+func ReadImage(path string) (Info, error) {
+	info := Info{Kind: KindImage}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return info, err
+	}
+	defer f.Close()
+
+	udfLabel, _ := readUDFLabel(f)
+	isoLabel, _ := readISO9660Label(f)
+	switch {
+	case udfLabel != "":
+		info.Label = udfLabel
+	case isoLabel != "":
+		info.Label = isoLabel
+	default:
+		return info, fmt.Errorf("no volume label found in %s", path)
+	}
+	return info, nil
+}
+
+// readISO9660Label returns the volume identifier of the ISO 9660 primary volume descriptor
+func readISO9660Label(r io.ReaderAt) (string, error) {
+	descriptor := make([]byte, sectorSize)
+	for sector := int64(16); sector < 32; sector++ {
+		if _, err := r.ReadAt(descriptor, sector*sectorSize); err != nil {
+			return "", err
+		}
+		if string(descriptor[1:6]) != "CD001" {
+			return "", errors.New("no ISO 9660 volume descriptor")
+		}
+		switch descriptor[0] {
+		case 1: // Primary volume descriptor
+			return strings.TrimSpace(string(descriptor[40:72])), nil
+		case 255: // Terminator
+			return "", errors.New("no ISO 9660 primary volume descriptor")
+		}
+	}
+	return "", errors.New("no ISO 9660 primary volume descriptor")
+}
+
+// readUDFLabel returns the UDF logical volume identifier, or the primary volume identifier,
+// following the anchor volume descriptor pointer at sector 256
+func readUDFLabel(r io.ReaderAt) (string, error) {
+	anchor := make([]byte, sectorSize)
+	if _, err := r.ReadAt(anchor, 256*sectorSize); err != nil {
+		return "", err
+	}
+	if binary.LittleEndian.Uint16(anchor[0:]) != 2 {
+		return "", errors.New("no UDF anchor volume descriptor")
+	}
+	length := binary.LittleEndian.Uint32(anchor[16:])
+	location := binary.LittleEndian.Uint32(anchor[20:])
+
+	var primaryLabel string
+	descriptor := make([]byte, sectorSize)
+	for i := uint32(0); i < length/sectorSize && i < 64; i++ {
+		if _, err := r.ReadAt(descriptor, int64(location+i)*sectorSize); err != nil {
+			return "", err
+		}
+		switch binary.LittleEndian.Uint16(descriptor[0:]) {
+		case 1: // Primary volume descriptor
+			primaryLabel = decodeDString(descriptor[24:56])
+		case 6: // Logical volume descriptor
+			if label := decodeDString(descriptor[84:212]); label != "" {
+				return label, nil
+			}
+		case 8: // Terminating descriptor
+			return primaryLabel, nil
+		}
+	}
+	return primaryLabel, nil
+}
+
+// decodeDString decodes a UDF dstring: a compression ID (8 for bytes, 16 for UTF-16BE), the
+// characters, and the used length in the last byte
+func decodeDString(field []byte) string {
+	used := int(field[len(field)-1])
+	if used < 2 || used > len(field)-1 {
+		return ""
+	}
+	data := field[1:used]
+
+	switch field[0] {
+	case 8:
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return strings.TrimSpace(string(runes))
+	case 16:
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(data[2*i:])
+		}
+		return strings.TrimSpace(string(utf16.Decode(units)))
+	}
+	return ""
+}
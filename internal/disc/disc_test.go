@@ -0,0 +1,126 @@
+package disc
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func mkdirWrite(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// indexBDMV builds a minimal index.bdmv with the given number of titles
+func indexBDMV(titles int) []byte {
+	data := make([]byte, 120)
+	copy(data, "INDX0200")
+	binary.BigEndian.PutUint32(data[8:], 78)
+	binary.BigEndian.PutUint16(data[78+28:], uint16(titles))
+	return data
+}
+
+func TestReadBluray(t *testing.T) {
+	dir := t.TempDir()
+	mkdirWrite(t, filepath.Join(dir, "BDMV", "index.bdmv"), indexBDMV(3))
+	mkdirWrite(t, filepath.Join(dir, "BDMV", "META", "DL", "bdmt_fra.xml"), []byte(`<?xml version="1.0"?>
+<disclib xmlns="urn:BDA:bdmv;disclib" xmlns:di="urn:BDA:bdmv;discinfo">
+  <di:discinfo><di:title><di:name>Matrix</di:name></di:title></di:discinfo>
+</disclib>`))
+	mkdirWrite(t, filepath.Join(dir, "BDMV", "META", "DL", "bdmt_eng.xml"), []byte(`<?xml version="1.0"?>
+<disclib xmlns="urn:BDA:bdmv;disclib" xmlns:di="urn:BDA:bdmv;discinfo">
+  <di:discinfo><di:title><di:name>The Matrix™ - Blu-ray</di:name></di:title></di:discinfo>
+</disclib>`))
+
+	info, err := ReadBluray(dir)
+	if err != nil {
+		t.Fatalf("ReadBluray() error: %v", err)
+	}
+	if info.Titles != 3 || info.Title != "The Matrix™ - Blu-ray" {
+		t.Errorf("ReadBluray() = %+v", info)
+	}
+	if query := info.Query(); query != "The Matrix" {
+		t.Errorf("Query() = %q, want %q", query, "The Matrix")
+	}
+}
+
+func TestReadDVD(t *testing.T) {
+	dir := t.TempDir()
+	ifo := make([]byte, 0x100)
+	copy(ifo, "DVDVIDEO-VMG")
+	binary.BigEndian.PutUint16(ifo[0x3e:], 4)
+	copy(ifo[0x40:], "WARNER HOME VIDEO")
+	mkdirWrite(t, filepath.Join(dir, "VIDEO_TS", "VIDEO_TS.IFO"), ifo)
+
+	info, err := ReadDVD(dir)
+	if err != nil {
+		t.Fatalf("ReadDVD() error: %v", err)
+	}
+	if info.Titles != 4 || info.Provider != "WARNER HOME VIDEO" {
+		t.Errorf("ReadDVD() = %+v", info)
+	}
+	if query := info.Query(); query != "" {
+		t.Errorf("Query() = %q, want no query from the provider", query)
+	}
+}
+
+func TestReadImageISO9660(t *testing.T) {
+	image := make([]byte, 18*sectorSize)
+	pvd := image[16*sectorSize:]
+	pvd[0] = 1
+	copy(pvd[1:], "CD001")
+	copy(pvd[40:72], "THE_MATRIX_DISC_1               ")
+	terminator := image[17*sectorSize:]
+	terminator[0] = 255
+	copy(terminator[1:], "CD001")
+
+	path := filepath.Join(t.TempDir(), "movie.iso")
+	mkdirWrite(t, path, image)
+
+	info, err := ReadImage(path)
+	if err != nil {
+		t.Fatalf("ReadImage() error: %v", err)
+	}
+	if info.Label != "THE_MATRIX_DISC_1" || info.Query() != "The Matrix" {
+		t.Errorf("ReadImage() = %+v, query %q", info, info.Query())
+	}
+}
+
+func TestReadImageUDF(t *testing.T) {
+	image := make([]byte, 260*sectorSize)
+	anchor := image[256*sectorSize:]
+	binary.LittleEndian.PutUint16(anchor[0:], 2)
+	binary.LittleEndian.PutUint32(anchor[16:], 2*sectorSize)
+	binary.LittleEndian.PutUint32(anchor[20:], 257)
+
+	// Logical volume descriptor with a UTF-16 identifier
+	lvd := image[257*sectorSize:]
+	binary.LittleEndian.PutUint16(lvd[0:], 6)
+	field := lvd[84:212]
+	field[0] = 16
+	name := "Inception"
+	for i, c := range name {
+		binary.BigEndian.PutUint16(field[1+2*i:], uint16(c))
+	}
+	field[127] = byte(1 + 2*len(name))
+
+	terminator := image[258*sectorSize:]
+	binary.LittleEndian.PutUint16(terminator[0:], 8)
+
+	path := filepath.Join(t.TempDir(), "movie.iso")
+	mkdirWrite(t, path, image)
+
+	info, err := ReadImage(path)
+	if err != nil {
+		t.Fatalf("ReadImage() error: %v", err)
+	}
+	if info.Label != "Inception" || info.Query() != "Inception" {
+		t.Errorf("ReadImage() = %+v", info)
+	}
+}
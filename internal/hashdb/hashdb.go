@@ -0,0 +1,169 @@
+package hashdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"kodi-renamer/internal/api"
+)
+
+// Entry records the identification of a file we renamed, keyed by its movie hash
+type Entry struct {
+	Hash      string    `json:"hash"`
+	Size      int64     `json:"size"`
+	ID        string    `json:"id"`
+	Source    string    `json:"source"`
+	Type      string    `json:"type"`
+	Title     string    `json:"title"`
+	Year      string    `json:"year,omitempty"`
+	Season    int       `json:"season,omitempty"`
+	Episode   int       `json:"episode,omitempty"`
+	Path      string    `json:"path"`
+	RenamedAt time.Time `json:"renamed_at"`
+	// IDs maps every site known to describe the media ("tvdb", "tmdb", "imdb") to its ID there
+	IDs map[string]string `json:"ids,omitempty"`
+}
+
+// Store is a local hash to media ID database, persisted as a JSON file and populated
+// from our own renames so that re-downloaded files can be identified automatically
+type Store struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]Entry
+	dirty   bool
+}
+
+// DefaultPath returns the default location of the hash database in the user configuration directory
+func DefaultPath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "kodi-renamer", "hashes.json")
+}
+
+// Open loads the hash database at path, starting empty if the file does not exist yet
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path:    path,
+		entries: make(map[string]Entry),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hash database: %w", err)
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode hash database: %w", err)
+	}
+	for _, entry := range entries {
+		s.entries[key(entry.Hash, entry.Size)] = entry
+	}
+
+	return s, nil
+}
+
+// key builds the lookup key of a file; the size is part of the key to reduce hash collisions
+func key(hash string, size int64) string {
+	return fmt.Sprintf("%s:%d", hash, size)
+}
+
+// Name returns the identifier name used in log messages
+func (s *Store) Name() string {
+	return "Local hash database"
+}
+
+// IdentifyByHash returns the media previously recorded for the given hash, or nil if unknown
+func (s *Store) IdentifyByHash(hash string, size int64) (*api.HashMatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key(hash, size)]
+	if !ok {
+		return nil, nil
+	}
+
+	return &api.HashMatch{
+		ID:      entry.ID,
+		Source:  entry.Source,
+		Type:    entry.Type,
+		Title:   entry.Title,
+		Year:    entry.Year,
+		Season:  entry.Season,
+		Episode: entry.Episode,
+	}, nil
+}
+
+// Record stores the identification of a renamed file; empty hashes are ignored
+func (s *Store) Record(entry Entry) {
+	if entry.Hash == "" {
+		return
+	}
+	if entry.RenamedAt.IsZero() {
+		entry.RenamedAt = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key(entry.Hash, entry.Size)] = entry
+	s.dirty = true
+}
+
+// Len returns the number of recorded files
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Save writes the database back to disk if it changed, atomically replacing the previous file
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+
+	entries := make([]Entry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Hash != entries[j].Hash {
+			return entries[i].Hash < entries[j].Hash
+		}
+		return entries[i].Size < entries[j].Size
+	})
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode hash database: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create hash database directory: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write hash database: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to replace hash database: %w", err)
+	}
+
+	s.dirty = false
+	return nil
+}
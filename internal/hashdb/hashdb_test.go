@@ -0,0 +1,83 @@
+package hashdb
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "hashes.json")
+
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if store.Len() != 0 {
+		t.Fatalf("new store has %d entries", store.Len())
+	}
+
+	store.Record(Entry{Hash: "8e245d9679d31e12", Size: 12909756, ID: "603", Source: "tmdb", Type: "movie", Title: "The Matrix", Year: "1999"})
+	store.Record(Entry{Hash: "", Size: 1, ID: "ignored"})
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if reopened.Len() != 1 {
+		t.Fatalf("reopened store has %d entries, want 1", reopened.Len())
+	}
+
+	match, err := reopened.IdentifyByHash("8e245d9679d31e12", 12909756)
+	if err != nil || match == nil {
+		t.Fatalf("IdentifyByHash() = %v, %v", match, err)
+	}
+	if match.ID != "603" || match.Source != "tmdb" || match.Type != "movie" || match.Year != "1999" {
+		t.Errorf("IdentifyByHash() = %+v", match)
+	}
+
+	// Same hash with another size is a different file
+	if match, _ := reopened.IdentifyByHash("8e245d9679d31e12", 42); match != nil {
+		t.Errorf("IdentifyByHash() with wrong size = %+v, want nil", match)
+	}
+}
+
+func TestStoreSaveIsStable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hashes.json")
+	renamedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Hash: "8e245d9679d31e12", Size: 12909756, ID: "603", Source: "tmdb", Type: "movie", RenamedAt: renamedAt},
+		{Hash: "8e245d9679d31e12", Size: 42, ID: "604", Source: "tmdb", Type: "movie", RenamedAt: renamedAt},
+		{Hash: "0123456789abcdef", Size: 7, ID: "81189", Source: "tvdb", Type: "series", RenamedAt: renamedAt},
+		{Hash: "fedcba9876543210", Size: 9, ID: "550", Source: "tmdb", Type: "movie", RenamedAt: renamedAt},
+	}
+
+	var saved [][]byte
+	for range 2 {
+		store, err := Open(path)
+		if err != nil {
+			t.Fatalf("Open() error: %v", err)
+		}
+		// Recording the same entries again marks the store dirty without changing it
+		for _, entry := range entries {
+			store.Record(entry)
+		}
+		if err := store.Save(); err != nil {
+			t.Fatalf("Save() error: %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved = append(saved, data)
+	}
+
+	if !bytes.Equal(saved[0], saved[1]) {
+		t.Errorf("saving the same entries twice gave different files:\n%s\n%s", saved[0], saved[1])
+	}
+}
@@ -0,0 +1,263 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTimeout bounds a single attempt of a request
+	DefaultTimeout = 30 * time.Second
+	// DefaultMaxRetries is how many times a throttled or failed request is retried
+	DefaultMaxRetries = 3
+	// DefaultBaseDelay is the backoff before the first retry, doubled on each following one
+	DefaultBaseDelay = 500 * time.Millisecond
+	// DefaultMaxDelay caps the backoff and the Retry-After delays we are willing to wait
+	DefaultMaxDelay = 30 * time.Second
+)
+
+// redacted replaces secrets in logged URLs and returned errors
+const redacted = "REDACTED"
+
+// sensitiveParams are query parameters whose values are never logged
+var sensitiveParams = []string{"api_key", "apikey", "pin", "token"}
+
+// Client sends the requests of one provider through a shared rate limiter, retrying throttled
+// (429) and failed (5xx, network) requests with exponential backoff
+type Client struct {
+	provider   string
+	httpClient *http.Client
+	limiter    *Limiter
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	logger     *log.Logger
+	// secretsMu guards secrets, which can be added while requests are being redacted
+	secretsMu sync.RWMutex
+	secrets   []string
+}
+
+// New creates a client for a provider, limited to rate requests per second with bursts of burst requests
+func New(provider string, rate float64, burst int) *Client {
+	return &Client{
+		provider: provider,
+		httpClient: &http.Client{
+			Timeout: DefaultTimeout,
+		},
+		limiter:    NewLimiter(rate, burst),
+		maxRetries: DefaultMaxRetries,
+		baseDelay:  DefaultBaseDelay,
+		maxDelay:   DefaultMaxDelay,
+	}
+}
+
+// SetHTTPClient replaces the underlying HTTP client, for example to send requests to a local fake server
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	c.httpClient = httpClient
+}
+
+// SetRetries sets how many times a request is retried and the bounds of the backoff between attempts
+func (c *Client) SetRetries(maxRetries int, baseDelay, maxDelay time.Duration) {
+	c.maxRetries = maxRetries
+	c.baseDelay = baseDelay
+	c.maxDelay = maxDelay
+}
+
+// SetLogger enables request/response debug logging (nil to disable)
+func (c *Client) SetLogger(logger *log.Logger) {
+	c.logger = logger
+}
+
+// AddSecret registers a value, such as an API key, that must never appear in logs or errors
+func (c *Client) AddSecret(secret string) {
+	if secret == "" {
+		return
+	}
+	c.secretsMu.Lock()
+	defer c.secretsMu.Unlock()
+	c.secrets = append(c.secrets, secret)
+}
+
+// Do sends the request, waiting for the rate limiter and retrying throttled and failed attempts until
+// the request's context is done. The response of the last attempt is returned whatever its status.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		attemptReq := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewind request body: %w", err)
+			}
+			attemptReq = req.Clone(ctx)
+			attemptReq.Body = body
+		}
+
+		start := time.Now()
+		resp, err := c.httpClient.Do(attemptReq)
+		c.logAttempt(req, resp, err, time.Since(start))
+
+		if err != nil {
+			err = c.redactError(err)
+			if ctx.Err() != nil || attempt >= c.maxRetries || !canRetry(req) {
+				return nil, err
+			}
+			if err := c.sleep(req, attempt, c.backoff(attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if !retryableStatus(resp.StatusCode) || attempt >= c.maxRetries || !canRetry(req) {
+			return resp, nil
+		}
+
+		delay, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if !ok {
+			delay = c.backoff(attempt)
+		}
+		if delay > c.maxDelay {
+			delay = c.maxDelay
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+		resp.Body.Close()
+
+		if err := c.sleep(req, attempt, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleep waits before the next attempt, returning early with the context's error when it is done
+func (c *Client) sleep(req *http.Request, attempt int, delay time.Duration) error {
+	if c.logger != nil {
+		c.logger.Printf("[%s] retrying %s %s in %s (attempt %d of %d)",
+			c.provider, req.Method, c.redactURL(req.URL), delay.Round(time.Millisecond), attempt+2, c.maxRetries+1)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-req.Context().Done():
+		return req.Context().Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// backoff returns the jittered exponential delay before retry number attempt+1
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.baseDelay << attempt
+	if delay <= 0 || delay > c.maxDelay {
+		delay = c.maxDelay
+	}
+	if half := int64(delay / 2); half > 0 {
+		delay = time.Duration(half + rand.Int63n(half+1))
+	}
+	return delay
+}
+
+// logAttempt writes one debug line per attempt
+func (c *Client) logAttempt(req *http.Request, resp *http.Response, err error, elapsed time.Duration) {
+	if c.logger == nil {
+		return
+	}
+	target := c.redactURL(req.URL)
+	elapsed = elapsed.Round(time.Millisecond)
+	if err != nil {
+		c.logger.Printf("[%s] %s %s -> error: %v (%s)", c.provider, req.Method, target, c.redactError(err), elapsed)
+		return
+	}
+	c.logger.Printf("[%s] %s %s -> %s (%s)", c.provider, req.Method, target, resp.Status, elapsed)
+}
+
+// redactURL renders a URL with sensitive query parameters and registered secrets removed
+func (c *Client) redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	clean := *u
+	query := clean.Query()
+	for _, name := range sensitiveParams {
+		if query.Has(name) {
+			query.Set(name, redacted)
+		}
+	}
+	clean.RawQuery = query.Encode()
+	return c.redact(clean.String())
+}
+
+// redact replaces every registered secret in s
+func (c *Client) redact(s string) string {
+	c.secretsMu.RLock()
+	defer c.secretsMu.RUnlock()
+	for _, secret := range c.secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	return s
+}
+
+// redactError removes secrets from the URL that net/http puts in its errors
+func (c *Client) redactError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+			urlErr.URL = c.redactURL(u)
+		} else {
+			urlErr.URL = c.redact(urlErr.URL)
+		}
+	}
+	return err
+}
+
+// canRetry reports whether the request can be sent again, which needs a rewindable body
+func canRetry(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// retryableStatus reports whether a status means the same request may succeed later
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusRequestTimeout,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := date.Sub(now)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
@@ -0,0 +1,244 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient returns an unlimited client with fast retries
+func newTestClient() *Client {
+	c := New("test", 0, 1)
+	c.SetRetries(3, time.Millisecond, 10*time.Millisecond)
+	return c
+}
+
+func TestDoRetriesThrottledAndFailedRequests(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			io.WriteString(w, "ok")
+		}
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	resp, err := newTestClient().Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK || string(body) != "ok" {
+		t.Errorf("Do() = %d %q, want 200 \"ok\"", resp.StatusCode, body)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("server called %d times, want 3", calls.Load())
+	}
+}
+
+func TestDoReturnsLastResponseWhenRetriesAreExhausted(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	resp, err := newTestClient().Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", resp.StatusCode)
+	}
+	if calls.Load() != 4 {
+		t.Errorf("server called %d times, want 4 (1 + 3 retries)", calls.Load())
+	}
+}
+
+func TestDoDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	resp, err := newTestClient().Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if calls.Load() != 1 {
+		t.Errorf("server called %d times, want 1", calls.Load())
+	}
+}
+
+func TestDoResendsRequestBody(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("POST", server.URL, bytes.NewReader([]byte(`{"apikey":"k"}`)))
+	resp, err := newTestClient().Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if len(bodies) != 2 || bodies[0] != bodies[1] || bodies[1] != `{"apikey":"k"}` {
+		t.Errorf("bodies = %q, want the same body twice", bodies)
+	}
+}
+
+func TestDoStopsWhenContextIsCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	c := New("test", 0, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	start := time.Now()
+	_, err := c.Do(req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Do() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Do() returned after %s, want it to stop at the deadline", elapsed)
+	}
+}
+
+func TestDoRedactsSecrets(t *testing.T) {
+	var logs bytes.Buffer
+	c := newTestClient()
+	c.SetRetries(0, time.Millisecond, time.Millisecond)
+	c.SetLogger(log.New(&logs, "", 0))
+	c.AddSecret("s3cret")
+
+	// Nothing listens on this port, so the request fails with a *url.Error carrying the URL
+	req, _ := http.NewRequest("GET", "http://127.0.0.1:1/search/s3cret?api_key=abc123&query=matrix", nil)
+	_, err := c.Do(req)
+	if err == nil {
+		t.Fatal("Do() error = nil, want a connection error")
+	}
+
+	for _, text := range []string{err.Error(), logs.String()} {
+		if strings.Contains(text, "abc123") || strings.Contains(text, "s3cret") {
+			t.Errorf("secret leaked in %q", text)
+		}
+		if !strings.Contains(text, "query=matrix") {
+			t.Errorf("%q lost the non-secret query", text)
+		}
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	c := New("test", 0, 1)
+	c.AddSecret("pin-1234")
+	u, _ := url.Parse("https://api.example.com/3/movie/603?api_key=abc&language=en&note=pin-1234")
+
+	got := c.redactURL(u)
+	want := "https://api.example.com/3/movie/603?api_key=REDACTED&language=en&note=REDACTED"
+	if got != want {
+		t.Errorf("redactURL() = %q, want %q", got, want)
+	}
+}
+
+func TestAddSecretWhileRedacting(t *testing.T) {
+	c := New("test", 0, 1)
+	u, _ := url.Parse("https://api.example.com/3/search?query=pin-7")
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.AddSecret(fmt.Sprintf("pin-%d", i))
+		}()
+		go func() {
+			defer wg.Done()
+			c.redactURL(u)
+		}()
+	}
+	wg.Wait()
+
+	if got := c.redactURL(u); strings.Contains(got, "pin-7") {
+		t.Errorf("redactURL() = %q, want the secret added concurrently redacted", got)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{" 0 ", 0, true},
+		{"-1", 0, false},
+		{"Sun, 18 Oct 2026 12:00:05 GMT", 5 * time.Second, true},
+		{"Sun, 18 Oct 2026 11:59:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %s, %v; want %s, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBackoffStaysWithinBounds(t *testing.T) {
+	c := New("test", 0, 1)
+	c.SetRetries(5, 100*time.Millisecond, time.Second)
+
+	for attempt := 0; attempt < 8; attempt++ {
+		full := 100 * time.Millisecond << attempt
+		if full > time.Second {
+			full = time.Second
+		}
+		for i := 0; i < 20; i++ {
+			if d := c.backoff(attempt); d < full/2 || d > full {
+				t.Fatalf("backoff(%d) = %s, want between %s and %s", attempt, d, full/2, full)
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiterReserve(t *testing.T) {
+	l := NewLimiter(2, 2)
+	now := l.last
+
+	if d := l.reserve(now); d != 0 {
+		t.Fatalf("first token delay = %s, want 0", d)
+	}
+	if d := l.reserve(now); d != 0 {
+		t.Fatalf("second token delay = %s, want 0 (burst of 2)", d)
+	}
+	if d := l.reserve(now); d != 500*time.Millisecond {
+		t.Fatalf("third token delay = %s, want 500ms at 2 tokens/s", d)
+	}
+
+	// Half a second later one token has been refilled
+	if d := l.reserve(now.Add(500 * time.Millisecond)); d != 0 {
+		t.Errorf("refilled token delay = %s, want 0", d)
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	l := NewLimiter(0, 1)
+	for i := 0; i < 100; i++ {
+		if d := l.reserve(time.Now()); d != 0 {
+			t.Fatalf("reserve() = %s with limiting disabled, want 0", d)
+		}
+	}
+}
+
+func TestLimiterWaitHonoursContext(t *testing.T) {
+	l := NewLimiter(0.1, 1)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, want context.DeadlineExceeded", err)
+	}
+}
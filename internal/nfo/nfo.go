@@ -0,0 +1,137 @@
+package nfo
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"kodi-renamer/internal/api"
+)
+
+// Movie is the Kodi movie NFO document, limited to the fields we know from the providers
+type Movie struct {
+	XMLName       xml.Name   `xml:"movie"`
+	Title         string     `xml:"title"`
+	OriginalTitle string     `xml:"originaltitle,omitempty"`
+	Year          string     `xml:"year,omitempty"`
+	Premiered     string     `xml:"premiered,omitempty"`
+	Tagline       string     `xml:"tagline,omitempty"`
+	Plot          string     `xml:"plot,omitempty"`
+	Runtime       int        `xml:"runtime,omitempty"`
+	MPAA          string     `xml:"mpaa,omitempty"`
+	Genres        []string   `xml:"genre"`
+	Countries     []string   `xml:"country"`
+	Directors     []string   `xml:"director"`
+	Credits       []string   `xml:"credits"`
+	Tags          []string   `xml:"tag"`
+	Actors        []Actor    `xml:"actor"`
+	UniqueIDs     []UniqueID `xml:"uniqueid"`
+	Set           *Set       `xml:"set"`
+}
+
+// Actor is a cast member and the character they play
+type Actor struct {
+	Name  string `xml:"name"`
+	Role  string `xml:"role,omitempty"`
+	Order int    `xml:"order"`
+}
+
+// UniqueID is the ID of the movie on one site; Kodi scrapes the default one
+type UniqueID struct {
+	Type    string `xml:"type,attr"`
+	Default bool   `xml:"default,attr,omitempty"`
+	ID      string `xml:",chardata"`
+}
+
+// Set is the collection the movie belongs to, grouping a franchise in the Kodi library
+type Set struct {
+	Name     string `xml:"name"`
+	Overview string `xml:"overview,omitempty"`
+}
+
+// NewMovie builds the NFO of a movie from its details and, when fetched, its extended metadata, with
+// the certification and release date of a country (ISO 3166-1 code); the ID of the provider the
+// details came from is the default one
+func NewMovie(details *api.UnifiedMovieProposition, country string) *Movie {
+	movie := &Movie{
+		Title:   details.Title,
+		Year:    details.Year,
+		Plot:    details.Overview,
+		Runtime: details.Runtime,
+		Genres:  details.Genres,
+	}
+	if details.CollectionName != "" {
+		movie.Set = &Set{Name: details.CollectionName}
+	}
+	if meta := details.Metadata; meta != nil {
+		if meta.OriginalTitle != details.Title {
+			movie.OriginalTitle = meta.OriginalTitle
+		}
+		movie.Premiered = meta.ReleaseDateIn(country)
+		movie.Tagline = meta.Tagline
+		movie.MPAA = meta.Certification(country)
+		movie.Countries = meta.Countries
+		movie.Directors = meta.Directors
+		movie.Credits = meta.Writers
+		movie.Tags = meta.Keywords
+		for _, cast := range meta.Cast {
+			movie.Actors = append(movie.Actors, Actor{Name: cast.Name, Role: cast.Role, Order: cast.Order})
+		}
+	}
+
+	ids := make(map[string]string, len(details.UniqueIDs)+1)
+	for source, id := range details.UniqueIDs {
+		ids[source] = id
+	}
+	if details.ID != "" {
+		ids[details.Source] = details.ID
+	}
+	sources := make([]string, 0, len(ids))
+	for source := range ids {
+		sources = append(sources, source)
+	}
+	slices.Sort(sources)
+	for _, source := range sources {
+		movie.UniqueIDs = append(movie.UniqueIDs, UniqueID{
+			Type:    source,
+			Default: source == details.Source,
+			ID:      ids[source],
+		})
+	}
+	return movie
+}
+
+// PathFor returns the NFO path Kodi reads for a video file: the same name with the .nfo extension
+func PathFor(videoPath string) string {
+	return strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + ".nfo"
+}
+
+// WriteMovie writes the NFO of a movie to path. An existing NFO is left untouched, as it may hold
+// metadata edited by hand; written reports whether the file was created.
+func WriteMovie(path string, movie *Movie) (written bool, err error) {
+	if _, err := os.Lstat(path); err == nil {
+		return false, nil
+	} else if !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to check NFO %s: %w", path, err)
+	}
+
+	data, err := xml.MarshalIndent(movie, "", "  ")
+	if err != nil {
+		return false, fmt.Errorf("failed to encode NFO: %w", err)
+	}
+	data = append([]byte(xml.Header), data...)
+	data = append(data, '\n')
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return false, fmt.Errorf("failed to write NFO: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return false, fmt.Errorf("failed to replace NFO: %w", err)
+	}
+	return true, nil
+}
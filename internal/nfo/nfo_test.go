@@ -0,0 +1,99 @@
+package nfo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"kodi-renamer/internal/api"
+)
+
+func TestWriteMovie(t *testing.T) {
+	path := PathFor(filepath.Join(t.TempDir(), "The Matrix (1999).mkv"))
+	if filepath.Base(path) != "The Matrix (1999).nfo" {
+		t.Fatalf("PathFor() = %s", path)
+	}
+
+	movie := NewMovie(&api.UnifiedMovieProposition{
+		ID:             "169",
+		Source:         "tvdb",
+		Title:          "The Matrix",
+		Year:           "1999",
+		Runtime:        136,
+		Genres:         []string{"Action", "Science Fiction"},
+		UniqueIDs:      map[string]string{"tvdb": "169", "tmdb": "603", "imdb": "tt0133093"},
+		CollectionID:   "2344",
+		CollectionName: "The Matrix Collection",
+	}, "US")
+	written, err := WriteMovie(path, movie)
+	if err != nil || !written {
+		t.Fatalf("WriteMovie() = %v, %v", written, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<movie>",
+		"<title>The Matrix</title>",
+		"<runtime>136</runtime>",
+		"<genre>Science Fiction</genre>",
+		`<uniqueid type="imdb">tt0133093</uniqueid>`,
+		`<uniqueid type="tmdb">603</uniqueid>`,
+		`<uniqueid type="tvdb" default="true">169</uniqueid>`,
+		"<set>\n    <name>The Matrix Collection</name>\n  </set>",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("NFO lacks %q:\n%s", want, data)
+		}
+	}
+
+	// An existing NFO, maybe edited by hand, is kept
+	if err := os.WriteFile(path, []byte("<movie/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if written, err := WriteMovie(path, movie); err != nil || written {
+		t.Errorf("WriteMovie() over an existing NFO = %v, %v", written, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "<movie/>" {
+		t.Errorf("existing NFO overwritten: %s", data)
+	}
+}
+
+func TestNewMovieWithMetadata(t *testing.T) {
+	movie := NewMovie(&api.UnifiedMovieProposition{
+		ID:     "603",
+		Source: "tmdb",
+		Title:  "Matrix",
+		Metadata: &api.MovieMetadata{
+			OriginalTitle:  "The Matrix",
+			Tagline:        "Welcome to the Real World.",
+			Directors:      []string{"Lana Wachowski", "Lilly Wachowski"},
+			Cast:           []api.CastMember{{Name: "Keanu Reeves", Role: "Neo"}},
+			Certifications: map[string]string{"US": "R", "DE": "16"},
+			ReleaseDates: []api.ReleaseDate{
+				{Country: "US", Date: "1999-03-31", Type: api.ReleaseTheatrical},
+				{Country: "DE", Date: "1999-06-17", Type: api.ReleaseTheatrical},
+			},
+		},
+	}, "DE")
+
+	if movie.OriginalTitle != "The Matrix" || movie.MPAA != "16" || movie.Premiered != "1999-06-17" {
+		t.Errorf("NewMovie() = %+v, want the original title and the German certification and release", movie)
+	}
+	if len(movie.Directors) != 2 || len(movie.Actors) != 1 || movie.Actors[0].Role != "Neo" {
+		t.Errorf("NewMovie() credits = %v %+v", movie.Directors, movie.Actors)
+	}
+}
+
+func TestNewMovieWithoutCollection(t *testing.T) {
+	movie := NewMovie(&api.UnifiedMovieProposition{ID: "553", Source: "tvdb", Title: "Dune"}, "US")
+	if movie.Set != nil {
+		t.Errorf("NewMovie() set = %+v, want none", movie.Set)
+	}
+	if len(movie.UniqueIDs) != 1 || !movie.UniqueIDs[0].Default {
+		t.Errorf("NewMovie() unique IDs = %+v", movie.UniqueIDs)
+	}
+}
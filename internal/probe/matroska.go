@@ -0,0 +1,378 @@
+package probe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// Matroska element IDs (with their length marker bits, as written in the file)
+const (
+	mkvSegment                 = 0x18538067
+	mkvInfo                    = 0x1549A966
+	mkvTimecodeScale           = 0x2AD7B1
+	mkvDuration                = 0x4489
+	mkvTracks                  = 0x1654AE6B
+	mkvTrackEntry              = 0xAE
+	mkvTrackType               = 0x83
+	mkvCodecID                 = 0x86
+	mkvLanguage                = 0x22B59C
+	mkvLanguageIETF            = 0x22B59D
+	mkvVideo                   = 0xE0
+	mkvPixelWidth              = 0xB0
+	mkvPixelHeight             = 0xBA
+	mkvColour                  = 0x55B0
+	mkvTransferCharacteristics = 0x55BA
+	mkvBlockAdditionMapping    = 0x41E4
+	mkvBlockAddIDType          = 0x41E7
+	mkvCluster                 = 0x1F43B675
+)
+
+// Matroska track types
+const (
+	mkvTrackTypeVideo    = 1
+	mkvTrackTypeAudio    = 2
+	mkvTrackTypeSubtitle = 17
+)
+
+// maxElementSize bounds the size of metadata elements read into memory
+const maxElementSize = 16 * 1024 * 1024
+
+// unknownSize is returned for EBML elements whose size is not known in advance (live streams)
+const unknownSize = math.MaxInt64
+
+// mkvCodecs maps Matroska codec IDs to short codec names
+var mkvCodecs = map[string]string{
+	"V_MPEG4/ISO/AVC":  "h264",
+	"V_MPEGH/ISO/HEVC": "hevc",
+	"V_AV1":            "av1",
+	"V_VP8":            "vp8",
+	"V_VP9":            "vp9",
+	"V_MPEG4/ISO/ASP":  "mpeg4",
+	"V_MPEG4/ISO/SP":   "mpeg4",
+	"V_MPEG2":          "mpeg2",
+	"V_MS/VFW/FOURCC":  "vfw",
+	"V_THEORA":         "theora",
+}
+
+// readVint reads an EBML variable-length integer. When keepMarker is true the
+// length marker bit is kept, which is how element IDs are conventionally written.
+func readVint(r io.Reader, keepMarker bool) (value int64, length int, err error) {
+	first := make([]byte, 1)
+	if _, err := io.ReadFull(r, first); err != nil {
+		return 0, 0, err
+	}
+
+	length = 1
+	mask := byte(0x80)
+	for length <= 8 && first[0]&mask == 0 {
+		mask >>= 1
+		length++
+	}
+	if length > 8 {
+		return 0, 0, fmt.Errorf("invalid EBML variable-length integer")
+	}
+
+	value = int64(first[0])
+	if !keepMarker {
+		value = int64(first[0] & (mask - 1))
+	}
+	allOnes := value == int64(mask-1)
+
+	rest := make([]byte, length-1)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return 0, 0, err
+	}
+	for _, b := range rest {
+		value = value<<8 | int64(b)
+		if b != 0xFF {
+			allOnes = false
+		}
+	}
+
+	if !keepMarker && allOnes {
+		return unknownSize, length, nil
+	}
+
+	return value, length, nil
+}
+
+// readElementHeader reads an EBML element ID and data size
+func readElementHeader(r io.Reader) (id int64, size int64, err error) {
+	id, _, err = readVint(r, true)
+	if err != nil {
+		return 0, 0, err
+	}
+	size, _, err = readVint(r, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	return id, size, nil
+}
+
+// ebmlElement is an element whose payload has been read into memory
+type ebmlElement struct {
+	id   int64
+	data []byte
+}
+
+// parseChildren splits an in-memory master element payload into its child elements
+func parseChildren(data []byte) ([]ebmlElement, error) {
+	var children []ebmlElement
+	r := &sliceReader{data: data}
+
+	for r.pos < len(data) {
+		id, size, err := readElementHeader(r)
+		if err != nil {
+			return children, err
+		}
+		if size == unknownSize || size > int64(len(data)-r.pos) {
+			return children, fmt.Errorf("element 0x%X overflows its parent", id)
+		}
+		children = append(children, ebmlElement{id: id, data: data[r.pos : r.pos+int(size)]})
+		r.pos += int(size)
+	}
+
+	return children, nil
+}
+
+// sliceReader is a minimal io.Reader over a byte slice exposing its position
+type sliceReader struct {
+	data []byte
+	pos  int
+}
+
+func (s *sliceReader) Read(p []byte) (int, error) {
+	if s.pos >= len(s.data) {
+		return 0, io.EOF
+	}
+	n := copy(p, s.data[s.pos:])
+	s.pos += n
+	return n, nil
+}
+
+// probeMatroska walks the EBML structure up to the first cluster, reading segment info and tracks
+func probeMatroska(r io.ReadSeeker) (*Info, error) {
+	// EBML header
+	id, size, err := readElementHeader(r)
+	if err != nil || id != 0x1A45DFA3 {
+		return nil, fmt.Errorf("invalid EBML header")
+	}
+	if _, err := r.Seek(size, io.SeekCurrent); err != nil {
+		return nil, fmt.Errorf("failed to skip EBML header: %w", err)
+	}
+
+	id, _, err = readElementHeader(r)
+	if err != nil || id != mkvSegment {
+		return nil, fmt.Errorf("matroska segment not found")
+	}
+
+	timecodeScale := int64(1000000)
+	var rawDuration float64
+	var tracks []track
+	foundInfo, foundTracks := false, false
+
+	for !(foundInfo && foundTracks) {
+		id, size, err := readElementHeader(r)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read segment element: %w", err)
+		}
+
+		// Media data starts here: all metadata we care about comes before it in practice
+		if id == mkvCluster || size == unknownSize {
+			break
+		}
+
+		switch id {
+		case mkvInfo, mkvTracks:
+			if size > maxElementSize {
+				return nil, fmt.Errorf("element 0x%X too large", id)
+			}
+			data := make([]byte, size)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, fmt.Errorf("failed to read element 0x%X: %w", id, err)
+			}
+			if id == mkvInfo {
+				timecodeScale, rawDuration = parseMatroskaInfo(data, timecodeScale)
+				foundInfo = true
+			} else {
+				tracks = parseMatroskaTracks(data)
+				foundTracks = true
+			}
+		default:
+			if _, err := r.Seek(size, io.SeekCurrent); err != nil {
+				return nil, fmt.Errorf("failed to skip element 0x%X: %w", id, err)
+			}
+		}
+	}
+
+	if !foundInfo && !foundTracks {
+		return nil, fmt.Errorf("no matroska metadata found")
+	}
+
+	duration := time.Duration(rawDuration * float64(timecodeScale))
+	return buildInfo(ContainerMatroska, duration, tracks), nil
+}
+
+// parseMatroskaInfo extracts the timecode scale and raw duration from a segment Info element
+func parseMatroskaInfo(data []byte, timecodeScale int64) (int64, float64) {
+	children, _ := parseChildren(data)
+	var duration float64
+
+	for _, child := range children {
+		switch child.id {
+		case mkvTimecodeScale:
+			if scale := readUint(child.data); scale > 0 {
+				timecodeScale = int64(scale)
+			}
+		case mkvDuration:
+			duration = readFloat(child.data)
+		}
+	}
+
+	return timecodeScale, duration
+}
+
+// parseMatroskaTracks extracts all track entries from a Tracks element
+func parseMatroskaTracks(data []byte) []track {
+	children, _ := parseChildren(data)
+	var tracks []track
+
+	for _, child := range children {
+		if child.id != mkvTrackEntry {
+			continue
+		}
+
+		entries, _ := parseChildren(child.data)
+		t := track{}
+		var trackType uint64
+		var ietf string
+
+		for _, entry := range entries {
+			switch entry.id {
+			case mkvTrackType:
+				trackType = readUint(entry.data)
+			case mkvCodecID:
+				t.codec = readString(entry.data)
+			case mkvLanguage:
+				t.language = readString(entry.data)
+			case mkvLanguageIETF:
+				ietf = readString(entry.data)
+			case mkvVideo:
+				t.width, t.height, t.hdr = parseMatroskaVideo(entry.data)
+			case mkvBlockAdditionMapping:
+				if isDolbyVisionMapping(entry.data) {
+					t.hdr = "Dolby Vision"
+				}
+			}
+		}
+
+		// Fall back to the primary subtag of the IETF (BCP 47) language, then to the Matroska default
+		if t.language == "" && ietf != "" {
+			t.language = strings.ToLower(strings.SplitN(ietf, "-", 2)[0])
+		}
+		if t.language == "" {
+			t.language = "eng"
+		}
+
+		switch trackType {
+		case mkvTrackTypeVideo:
+			t.kind = "video"
+			if name, ok := mkvCodecs[t.codec]; ok {
+				t.codec = name
+			}
+		case mkvTrackTypeAudio:
+			t.kind = "audio"
+		case mkvTrackTypeSubtitle:
+			t.kind = "subtitle"
+		default:
+			continue
+		}
+
+		tracks = append(tracks, t)
+	}
+
+	return tracks
+}
+
+// parseMatroskaVideo extracts dimensions and HDR transfer characteristics from a Video element
+func parseMatroskaVideo(data []byte) (width, height int, hdr string) {
+	children, _ := parseChildren(data)
+
+	for _, child := range children {
+		switch child.id {
+		case mkvPixelWidth:
+			width = int(readUint(child.data))
+		case mkvPixelHeight:
+			height = int(readUint(child.data))
+		case mkvColour:
+			colour, _ := parseChildren(child.data)
+			for _, c := range colour {
+				if c.id == mkvTransferCharacteristics {
+					hdr = transferToHDR(readUint(c.data))
+				}
+			}
+		}
+	}
+
+	return width, height, hdr
+}
+
+// isDolbyVisionMapping checks if a BlockAdditionMapping declares a Dolby Vision configuration
+func isDolbyVisionMapping(data []byte) bool {
+	children, _ := parseChildren(data)
+	for _, child := range children {
+		// The type is an unsigned integer holding the configuration box FourCC
+		if child.id == mkvBlockAddIDType {
+			fourcc := string(child.data)
+			if fourcc == "dvcC" || fourcc == "dvvC" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// transferToHDR maps ITU-T H.273 transfer characteristics to an HDR format name
+func transferToHDR(transfer uint64) string {
+	switch transfer {
+	case 16:
+		return "HDR10"
+	case 18:
+		return "HLG"
+	default:
+		return ""
+	}
+}
+
+// readUint decodes a big-endian unsigned integer of up to 8 bytes
+func readUint(data []byte) uint64 {
+	var v uint64
+	for _, b := range data {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+// readFloat decodes a 4 or 8 byte big-endian IEEE 754 float
+func readFloat(data []byte) float64 {
+	switch len(data) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	default:
+		return 0
+	}
+}
+
+// readString decodes an EBML string, trimming NUL padding
+func readString(data []byte) string {
+	return strings.TrimRight(string(data), "\x00")
+}
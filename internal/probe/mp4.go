@@ -0,0 +1,309 @@
+package probe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// maxMoovSize bounds the size of the movie header atom read into memory
+const maxMoovSize = 64 * 1024 * 1024
+
+// mp4Codecs maps MP4 sample entry FourCCs to short codec names
+var mp4Codecs = map[string]string{
+	"avc1": "h264",
+	"avc3": "h264",
+	"dvav": "h264",
+	"dva1": "h264",
+	"hvc1": "hevc",
+	"hev1": "hevc",
+	"dvh1": "hevc",
+	"dvhe": "hevc",
+	"av01": "av1",
+	"vp09": "vp9",
+	"vp08": "vp8",
+	"mp4v": "mpeg4",
+}
+
+// mp4DolbyVisionEntries lists sample entry FourCCs that always carry Dolby Vision
+var mp4DolbyVisionEntries = map[string]bool{
+	"dvav": true, "dva1": true, "dvh1": true, "dvhe": true,
+}
+
+// mp4Handlers maps track handler types to track kinds
+var mp4Handlers = map[string]string{
+	"vide": "video",
+	"soun": "audio",
+	"sbtl": "subtitle",
+	"subt": "subtitle",
+	"text": "subtitle",
+	"clcp": "subtitle",
+}
+
+// atom is an MP4 box whose payload has been read into memory
+type atom struct {
+	kind string
+	data []byte
+}
+
+// probeMP4 locates the moov atom among the top-level atoms and parses it
+func probeMP4(r io.ReadSeeker) (*Info, error) {
+	header := make([]byte, 8)
+
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, fmt.Errorf("moov atom not found")
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		kind := string(header[4:8])
+		headerSize := int64(8)
+
+		switch size {
+		case 0:
+			// Atom extends to the end of the file
+			if kind != "moov" {
+				return nil, fmt.Errorf("moov atom not found")
+			}
+			size = maxMoovSize + headerSize
+		case 1:
+			largeSize := make([]byte, 8)
+			if _, err := io.ReadFull(r, largeSize); err != nil {
+				return nil, fmt.Errorf("failed to read atom size: %w", err)
+			}
+			size = int64(binary.BigEndian.Uint64(largeSize))
+			headerSize = 16
+		}
+
+		if size < headerSize {
+			return nil, fmt.Errorf("invalid atom size for '%s'", kind)
+		}
+
+		if kind != "moov" {
+			if _, err := r.Seek(size-headerSize, io.SeekCurrent); err != nil {
+				return nil, fmt.Errorf("failed to skip atom '%s': %w", kind, err)
+			}
+			continue
+		}
+
+		if size-headerSize > maxMoovSize {
+			return nil, fmt.Errorf("moov atom too large")
+		}
+		data, err := io.ReadAll(io.LimitReader(r, size-headerSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read moov atom: %w", err)
+		}
+
+		return parseMoov(data), nil
+	}
+}
+
+// parseAtoms splits an in-memory container atom payload into its children
+func parseAtoms(data []byte) []atom {
+	var atoms []atom
+
+	for pos := 0; pos+8 <= len(data); {
+		size := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		kind := string(data[pos+4 : pos+8])
+		headerSize := 8
+
+		switch size {
+		case 0:
+			size = len(data) - pos
+		case 1:
+			if pos+16 > len(data) {
+				return atoms
+			}
+			size = int(binary.BigEndian.Uint64(data[pos+8 : pos+16]))
+			headerSize = 16
+		}
+
+		if size < headerSize || size > len(data)-pos {
+			return atoms
+		}
+
+		atoms = append(atoms, atom{kind: kind, data: data[pos+headerSize : pos+size]})
+		pos += size
+	}
+
+	return atoms
+}
+
+// findAtom returns the first child atom of the given kind
+func findAtom(atoms []atom, kind string) (atom, bool) {
+	for _, a := range atoms {
+		if a.kind == kind {
+			return a, true
+		}
+	}
+	return atom{}, false
+}
+
+// findPath descends through nested container atoms following the given kinds
+func findPath(data []byte, kinds ...string) (atom, bool) {
+	current := atom{data: data}
+	for _, kind := range kinds {
+		next, ok := findAtom(parseAtoms(current.data), kind)
+		if !ok {
+			return atom{}, false
+		}
+		current = next
+	}
+	return current, true
+}
+
+// parseMoov extracts duration and tracks from the moov atom payload
+func parseMoov(data []byte) *Info {
+	var duration time.Duration
+	var tracks []track
+
+	for _, child := range parseAtoms(data) {
+		switch child.kind {
+		case "mvhd":
+			duration = parseMvhd(child.data)
+		case "trak":
+			if t, ok := parseTrak(child.data); ok {
+				tracks = append(tracks, t)
+			}
+		}
+	}
+
+	return buildInfo(ContainerMP4, duration, tracks)
+}
+
+// parseMvhd reads the movie duration from a movie header atom
+func parseMvhd(data []byte) time.Duration {
+	if len(data) < 20 {
+		return 0
+	}
+
+	var timescale, duration uint64
+	if data[0] == 1 {
+		if len(data) < 32 {
+			return 0
+		}
+		timescale = uint64(binary.BigEndian.Uint32(data[20:24]))
+		duration = binary.BigEndian.Uint64(data[24:32])
+	} else {
+		timescale = uint64(binary.BigEndian.Uint32(data[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(data[16:20]))
+	}
+
+	if timescale == 0 {
+		return 0
+	}
+	return time.Duration(float64(duration) / float64(timescale) * float64(time.Second))
+}
+
+// parseTrak reads handler type, language, dimensions and codec of a track atom
+func parseTrak(data []byte) (track, bool) {
+	children := parseAtoms(data)
+	var t track
+
+	mdia, ok := findAtom(children, "mdia")
+	if !ok {
+		return t, false
+	}
+	mdiaChildren := parseAtoms(mdia.data)
+
+	hdlr, ok := findAtom(mdiaChildren, "hdlr")
+	if !ok || len(hdlr.data) < 12 {
+		return t, false
+	}
+	t.kind, ok = mp4Handlers[string(hdlr.data[8:12])]
+	if !ok {
+		return t, false
+	}
+
+	if mdhd, ok := findAtom(mdiaChildren, "mdhd"); ok {
+		t.language = parseMdhdLanguage(mdhd.data)
+	}
+
+	if t.kind == "video" {
+		if tkhd, ok := findAtom(children, "tkhd"); ok {
+			t.width, t.height = parseTkhdDimensions(tkhd.data)
+		}
+		if stsd, ok := findPath(mdia.data, "minf", "stbl", "stsd"); ok {
+			t.codec, t.hdr = parseVisualSampleEntry(stsd.data)
+		}
+	}
+
+	return t, true
+}
+
+// parseMdhdLanguage decodes the packed ISO 639-2/T language code of a media header atom
+func parseMdhdLanguage(data []byte) string {
+	offset := 20
+	if len(data) > 0 && data[0] == 1 {
+		offset = 32
+	}
+	if len(data) < offset+2 {
+		return ""
+	}
+
+	packed := binary.BigEndian.Uint16(data[offset : offset+2])
+	// Values below 0x400 are legacy Macintosh language codes, not ISO codes
+	if packed < 0x400 {
+		return ""
+	}
+
+	return string([]byte{
+		byte((packed>>10)&0x1F) + 0x60,
+		byte((packed>>5)&0x1F) + 0x60,
+		byte(packed&0x1F) + 0x60,
+	})
+}
+
+// parseTkhdDimensions reads the 16.16 fixed-point presentation size of a track header atom
+func parseTkhdDimensions(data []byte) (width, height int) {
+	offset := 76
+	if len(data) > 0 && data[0] == 1 {
+		offset = 88
+	}
+	if len(data) < offset+8 {
+		return 0, 0
+	}
+
+	width = int(binary.BigEndian.Uint32(data[offset:offset+4]) >> 16)
+	height = int(binary.BigEndian.Uint32(data[offset+4:offset+8]) >> 16)
+	return width, height
+}
+
+// parseVisualSampleEntry reads the codec and HDR signalling of the first sample description
+func parseVisualSampleEntry(data []byte) (codec, hdr string) {
+	// version/flags (4) + entry count (4)
+	if len(data) < 8 {
+		return "", ""
+	}
+	entries := parseAtoms(data[8:])
+	if len(entries) == 0 {
+		return "", ""
+	}
+
+	entry := entries[0]
+	codec = entry.kind
+	if name, ok := mp4Codecs[entry.kind]; ok {
+		codec = name
+	}
+	if mp4DolbyVisionEntries[entry.kind] {
+		hdr = "Dolby Vision"
+	}
+
+	// Child boxes follow the 78 byte visual sample entry fields
+	if len(entry.data) <= 78 {
+		return codec, hdr
+	}
+	for _, child := range parseAtoms(entry.data[78:]) {
+		switch child.kind {
+		case "dvcC", "dvvC":
+			hdr = "Dolby Vision"
+		case "colr":
+			if hdr == "" && len(child.data) >= 8 && string(child.data[:4]) == "nclx" {
+				hdr = transferToHDR(uint64(binary.BigEndian.Uint16(child.data[6:8])))
+			}
+		}
+	}
+
+	return codec, hdr
+}
@@ -0,0 +1,143 @@
+package probe
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	// ContainerMatroska identifies Matroska and WebM files
+	ContainerMatroska = "matroska"
+	// ContainerMP4 identifies MP4, M4V and QuickTime MOV files
+	ContainerMP4 = "mp4"
+)
+
+// ebmlMagic is the EBML header element ID starting every Matroska/WebM file
+var ebmlMagic = []byte{0x1A, 0x45, 0xDF, 0xA3}
+
+// mp4TopLevelAtoms lists atom types that can legitimately start an MP4/MOV file
+var mp4TopLevelAtoms = [][]byte{
+	[]byte("ftyp"), []byte("moov"), []byte("mdat"), []byte("free"), []byte("wide"), []byte("skip"), []byte("pnot"),
+}
+
+// Info contains the technical properties read from a video container header
+type Info struct {
+	Container         string
+	Duration          time.Duration
+	Width             int
+	Height            int
+	VideoCodec        string
+	HDR               string // HDR format ("HDR10", "HLG", "Dolby Vision") or empty for SDR
+	AudioLanguages    []string
+	SubtitleLanguages []string
+}
+
+// RuntimeMinutes returns the duration rounded to the nearest minute
+func (i *Info) RuntimeMinutes() int {
+	return int((i.Duration + 30*time.Second) / time.Minute)
+}
+
+// Resolution returns a resolution label such as "1080p" derived from the video dimensions
+func (i *Info) Resolution() string {
+	switch {
+	case i.Width == 0 && i.Height == 0:
+		return ""
+	case i.Width >= 3200 || i.Height >= 2000:
+		return "2160p"
+	case i.Width >= 1800 || i.Height >= 1000:
+		return "1080p"
+	case i.Width >= 1200 || i.Height >= 700:
+		return "720p"
+	case i.Height >= 560:
+		return "576p"
+	default:
+		return "480p"
+	}
+}
+
+// track is the container-agnostic description of a single stream
+type track struct {
+	kind     string // "video", "audio" or "subtitle"
+	codec    string
+	language string
+	width    int
+	height   int
+	hdr      string
+}
+
+// Probe reads the container header of a video file and extracts its technical properties
+func Probe(path string) (*Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	return ProbeReader(f)
+}
+
+// ProbeReader detects the container format of r and extracts its technical properties
+func ProbeReader(r io.ReadSeeker) (*Info, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind: %w", err)
+	}
+
+	if bytes.Equal(header[:4], ebmlMagic) {
+		return probeMatroska(r)
+	}
+
+	for _, atom := range mp4TopLevelAtoms {
+		if bytes.Equal(header[4:8], atom) {
+			return probeMP4(r)
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported container format")
+}
+
+// buildInfo assembles an Info from the tracks found in a container
+func buildInfo(container string, duration time.Duration, tracks []track) *Info {
+	info := &Info{
+		Container: container,
+		Duration:  duration,
+	}
+
+	for _, t := range tracks {
+		switch t.kind {
+		case "video":
+			// The first video track is the main picture
+			if info.VideoCodec == "" {
+				info.VideoCodec = t.codec
+				info.Width = t.width
+				info.Height = t.height
+				info.HDR = t.hdr
+			}
+		case "audio":
+			info.AudioLanguages = appendLanguage(info.AudioLanguages, t.language)
+		case "subtitle":
+			info.SubtitleLanguages = appendLanguage(info.SubtitleLanguages, t.language)
+		}
+	}
+
+	return info
+}
+
+// appendLanguage adds a language code to the list unless it is undetermined or already present
+func appendLanguage(languages []string, language string) []string {
+	if language == "" || language == "und" {
+		return languages
+	}
+	for _, existing := range languages {
+		if existing == language {
+			return languages
+		}
+	}
+	return append(languages, language)
+}
@@ -0,0 +1,208 @@
+package probe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// ebml encodes an EBML element with an 8-byte size field
+func ebml(id uint32, payload ...[]byte) []byte {
+	var buf bytes.Buffer
+	idBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(idBytes, id)
+	buf.Write(bytes.TrimLeft(idBytes, "\x00"))
+
+	data := bytes.Join(payload, nil)
+	size := make([]byte, 8)
+	binary.BigEndian.PutUint64(size, uint64(len(data)))
+	size[0] = 0x01
+	buf.Write(size)
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func ebmlUint(id uint32, v uint64) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, v)
+	return ebml(id, data)
+}
+
+func ebmlFloat(id uint32, v float64) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, math.Float64bits(v))
+	return ebml(id, data)
+}
+
+func ebmlString(id uint32, s string) []byte {
+	return ebml(id, []byte(s))
+}
+
+func TestProbeMatroska(t *testing.T) {
+	file := bytes.Join([][]byte{
+		ebml(0x1A45DFA3, ebmlString(0x4282, "matroska")),
+		ebml(mkvSegment,
+			ebml(mkvInfo,
+				ebmlUint(mkvTimecodeScale, 1000000),
+				ebmlFloat(mkvDuration, 7200000),
+			),
+			ebml(mkvTracks,
+				ebml(mkvTrackEntry,
+					ebmlUint(mkvTrackType, mkvTrackTypeVideo),
+					ebmlString(mkvCodecID, "V_MPEGH/ISO/HEVC"),
+					ebml(mkvVideo,
+						ebmlUint(mkvPixelWidth, 3840),
+						ebmlUint(mkvPixelHeight, 1600),
+						ebml(mkvColour, ebmlUint(mkvTransferCharacteristics, 16)),
+					),
+				),
+				ebml(mkvTrackEntry,
+					ebmlUint(mkvTrackType, mkvTrackTypeAudio),
+					ebmlString(mkvCodecID, "A_EAC3"),
+					ebmlString(mkvLanguage, "fre"),
+				),
+				ebml(mkvTrackEntry,
+					ebmlUint(mkvTrackType, mkvTrackTypeAudio),
+					ebmlString(mkvCodecID, "A_AC3"),
+				),
+				ebml(mkvTrackEntry,
+					ebmlUint(mkvTrackType, mkvTrackTypeSubtitle),
+					ebmlString(mkvCodecID, "S_TEXT/UTF8"),
+					ebmlString(mkvLanguageIETF, "fr-CA"),
+				),
+			),
+			ebml(mkvCluster, []byte{0x00}),
+		),
+	}, nil)
+
+	info, err := ProbeReader(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("ProbeReader() error: %v", err)
+	}
+
+	if info.Container != ContainerMatroska {
+		t.Errorf("Container = %q", info.Container)
+	}
+	if info.Duration != 2*time.Hour || info.RuntimeMinutes() != 120 {
+		t.Errorf("Duration = %v", info.Duration)
+	}
+	if info.Width != 3840 || info.Height != 1600 || info.Resolution() != "2160p" {
+		t.Errorf("dimensions = %dx%d (%s)", info.Width, info.Height, info.Resolution())
+	}
+	if info.VideoCodec != "hevc" || info.HDR != "HDR10" {
+		t.Errorf("codec = %q, hdr = %q", info.VideoCodec, info.HDR)
+	}
+	if len(info.AudioLanguages) != 2 || info.AudioLanguages[0] != "fre" || info.AudioLanguages[1] != "eng" {
+		t.Errorf("AudioLanguages = %v", info.AudioLanguages)
+	}
+	if len(info.SubtitleLanguages) != 1 || info.SubtitleLanguages[0] != "fr" {
+		t.Errorf("SubtitleLanguages = %v", info.SubtitleLanguages)
+	}
+}
+
+// box encodes an MP4 atom
+func box(kind string, payload ...[]byte) []byte {
+	data := bytes.Join(payload, nil)
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(data)+8))
+	copy(header[4:], kind)
+	return append(header, data...)
+}
+
+func mdhd(language string) []byte {
+	data := make([]byte, 24)
+	packed := uint16(language[0]-0x60)<<10 | uint16(language[1]-0x60)<<5 | uint16(language[2]-0x60)
+	binary.BigEndian.PutUint16(data[20:22], packed)
+	return box("mdhd", data)
+}
+
+func hdlr(handler string) []byte {
+	data := make([]byte, 24)
+	copy(data[8:12], handler)
+	return box("hdlr", data)
+}
+
+func TestProbeMP4(t *testing.T) {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:16], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:20], 5400000)
+
+	tkhd := make([]byte, 84)
+	binary.BigEndian.PutUint32(tkhd[76:80], 1920<<16)
+	binary.BigEndian.PutUint32(tkhd[80:84], 1080<<16)
+
+	stsd := append(make([]byte, 8), box("avc1", make([]byte, 78), box("avcC"))...)
+
+	file := bytes.Join([][]byte{
+		box("ftyp", []byte("isom")),
+		box("mdat", make([]byte, 32)),
+		box("moov",
+			box("mvhd", mvhd),
+			box("trak",
+				box("tkhd", tkhd),
+				box("mdia", mdhd("und"), hdlr("vide"), box("minf", box("stbl", box("stsd", stsd)))),
+			),
+			box("trak", box("mdia", mdhd("eng"), hdlr("soun"))),
+			box("trak", box("mdia", mdhd("ger"), hdlr("sbtl"))),
+		),
+	}, nil)
+
+	info, err := ProbeReader(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("ProbeReader() error: %v", err)
+	}
+
+	if info.Container != ContainerMP4 || info.RuntimeMinutes() != 90 {
+		t.Errorf("container = %q, duration = %v", info.Container, info.Duration)
+	}
+	if info.Resolution() != "1080p" || info.VideoCodec != "h264" || info.HDR != "" {
+		t.Errorf("video = %dx%d %q %q", info.Width, info.Height, info.VideoCodec, info.HDR)
+	}
+	if len(info.AudioLanguages) != 1 || info.AudioLanguages[0] != "eng" {
+		t.Errorf("AudioLanguages = %v", info.AudioLanguages)
+	}
+	if len(info.SubtitleLanguages) != 1 || info.SubtitleLanguages[0] != "ger" {
+		t.Errorf("SubtitleLanguages = %v", info.SubtitleLanguages)
+	}
+}
+
+func TestProbeMP4OversizedAtom(t *testing.T) {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:16], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:20], 5400000)
+
+	// A 64-bit sized atom whose size would overflow pos+size
+	oversized := make([]byte, 24)
+	binary.BigEndian.PutUint32(oversized[0:4], 1)
+	copy(oversized[4:8], "co64")
+	binary.BigEndian.PutUint64(oversized[8:16], math.MaxInt64-4)
+
+	// A 64-bit sized atom truncated before the end of its declared size
+	truncated := make([]byte, 24)
+	binary.BigEndian.PutUint32(truncated[0:4], 1)
+	copy(truncated[4:8], "co64")
+	binary.BigEndian.PutUint64(truncated[8:16], 64)
+
+	for name, child := range map[string][]byte{"oversized": oversized, "truncated": truncated} {
+		file := bytes.Join([][]byte{
+			box("ftyp", []byte("isom")),
+			box("moov", box("mvhd", mvhd), child),
+		}, nil)
+
+		info, err := ProbeReader(bytes.NewReader(file))
+		if err != nil {
+			t.Fatalf("%s: ProbeReader() error: %v", name, err)
+		}
+		if info.RuntimeMinutes() != 90 {
+			t.Errorf("%s: duration = %v", name, info.Duration)
+		}
+	}
+}
+
+func TestProbeUnsupported(t *testing.T) {
+	if _, err := ProbeReader(bytes.NewReader([]byte("RIFF....AVI LIST"))); err == nil {
+		t.Error("expected an error for an unsupported container")
+	}
+}
@@ -0,0 +1,56 @@
+package renamer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"kodi-renamer/internal/archive"
+)
+
+// ExtractArchiveMember extracts a video from its archive set to destDir/newFilename, which takes
+// the place of moving the file when the video only exists inside an archive
+func (r *Renamer) ExtractArchiveMember(member *archive.Member, destDir, newFilename string) error {
+	newPath := filepath.Join(destDir, newFilename)
+	source := member.Set.Volumes[0] + ":" + member.Entry.Name
+
+	if _, err := os.Stat(newPath); err == nil {
+		return fmt.Errorf("file already exists: %s", newPath)
+	}
+	if !member.Entry.Extractable {
+		return fmt.Errorf("%s: %w", source, archive.ErrNotExtractable)
+	}
+
+	if r.dryRun {
+		fmt.Printf("[DRY RUN] Would extract:\n  FROM: %s\n  TO:   %s\n", source, newPath)
+		return nil
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := member.Set.ExtractTo(member.Entry.Name, newPath); err != nil {
+		return err
+	}
+
+	fmt.Printf("Extracted:\n  FROM: %s\n  TO:   %s\n", source, newPath)
+	return nil
+}
+
+// RemoveArchiveSet deletes every volume of an archive set once its videos have been extracted
+func (r *Renamer) RemoveArchiveSet(set archive.Set) error {
+	for _, volume := range set.Volumes {
+		if r.dryRun {
+			fmt.Printf("[DRY RUN] Would delete archive volume: %s\n", volume)
+			continue
+		}
+		if err := os.Remove(volume); err != nil {
+			return fmt.Errorf("failed to delete archive volume: %w", err)
+		}
+	}
+
+	if !r.dryRun {
+		fmt.Printf("Deleted archive set %s (%d volume(s))\n", set.Volumes[0], len(set.Volumes))
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package renamer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// companionExtensions lists the metadata and artwork files that follow a video when it is renamed
+	companionExtensions = []string{".nfo", ".jpg", ".jpeg", ".png", ".tbn"}
+
+	// audioExtensions lists the external audio track formats that follow a video when it is renamed
+	audioExtensions = []string{".mka", ".ac3", ".eac3", ".dts", ".aac", ".flac"}
+
+	// subtitleFolderNames lists the subtitle subdirectories found in release packs
+	subtitleFolderNames = []string{"subs", "subtitles", "sub"}
+)
+
+// companionFile is a file belonging to a video, with the name it takes after the video is renamed
+type companionFile struct {
+	path    string
+	newName string
+}
+
+// group returns the key shared by files that must move together, such as the .idx and .sub
+// halves of a VobSub subtitle
+func (c companionFile) group() string {
+	return strings.TrimSuffix(c.path, filepath.Ext(c.path))
+}
+
+// belongsToVideo reports whether a file name without extension is the video name, optionally
+// followed by a separator and tags: "Show.S01E01.en" and "Show.S01E01-thumb" belong to
+// "Show.S01E01" but "Show.S01E01E02" does not
+func belongsToVideo(nameWithoutExt, videoNameWithoutExt string) bool {
+	if !strings.HasPrefix(nameWithoutExt, videoNameWithoutExt) {
+		return false
+	}
+
+	rest := strings.TrimPrefix(nameWithoutExt, videoNameWithoutExt)
+	if rest == "" {
+		return true
+	}
+	if !strings.ContainsRune("._- [(", rune(rest[0])) {
+		return false
+	}
+	// A space alone is too weak a separator ("Movie 2"), unless tags follow it
+	return rest[0] != ' ' || ParseSubtitleTags(rest) != (SubtitleTags{})
+}
+
+// isCompanionExtension checks if the lower-case extension is a metadata or artwork format
+func isCompanionExtension(ext string) bool {
+	for _, companionExt := range companionExtensions {
+		if ext == companionExt {
+			return true
+		}
+	}
+	return false
+}
+
+// isAudioExtension checks if the lower-case extension is an external audio track format
+func isAudioExtension(ext string) bool {
+	for _, audioExt := range audioExtensions {
+		if ext == audioExt {
+			return true
+		}
+	}
+	return false
+}
+
+// isSubtitleFolder checks if a directory name is a subtitle subdirectory
+func isSubtitleFolder(name string) bool {
+	lower := strings.ToLower(name)
+	for _, folderName := range subtitleFolderNames {
+		if lower == folderName {
+			return true
+		}
+	}
+	return false
+}
+
+// findCompanionFiles finds the files belonging to a video: subtitles, external audio tracks, NFO
+// and thumbnails with the same base name, plus subtitles in Subs/ subdirectories, either named
+// after the video (Subs/Name.en.srt) or grouped per video (Subs/Name/2_English.srt)
+func findCompanionFiles(videoPath, newNameWithoutExt string) []companionFile {
+	dir := filepath.Dir(videoPath)
+	videoFilename := filepath.Base(videoPath)
+	videoNameWithoutExt := strings.TrimSuffix(videoFilename, filepath.Ext(videoFilename))
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var companions []companionFile
+	for _, entry := range entries {
+		name := entry.Name()
+
+		if entry.IsDir() {
+			if isSubtitleFolder(name) {
+				companions = append(companions, findFolderSubtitles(filepath.Join(dir, name), videoNameWithoutExt, newNameWithoutExt)...)
+			}
+			continue
+		}
+
+		if name == videoFilename {
+			continue
+		}
+
+		ext := filepath.Ext(name)
+		nameWithoutExt := strings.TrimSuffix(name, ext)
+		if !belongsToVideo(nameWithoutExt, videoNameWithoutExt) {
+			continue
+		}
+
+		lowerExt := strings.ToLower(ext)
+		switch {
+		case isSubtitleExtension(lowerExt), isAudioExtension(lowerExt):
+			// Subtitles and audio tracks carry language tags: "Movie.en.ac3" becomes "Title (Year).eng.ac3"
+			companions = append(companions, companionFile{
+				path:    filepath.Join(dir, name),
+				newName: SubtitleFilename(newNameWithoutExt, videoNameWithoutExt, name),
+			})
+		case isCompanionExtension(lowerExt):
+			// Keep the suffix as is: "-thumb.jpg" stays "-thumb.jpg"
+			companions = append(companions, companionFile{
+				path:    filepath.Join(dir, name),
+				newName: newNameWithoutExt + strings.TrimPrefix(nameWithoutExt, videoNameWithoutExt) + lowerExt,
+			})
+		}
+	}
+
+	return uniqueCompanionNames(companions)
+}
+
+// findFolderSubtitles finds the subtitles of a video inside a subtitle subdirectory
+func findFolderSubtitles(subsDir, videoNameWithoutExt, newNameWithoutExt string) []companionFile {
+	entries, err := os.ReadDir(subsDir)
+	if err != nil {
+		return nil
+	}
+
+	var companions []companionFile
+	for _, entry := range entries {
+		name := entry.Name()
+
+		// Per-video folder: every subtitle inside belongs to the video, its name only carries tags
+		if entry.IsDir() {
+			if name != videoNameWithoutExt {
+				continue
+			}
+			videoSubsDir := filepath.Join(subsDir, name)
+			videoEntries, err := os.ReadDir(videoSubsDir)
+			if err != nil {
+				continue
+			}
+			for _, videoEntry := range videoEntries {
+				subName := videoEntry.Name()
+				if videoEntry.IsDir() || !isSubtitleExtension(strings.ToLower(filepath.Ext(subName))) {
+					continue
+				}
+				companions = append(companions, companionFile{
+					path:    filepath.Join(videoSubsDir, subName),
+					newName: SubtitleFilename(newNameWithoutExt, "", subName),
+				})
+			}
+			continue
+		}
+
+		ext := filepath.Ext(name)
+		if !isSubtitleExtension(strings.ToLower(ext)) || !belongsToVideo(strings.TrimSuffix(name, ext), videoNameWithoutExt) {
+			continue
+		}
+		companions = append(companions, companionFile{
+			path:    filepath.Join(subsDir, name),
+			newName: SubtitleFilename(newNameWithoutExt, videoNameWithoutExt, name),
+		})
+	}
+
+	return companions
+}
+
+// groupCompanions splits companions into the groups that must be moved together, in order
+func groupCompanions(companions []companionFile) [][]companionFile {
+	var groups [][]companionFile
+	index := make(map[string]int)
+
+	for _, companion := range companions {
+		key := companion.group()
+		if i, ok := index[key]; ok {
+			groups[i] = append(groups[i], companion)
+			continue
+		}
+		index[key] = len(groups)
+		groups = append(groups, []companionFile{companion})
+	}
+
+	return groups
+}
+
+// numberedName inserts a disambiguation number before the extension of a filename
+func numberedName(name string, n int) string {
+	if n <= 1 {
+		return name
+	}
+	ext := filepath.Ext(name)
+	return fmt.Sprintf("%s.%d%s", strings.TrimSuffix(name, ext), n, ext)
+}
+
+// uniqueCompanionNames numbers companions whose new names would otherwise collide; the files of
+// a group (such as a VobSub .idx/.sub pair) always receive the same number so they stay paired
+func uniqueCompanionNames(companions []companionFile) []companionFile {
+	used := make(map[string]bool)
+	result := make([]companionFile, 0, len(companions))
+
+	for _, group := range groupCompanions(companions) {
+		for n := 1; ; n++ {
+			free := true
+			for _, companion := range group {
+				if used[strings.ToLower(numberedName(companion.newName, n))] {
+					free = false
+					break
+				}
+			}
+			if !free {
+				continue
+			}
+
+			for _, companion := range group {
+				companion.newName = numberedName(companion.newName, n)
+				used[strings.ToLower(companion.newName)] = true
+				result = append(result, companion)
+			}
+			break
+		}
+	}
+
+	return result
+}
+
+// MoveCompanions moves the companion files of a video (subtitles, VobSub pairs, external audio,
+// NFO, thumbnails and Subs/ subtitles) to destDir, renaming them after the new video filename
+func (r *Renamer) MoveCompanions(oldVideoPath, destDir, newVideoFilename string) {
+	newNameWithoutExt := strings.TrimSuffix(newVideoFilename, filepath.Ext(newVideoFilename))
+	videoDir := filepath.Dir(oldVideoPath)
+
+	for _, group := range groupCompanions(findCompanionFiles(oldVideoPath, newNameWithoutExt)) {
+		if err := r.moveCompanionGroup(group, destDir); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			continue
+		}
+
+		// Drop Subs/<Name>/ and Subs/ once emptied
+		companionDir := filepath.Dir(group[0].path)
+		for companionDir != videoDir && strings.HasPrefix(companionDir, videoDir) {
+			r.RemoveEmptyDir(companionDir)
+			companionDir = filepath.Dir(companionDir)
+		}
+	}
+}
+
+// moveCompanionGroup moves the files of a companion group all or nothing: if any move fails,
+// the files already moved are put back so a VobSub pair is never split
+func (r *Renamer) moveCompanionGroup(group []companionFile, destDir string) error {
+	var pending []companionFile
+	for _, companion := range group {
+		if companion.path == filepath.Join(destDir, companion.newName) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(destDir, companion.newName)); err == nil {
+			return fmt.Errorf("companion file already exists: %s", filepath.Join(destDir, companion.newName))
+		}
+		pending = append(pending, companion)
+	}
+
+	if r.dryRun {
+		for _, companion := range pending {
+			fmt.Printf("[DRY RUN] Would move companion file:\n  FROM: %s\n  TO:   %s\n", companion.path, filepath.Join(destDir, companion.newName))
+		}
+		return nil
+	}
+
+	for i, companion := range pending {
+		newPath := filepath.Join(destDir, companion.newName)
+		if err := r.Move(companion.path, newPath); err != nil {
+			err = fmt.Errorf("failed to move companion file %s: %w", companion.path, err)
+			// Put the group back together where it was
+			for _, moved := range pending[:i] {
+				if rollbackErr := r.Move(filepath.Join(destDir, moved.newName), moved.path); rollbackErr != nil {
+					err = errors.Join(err, fmt.Errorf("failed to move back companion file %s: %w", moved.path, rollbackErr))
+				}
+			}
+			return err
+		}
+	}
+
+	for _, companion := range pending {
+		fmt.Printf("Moved companion file:\n  FROM: %s\n  TO:   %s\n", companion.path, filepath.Join(destDir, companion.newName))
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package renamer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBelongsToVideo(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"Show.S01E01", true},
+		{"Show.S01E01.en", true},
+		{"Show.S01E01-thumb", true},
+		{"Show.S01E01 English", true},
+		{"Show.S01E01E02", false},
+		{"Show.S01E01 2", false},
+		{"Show.S01E02", false},
+	}
+
+	for _, tt := range tests {
+		if got := belongsToVideo(tt.name, "Show.S01E01"); got != tt.want {
+			t.Errorf("belongsToVideo(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMoveCompanions(t *testing.T) {
+	srcDir := t.TempDir()
+	destDir := t.TempDir()
+
+	files := []string{
+		"Show.S01E01.mkv",
+		"Show.S01E01.nfo",
+		"Show.S01E01-thumb.jpg",
+		"Show.S01E01.mka",
+		"Show.S01E01.en.srt",
+		"Show.S01E02.mkv",
+		"Show.S01E02.nfo",
+		"Show.S01E01.txt",
+		filepath.Join("Subs", "Show.S01E01", "2_English.srt"),
+		filepath.Join("Subs", "Show.S01E01", "3_French.srt"),
+		filepath.Join("Subs", "Show.S01E02", "2_English.srt"),
+	}
+	for _, name := range files {
+		path := filepath.Join(srcDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	NewRenamer(false).MoveCompanions(filepath.Join(srcDir, "Show.S01E01.mkv"), destDir, "Show - S01E01 - Pilot.mkv")
+
+	for _, name := range []string{
+		"Show - S01E01 - Pilot.nfo",
+		"Show - S01E01 - Pilot-thumb.jpg",
+		"Show - S01E01 - Pilot.mka",
+		"Show - S01E01 - Pilot.eng.srt",
+		"Show - S01E01 - Pilot.eng.2.srt",
+		"Show - S01E01 - Pilot.fre.srt",
+	} {
+		if _, err := os.Stat(filepath.Join(destDir, name)); err != nil {
+			t.Errorf("expected %s to be moved: %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"Show.S01E02.nfo", "Show.S01E01.txt", filepath.Join("Subs", "Show.S01E02", "2_English.srt")} {
+		if _, err := os.Stat(filepath.Join(srcDir, name)); err != nil {
+			t.Errorf("%s should have been left in place: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(srcDir, "Subs", "Show.S01E01")); !os.IsNotExist(err) {
+		t.Error("emptied per-episode subtitle folder should be removed")
+	}
+}
+
+func TestMoveCompanionsPairsAndAudio(t *testing.T) {
+	srcDir := t.TempDir()
+	destDir := t.TempDir()
+
+	for _, name := range []string{
+		"Movie.mkv",
+		"Movie.en.idx", "Movie.en.sub",
+		"Movie.english.idx", "Movie.english.sub",
+		"Movie.en.sup",
+		"Movie.fr.ac3",
+		"Movie.dts",
+	} {
+		if err := os.WriteFile(filepath.Join(srcDir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	NewRenamer(false).MoveCompanions(filepath.Join(srcDir, "Movie.mkv"), destDir, "Title (2020).mkv")
+
+	for _, name := range []string{
+		"Title (2020).eng.idx", "Title (2020).eng.sub",
+		"Title (2020).eng.2.idx", "Title (2020).eng.2.sub",
+		"Title (2020).eng.sup",
+		"Title (2020).fre.ac3",
+		"Title (2020).dts",
+	} {
+		if _, err := os.Stat(filepath.Join(destDir, name)); err != nil {
+			t.Errorf("expected %s to be moved: %v", name, err)
+		}
+	}
+}
+
+func TestMoveCompanionsKeepsPairTogether(t *testing.T) {
+	srcDir := t.TempDir()
+	destDir := t.TempDir()
+
+	for _, name := range []string{"Movie.mkv", "Movie.idx", "Movie.sub"} {
+		if err := os.WriteFile(filepath.Join(srcDir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The .sub half cannot be moved: the .idx half must stay with it
+	if err := os.WriteFile(filepath.Join(destDir, "Title (2020).sub"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	NewRenamer(false).MoveCompanions(filepath.Join(srcDir, "Movie.mkv"), destDir, "Title (2020).mkv")
+
+	if _, err := os.Stat(filepath.Join(srcDir, "Movie.idx")); err != nil {
+		t.Errorf("Movie.idx should not be split from its .sub: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "Title (2020).idx")); err == nil {
+		t.Error("Title (2020).idx should not exist")
+	}
+}
@@ -0,0 +1,97 @@
+package renamer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// rename renames a path; tests replace it to simulate moves between filesystems
+var rename = os.Rename
+
+// copyFile copies a regular file; tests replace it to make a copy fail partway
+var copyFile = copyRegularFile
+
+// movePath moves a file or folder. Across filesystems, where a rename is impossible, it is copied
+// and the original deleted once the copy is complete; a failed copy is removed and leaves the original
+// untouched.
+func movePath(oldPath, newPath string) error {
+	err := rename(oldPath, newPath)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if _, err := os.Lstat(newPath); err == nil {
+		return fmt.Errorf("destination already exists: %s", newPath)
+	}
+	if err := copyPath(oldPath, newPath); err != nil {
+		if removeErr := os.RemoveAll(newPath); removeErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to remove partial copy %s: %w", newPath, removeErr))
+		}
+		return fmt.Errorf("failed to copy %s to another filesystem: %w", oldPath, err)
+	}
+	if err := os.RemoveAll(oldPath); err != nil {
+		return fmt.Errorf("copied %s but failed to remove it: %w", oldPath, err)
+	}
+	return nil
+}
+
+// copyPath copies a file, symbolic link or folder tree
+func copyPath(oldPath, newPath string) error {
+	info, err := os.Lstat(oldPath)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(oldPath)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, newPath)
+
+	case info.IsDir():
+		if err := os.Mkdir(newPath, info.Mode().Perm()); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(oldPath)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := copyPath(filepath.Join(oldPath, entry.Name()), filepath.Join(newPath, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return os.Chtimes(newPath, info.ModTime(), info.ModTime())
+
+	default:
+		return copyFile(oldPath, newPath, info)
+	}
+}
+
+// copyRegularFile copies the contents, permissions and modification time of a file
+func copyRegularFile(oldPath, newPath string, info os.FileInfo) error {
+	src, err := os.Open(oldPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(newPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(newPath, info.ModTime(), info.ModTime())
+}
@@ -0,0 +1,134 @@
+package renamer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// crossFilesystem makes every rename fail as it does between filesystems until the test ends
+func crossFilesystem(t *testing.T) {
+	t.Helper()
+	saved := rename
+	rename = func(oldPath, newPath string) error {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: syscall.EXDEV}
+	}
+	t.Cleanup(func() { rename = saved })
+}
+
+func TestMoveAcrossFilesystems(t *testing.T) {
+	crossFilesystem(t)
+	dir := t.TempDir()
+	oldDir := filepath.Join(dir, "Movie.2010")
+	if err := os.MkdirAll(filepath.Join(oldDir, "Subs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{"movie.mkv": "video", "Subs/movie.en.srt": "subtitle"} {
+		if err := os.WriteFile(filepath.Join(oldDir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	newDir := filepath.Join(dir, "library", "Movie (2010)")
+	if err := os.MkdirAll(filepath.Dir(newDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewRenamer(false).Move(oldDir, newDir); err != nil {
+		t.Fatalf("Move() error: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(newDir, "Subs", "movie.en.srt")); err != nil || string(data) != "subtitle" {
+		t.Errorf("copied subtitle reads %q, %v", data, err)
+	}
+	if _, err := os.Stat(oldDir); !os.IsNotExist(err) {
+		t.Errorf("original folder still exists: %v", err)
+	}
+}
+
+func TestMoveAcrossFilesystemsFailingPartway(t *testing.T) {
+	crossFilesystem(t)
+	savedCopy := copyFile
+	copyFile = func(oldPath, newPath string, info os.FileInfo) error {
+		if filepath.Base(oldPath) == "movie.srt" {
+			return errors.New("no space left on device")
+		}
+		return savedCopy(oldPath, newPath, info)
+	}
+	t.Cleanup(func() { copyFile = savedCopy })
+
+	dir := t.TempDir()
+	oldDir := filepath.Join(dir, "Movie.2010")
+	if err := os.MkdirAll(oldDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"movie.mkv", "movie.srt"} {
+		if err := os.WriteFile(filepath.Join(oldDir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	newDir := filepath.Join(dir, "Movie (2010)")
+	if err := NewRenamer(false).Move(oldDir, newDir); err == nil {
+		t.Fatal("Move() succeeded, want the copy error")
+	}
+	if _, err := os.Stat(newDir); !os.IsNotExist(err) {
+		t.Errorf("partial copy left behind: %v", err)
+	}
+	for _, name := range []string{"movie.mkv", "movie.srt"} {
+		if _, err := os.Stat(filepath.Join(oldDir, name)); err != nil {
+			t.Errorf("original %s lost: %v", name, err)
+		}
+	}
+}
+
+func TestMoveMoviePartsRollsBack(t *testing.T) {
+	dir := t.TempDir()
+	oldPaths := []string{filepath.Join(dir, "Movie.CD1.avi"), filepath.Join(dir, "Movie.CD2.avi")}
+	for _, path := range append(oldPaths, filepath.Join(dir, "Movie.CD1.srt")) {
+		if err := os.WriteFile(path, []byte(filepath.Base(path)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	saved := rename
+	rename = func(oldPath, newPath string) error {
+		if filepath.Base(oldPath) == "Movie.CD2.avi" {
+			return errors.New("permission denied")
+		}
+		return saved(oldPath, newPath)
+	}
+	t.Cleanup(func() { rename = saved })
+
+	movieDir := filepath.Join(dir, "Movie (2010)")
+	newPaths := []string{filepath.Join(movieDir, "Movie (2010)-cd1.avi"), filepath.Join(movieDir, "Movie (2010)-cd2.avi")}
+	if err := NewRenamer(false).MoveMovieParts(oldPaths, newPaths); err == nil {
+		t.Fatal("MoveMovieParts() succeeded, want the move error")
+	}
+	for _, name := range []string{"Movie.CD1.avi", "Movie.CD2.avi", "Movie.CD1.srt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s not moved back: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(newPaths[0]); !os.IsNotExist(err) {
+		t.Errorf("first part left in the movie folder: %v", err)
+	}
+}
+
+func TestMoveMoviePartsChecksEveryDestination(t *testing.T) {
+	dir := t.TempDir()
+	oldPaths := []string{filepath.Join(dir, "Movie.CD1.avi"), filepath.Join(dir, "Movie.CD2.avi")}
+	newPaths := []string{filepath.Join(dir, "Movie (2010)-cd1.avi"), filepath.Join(dir, "Movie (2010)-cd2.avi")}
+	for _, path := range append(oldPaths, newPaths[1]) {
+		if err := os.WriteFile(path, []byte(filepath.Base(path)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := NewRenamer(false).MoveMovieParts(oldPaths, newPaths); err == nil {
+		t.Fatal("MoveMovieParts() succeeded, want the collision error")
+	}
+	if _, err := os.Stat(oldPaths[0]); err != nil {
+		t.Errorf("first part moved before the collision was detected: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package renamer
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,13 +10,15 @@ import (
 
 // Renamer handles file renaming operations with optional dry-run mode
 type Renamer struct {
-	dryRun bool
+	dryRun     bool
+	linkPolicy LinkPolicy
 }
 
 // NewRenamer creates a new Renamer instance with the specified dry-run mode
 func NewRenamer(dryRun bool) *Renamer {
 	return &Renamer{
-		dryRun: dryRun,
+		dryRun:     dryRun,
+		linkPolicy: LinkPolicyMoveLink,
 	}
 }
 
@@ -59,7 +62,7 @@ func (r *Renamer) renameFileWithOutput(oldPath, newFilename string, silent bool)
 		return nil
 	}
 
-	if err := os.Rename(oldPath, newPath); err != nil {
+	if err := r.Move(oldPath, newPath); err != nil {
 		return fmt.Errorf("failed to rename file: %w", err)
 	}
 
@@ -101,7 +104,7 @@ func (r *Renamer) RenameSeriesFolder(oldDirPath, newFolderName string) (string,
 		return newDirPath, nil
 	}
 
-	if err := os.Rename(oldDirPath, newDirPath); err != nil {
+	if err := r.Move(oldDirPath, newDirPath); err != nil {
 		return "", fmt.Errorf("failed to rename folder: %w", err)
 	}
 
@@ -136,7 +139,7 @@ func (r *Renamer) RenameFileInSeriesFolder(oldFilePath, newFolderName, newFileNa
 		return nil
 	}
 
-	if err := os.Rename(oldFileInNewDir, newFilePath); err != nil {
+	if err := r.Move(oldFileInNewDir, newFilePath); err != nil {
 		return fmt.Errorf("failed to rename file: %w", err)
 	}
 
@@ -165,7 +168,7 @@ func (r *Renamer) MoveSeriesFolder(oldDirPath, newDirPath string) error {
 		return nil
 	}
 
-	if err := os.Rename(oldDirPath, newDirPath); err != nil {
+	if err := r.Move(oldDirPath, newDirPath); err != nil {
 		return fmt.Errorf("failed to move folder: %w", err)
 	}
 
@@ -174,13 +177,8 @@ func (r *Renamer) MoveSeriesFolder(oldDirPath, newDirPath string) error {
 }
 
 // MoveRenameMovieFile moves and renames a standalone movie file to a target directory with a new folder and filename
-// This creates: outputDir/folderName/newFilename (and moves accompanying subtitles)
+// This creates: outputDir/folderName/newFilename (and moves accompanying companion files)
 func (r *Renamer) MoveRenameMovieFile(oldPath, outputDir, folderName, newFilename string) error {
-	oldDir := filepath.Dir(oldPath)
-	oldFilename := filepath.Base(oldPath)
-	oldNameWithoutExt := strings.TrimSuffix(oldFilename, filepath.Ext(oldFilename))
-	newNameWithoutExt := strings.TrimSuffix(newFilename, filepath.Ext(newFilename))
-
 	// Create movie folder path
 	movieFolderPath := filepath.Join(outputDir, folderName)
 	newPath := filepath.Join(movieFolderPath, newFilename)
@@ -192,14 +190,7 @@ func (r *Renamer) MoveRenameMovieFile(oldPath, outputDir, folderName, newFilenam
 	if r.dryRun {
 		fmt.Printf("[DRY RUN] Would create folder: %s\n", movieFolderPath)
 		fmt.Printf("[DRY RUN] Would move movie:\n  FROM: %s\n  TO:   %s\n", oldPath, newPath)
-
-		// Check for subtitle files
-		subtitles, _ := findSubtitleFiles(oldDir, oldNameWithoutExt)
-		for _, subPath := range subtitles {
-			subExt := filepath.Ext(subPath)
-			newSubPath := filepath.Join(movieFolderPath, newNameWithoutExt+subExt)
-			fmt.Printf("[DRY RUN] Would move subtitle:\n  FROM: %s\n  TO:   %s\n", subPath, newSubPath)
-		}
+		r.MoveCompanions(oldPath, movieFolderPath, newFilename)
 		fmt.Println()
 		return nil
 	}
@@ -215,22 +206,13 @@ func (r *Renamer) MoveRenameMovieFile(oldPath, outputDir, folderName, newFilenam
 	}
 
 	// Move the video file
-	if err := os.Rename(oldPath, newPath); err != nil {
+	if err := r.Move(oldPath, newPath); err != nil {
 		return fmt.Errorf("failed to move movie file: %w", err)
 	}
 	fmt.Printf("Moved movie:\n  FROM: %s\n  TO:   %s\n", oldPath, newPath)
 
-	// Move accompanying subtitle files
-	subtitles, _ := findSubtitleFiles(oldDir, oldNameWithoutExt)
-	for _, subPath := range subtitles {
-		subExt := filepath.Ext(subPath)
-		newSubPath := filepath.Join(movieFolderPath, newNameWithoutExt+subExt)
-		if err := os.Rename(subPath, newSubPath); err != nil {
-			fmt.Printf("Warning: failed to move subtitle %s: %v\n", subPath, err)
-		} else {
-			fmt.Printf("Moved subtitle:\n  FROM: %s\n  TO:   %s\n", subPath, newSubPath)
-		}
-	}
+	// Move accompanying subtitles, audio tracks and metadata, keeping their language and flags
+	r.MoveCompanions(oldPath, movieFolderPath, newFilename)
 	fmt.Println()
 	return nil
 }
@@ -258,7 +240,7 @@ func (r *Renamer) MoveRenameMovieFolder(oldDirPath, outputDir, newFolderName str
 		return nil
 	}
 
-	if err := os.Rename(oldDirPath, newDirPath); err != nil {
+	if err := r.Move(oldDirPath, newDirPath); err != nil {
 		return fmt.Errorf("failed to move movie folder: %w", err)
 	}
 
@@ -266,14 +248,11 @@ func (r *Renamer) MoveRenameMovieFolder(oldDirPath, outputDir, newFolderName str
 	return nil
 }
 
-// RenameMovieFileInFolder renames the main video file inside a movie folder (and accompanying subtitles)
+// RenameMovieFileInFolder renames the main video file inside a movie folder (and its companion files)
 func (r *Renamer) RenameMovieFileInFolder(folderPath, oldFilename, newFilename string) error {
 	oldPath := filepath.Join(folderPath, oldFilename)
 	newPath := filepath.Join(folderPath, newFilename)
 
-	oldNameWithoutExt := strings.TrimSuffix(oldFilename, filepath.Ext(oldFilename))
-	newNameWithoutExt := strings.TrimSuffix(newFilename, filepath.Ext(newFilename))
-
 	if oldPath == newPath {
 		return nil
 	}
@@ -284,68 +263,137 @@ func (r *Renamer) RenameMovieFileInFolder(folderPath, oldFilename, newFilename s
 
 	if r.dryRun {
 		fmt.Printf("[DRY RUN] Would rename movie file:\n  FROM: %s\n  TO:   %s\n", oldPath, newPath)
-
-		// Check for subtitle files
-		subtitles, _ := findSubtitleFiles(folderPath, oldNameWithoutExt)
-		for _, subPath := range subtitles {
-			subExt := filepath.Ext(subPath)
-			newSubPath := filepath.Join(folderPath, newNameWithoutExt+subExt)
-			fmt.Printf("[DRY RUN] Would rename subtitle:\n  FROM: %s\n  TO:   %s\n", subPath, newSubPath)
-		}
+		r.MoveCompanions(oldPath, folderPath, newFilename)
 		fmt.Println()
 		return nil
 	}
 
 	// Rename the video file
-	if err := os.Rename(oldPath, newPath); err != nil {
+	if err := r.Move(oldPath, newPath); err != nil {
 		return fmt.Errorf("failed to rename movie file: %w", err)
 	}
 	fmt.Printf("Renamed movie file:\n  FROM: %s\n  TO:   %s\n", oldPath, newPath)
 
-	// Rename accompanying subtitle files
-	subtitles, _ := findSubtitleFiles(folderPath, oldNameWithoutExt)
-	for _, subPath := range subtitles {
-		subExt := filepath.Ext(subPath)
-		newSubPath := filepath.Join(folderPath, newNameWithoutExt+subExt)
-		if err := os.Rename(subPath, newSubPath); err != nil {
-			fmt.Printf("Warning: failed to rename subtitle %s: %v\n", subPath, err)
-		} else {
-			fmt.Printf("Renamed subtitle:\n  FROM: %s\n  TO:   %s\n", subPath, newSubPath)
-		}
-	}
+	// Rename accompanying subtitles, audio tracks and metadata, keeping their language and flags
+	r.MoveCompanions(oldPath, folderPath, newFilename)
 	fmt.Println()
 	return nil
 }
 
-// findSubtitleFiles finds all subtitle files matching the video filename
-func findSubtitleFiles(dir, videoNameWithoutExt string) ([]string, error) {
-	var subtitles []string
-	subtitleExts := []string{".srt", ".sub", ".ass", ".ssa", ".vtt"}
-
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return nil, err
+// MoveMovieParts moves the parts of a stacked movie (and their companion files) all or nothing: every
+// destination is checked before anything moves, and if a move fails the parts already moved are put back
+func (r *Renamer) MoveMovieParts(oldPaths, newPaths []string) error {
+	if len(oldPaths) != len(newPaths) {
+		return fmt.Errorf("got %d part(s) but %d destination(s)", len(oldPaths), len(newPaths))
 	}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
+	for i, newPath := range newPaths {
+		if oldPaths[i] == newPath {
 			continue
 		}
+		if _, err := os.Stat(newPath); err == nil {
+			return fmt.Errorf("file already exists: %s", newPath)
+		}
+	}
 
-		name := entry.Name()
-		nameWithoutExt := strings.TrimSuffix(name, filepath.Ext(name))
-		ext := strings.ToLower(filepath.Ext(name))
+	if r.dryRun {
+		for i, newPath := range newPaths {
+			fmt.Printf("[DRY RUN] Would move movie part:\n  FROM: %s\n  TO:   %s\n", oldPaths[i], newPath)
+			r.MoveCompanions(oldPaths[i], filepath.Dir(newPath), filepath.Base(newPath))
+		}
+		fmt.Println()
+		return nil
+	}
+
+	for i, newPath := range newPaths {
+		oldPath := oldPaths[i]
+		if oldPath == newPath {
+			continue
+		}
 
-		// Check if it's a subtitle file with matching name
-		for _, subExt := range subtitleExts {
-			if ext == subExt && strings.HasPrefix(nameWithoutExt, videoNameWithoutExt) {
-				subtitles = append(subtitles, filepath.Join(dir, name))
-				break
+		err := os.MkdirAll(filepath.Dir(newPath), 0755)
+		if err == nil {
+			err = r.Move(oldPath, newPath)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to move movie part %s: %w", oldPath, err)
+			// Put the parts already moved back where they were, with their companions
+			for j := range i {
+				if oldPaths[j] == newPaths[j] {
+					continue
+				}
+				if rollbackErr := r.Move(newPaths[j], oldPaths[j]); rollbackErr != nil {
+					err = errors.Join(err, fmt.Errorf("failed to move back movie part %s: %w", oldPaths[j], rollbackErr))
+					continue
+				}
+				r.MoveCompanions(newPaths[j], filepath.Dir(oldPaths[j]), filepath.Base(oldPaths[j]))
 			}
+			return err
 		}
+
+		fmt.Printf("Moved movie part:\n  FROM: %s\n  TO:   %s\n", oldPath, newPath)
+		r.MoveCompanions(oldPath, filepath.Dir(newPath), filepath.Base(newPath))
+	}
+	fmt.Println()
+	return nil
+}
+
+// MoveFileToFolder moves a file into destDir under newFilename, creating destDir if needed
+func (r *Renamer) MoveFileToFolder(oldPath, destDir, newFilename string) error {
+	newPath := filepath.Join(destDir, newFilename)
+
+	if oldPath == newPath {
+		return nil
+	}
+
+	if _, err := os.Stat(newPath); err == nil {
+		return fmt.Errorf("file already exists: %s", newPath)
+	}
+
+	if r.dryRun {
+		fmt.Printf("[DRY RUN] Would move file:\n  FROM: %s\n  TO:   %s\n", oldPath, newPath)
+		return nil
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if err := r.Move(oldPath, newPath); err != nil {
+		return fmt.Errorf("failed to move file: %w", err)
+	}
+
+	fmt.Printf("Moved file:\n  FROM: %s\n  TO:   %s\n", oldPath, newPath)
+	return nil
+}
+
+// RemoveFile deletes a file (used for unwanted content such as release samples)
+func (r *Renamer) RemoveFile(path string) error {
+	if r.dryRun {
+		fmt.Printf("[DRY RUN] Would delete file: %s\n", path)
+		return nil
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	fmt.Printf("Deleted file: %s\n", path)
+	return nil
+}
+
+// RemoveEmptyDir removes a directory only if it is empty, ignoring non-empty or missing directories
+func (r *Renamer) RemoveEmptyDir(dirPath string) {
+	if r.dryRun {
+		return
+	}
+
+	entries, err := os.ReadDir(dirPath)
+	if err != nil || len(entries) > 0 {
+		return
 	}
 
-	return subtitles, nil
+	_ = os.Remove(dirPath)
 }
 
 // validateFilename checks if a filename is valid and safe to use
@@ -0,0 +1,126 @@
+package renamer
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// subtitleExtensions lists the subtitle formats moved along with their video file
+// (.idx/.sub are VobSub pairs, .sup is PGS)
+var subtitleExtensions = []string{".srt", ".sub", ".idx", ".sup", ".ass", ".ssa", ".vtt"}
+
+// languageCodes maps common language names and release tags to the ISO 639-2/B code Kodi expects in
+// subtitle filenames; other ISO 639 codes are normalized by normalizeLanguageCode
+var languageCodes = map[string]string{
+	"en": "eng", "english": "eng",
+	"fr": "fre", "fra": "fre", "french": "fre", "francais": "fre", "français": "fre", "vf": "fre", "vff": "fre", "vfq": "fre",
+	"de": "ger", "deu": "ger", "german": "ger", "deutsch": "ger",
+	"es": "spa", "spanish": "spa", "espanol": "spa", "español": "spa", "castellano": "spa",
+	"it": "ita", "italian": "ita", "italiano": "ita",
+	"pt": "por", "portuguese": "por", "portugues": "por", "português": "por",
+	"nl": "dut", "nld": "dut", "dutch": "dut", "nederlands": "dut",
+	"sv": "swe", "swedish": "swe", "svenska": "swe",
+	"no": "nor", "nb": "nor", "nob": "nor", "norwegian": "nor", "norsk": "nor",
+	"da": "dan", "danish": "dan", "dansk": "dan",
+	"fi": "fin", "finnish": "fin", "suomi": "fin",
+	"pl": "pol", "polish": "pol", "polski": "pol",
+	"cs": "cze", "ces": "cze", "czech": "cze",
+	"hu": "hun", "hungarian": "hun", "magyar": "hun",
+	"ro": "rum", "ron": "rum", "romanian": "rum",
+	"el": "gre", "ell": "gre", "greek": "gre",
+	"tr": "tur", "turkish": "tur",
+	"ru": "rus", "russian": "rus",
+	"uk": "ukr", "ukrainian": "ukr",
+	"ar": "ara", "arabic": "ara",
+	"he": "heb", "hebrew": "heb",
+	"hindi": "hin", "hin": "hin",
+	"ja": "jpn", "japanese": "jpn",
+	"ko": "kor", "korean": "kor",
+	"zh": "chi", "zho": "chi", "chinese": "chi",
+	"th": "tha", "thai": "tha",
+	"vi": "vie", "vietnamese": "vie",
+	"id": "ind", "indonesian": "ind",
+}
+
+// SubtitleTags holds the language and flags encoded in a subtitle filename suffix
+type SubtitleTags struct {
+	Language string // ISO 639-2/B language code, empty if unknown
+	Default  bool   // Default subtitle track
+	Forced   bool   // Forced subtitles (foreign dialogue only)
+	SDH      bool   // Subtitles for the deaf and hard of hearing (also "cc", and "hi" after the language)
+}
+
+// ParseSubtitleTags extracts the language and flags from the part of a subtitle filename that
+// follows the video name, e.g. ".en.forced" or "_English_SDH"; unknown tokens are ignored
+func ParseSubtitleTags(suffix string) SubtitleTags {
+	var tags SubtitleTags
+
+	tokens := strings.FieldsFunc(strings.ToLower(suffix), func(r rune) bool {
+		return r == '.' || r == '_' || r == '-' || r == ' ' || r == '[' || r == ']' || r == '(' || r == ')'
+	})
+
+	for _, token := range tokens {
+		switch token {
+		case "forced", "foreign":
+			tags.Forced = true
+		case "sdh", "cc":
+			tags.SDH = true
+		case "default":
+			tags.Default = true
+		default:
+			if tags.Language != "" {
+				// "hi" is Hindi as a language, but hearing impaired once the language is known
+				if token == "hi" {
+					tags.SDH = true
+				}
+				continue
+			}
+			if code, ok := languageCodes[token]; ok {
+				tags.Language = code
+			} else if code, ok := normalizeLanguageCode(token); ok {
+				tags.Language = code
+			}
+		}
+	}
+
+	return tags
+}
+
+// Suffix returns the normalized Kodi suffix for the tags, e.g. ".eng.forced"
+func (t SubtitleTags) Suffix() string {
+	var parts []string
+	if t.Language != "" {
+		parts = append(parts, t.Language)
+	}
+	if t.Default {
+		parts = append(parts, "default")
+	}
+	if t.Forced {
+		parts = append(parts, "forced")
+	}
+	if t.SDH {
+		parts = append(parts, "sdh")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "." + strings.Join(parts, ".")
+}
+
+// SubtitleFilename builds the Kodi subtitle (or external audio track) filename for a video, keeping
+// its language and flags: "Title (Year)" + "Movie.en.forced.srt" gives "Title (Year).eng.forced.srt"
+func SubtitleFilename(newNameWithoutExt, videoNameWithoutExt, subtitleFilename string) string {
+	ext := filepath.Ext(subtitleFilename)
+	suffix := strings.TrimPrefix(strings.TrimSuffix(subtitleFilename, ext), videoNameWithoutExt)
+	return newNameWithoutExt + ParseSubtitleTags(suffix).Suffix() + strings.ToLower(ext)
+}
+
+// isSubtitleExtension checks if the lower-case extension is a supported subtitle format
+func isSubtitleExtension(ext string) bool {
+	for _, subExt := range subtitleExtensions {
+		if ext == subExt {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package renamer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSubtitleFilename(t *testing.T) {
+	tests := []struct {
+		subtitle string
+		want     string
+	}{
+		{"Movie.srt", "Title (2020).srt"},
+		{"Movie.en.srt", "Title (2020).eng.srt"},
+		{"Movie.en.forced.srt", "Title (2020).eng.forced.srt"},
+		{"Movie.English.SDH.srt", "Title (2020).eng.sdh.srt"},
+		{"Movie_fr_cc.SRT", "Title (2020).fre.sdh.srt"},
+		{"Movie.fra.default.forced.ass", "Title (2020).fre.default.forced.ass"},
+		{"Movie.ger.hi.srt", "Title (2020).ger.sdh.srt"},
+		{"Movie.YIFY.srt", "Title (2020).srt"},
+		{"Movie.hr.srt", "Title (2020).hrv.srt"},
+		{"Movie.srp.forced.srt", "Title (2020).srp.forced.srt"},
+		{"Movie.fa.srt", "Title (2020).per.srt"},
+		{"Movie.isl.srt", "Title (2020).ice.srt"},
+		{"Movie.hi.srt", "Title (2020).hin.srt"},
+		{"Movie.hi.sdh.srt", "Title (2020).hin.sdh.srt"},
+		{"Movie.en.hi.srt", "Title (2020).eng.sdh.srt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.subtitle, func(t *testing.T) {
+			if got := SubtitleFilename("Title (2020)", "Movie", tt.subtitle); got != tt.want {
+				t.Errorf("SubtitleFilename(%q) = %q, want %q", tt.subtitle, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoveCompanionSubtitles(t *testing.T) {
+	srcDir := t.TempDir()
+	destDir := t.TempDir()
+
+	for _, name := range []string{"Movie.mkv", "Movie.en.srt", "Movie.fr.srt", "Movie.en.forced.srt", "Movie.srt", "Movie.YIFY.srt", "Movie 2.srt"} {
+		if err := os.WriteFile(filepath.Join(srcDir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	NewRenamer(false).MoveCompanions(filepath.Join(srcDir, "Movie.mkv"), destDir, "Title (2020).mkv")
+
+	for _, name := range []string{"Title (2020).eng.srt", "Title (2020).fre.srt", "Title (2020).eng.forced.srt", "Title (2020).srt", "Title (2020).2.srt"} {
+		if _, err := os.Stat(filepath.Join(destDir, name)); err != nil {
+			t.Errorf("expected %s to be moved: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(srcDir, "Movie 2.srt")); err != nil {
+		t.Errorf("subtitle of another video should not be moved: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package renamer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LinkPolicy decides what is moved when the file or folder to rename is a symbolic link
+type LinkPolicy string
+
+const (
+	// LinkPolicyMoveLink moves the link itself and leaves its target in place (the default)
+	LinkPolicyMoveLink LinkPolicy = "link"
+	// LinkPolicyMoveTarget moves the target to the new location and removes the link
+	LinkPolicyMoveTarget LinkPolicy = "target"
+)
+
+// ParseLinkPolicy validates a link policy name
+func ParseLinkPolicy(name string) (LinkPolicy, error) {
+	switch policy := LinkPolicy(name); policy {
+	case LinkPolicyMoveLink, LinkPolicyMoveTarget:
+		return policy, nil
+	case "":
+		return LinkPolicyMoveLink, nil
+	default:
+		return "", fmt.Errorf("unknown symlink policy %q (expected %q or %q)", name, LinkPolicyMoveLink, LinkPolicyMoveTarget)
+	}
+}
+
+// SetLinkPolicy sets what is moved when renaming a symbolic link
+func (r *Renamer) SetLinkPolicy(policy LinkPolicy) {
+	r.linkPolicy = policy
+}
+
+// Move moves a file or folder to newPath, applying the link policy when oldPath is a symbolic link;
+// moves to another filesystem copy then delete
+func (r *Renamer) Move(oldPath, newPath string) error {
+	info, err := os.Lstat(oldPath)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return movePath(oldPath, newPath)
+	}
+
+	if r.linkPolicy == LinkPolicyMoveTarget {
+		target, err := filepath.EvalSymlinks(oldPath)
+		if err != nil {
+			return fmt.Errorf("dangling symlink %s: %w", oldPath, err)
+		}
+		if err := movePath(target, newPath); err != nil {
+			return err
+		}
+		return os.Remove(oldPath)
+	}
+
+	return moveLink(oldPath, newPath)
+}
+
+// moveLink moves a symbolic link, rewriting a relative target so it still points to the same file
+// from the new directory
+func moveLink(oldPath, newPath string) error {
+	target, err := os.Readlink(oldPath)
+	if err != nil {
+		return err
+	}
+	if filepath.IsAbs(target) || filepath.Dir(oldPath) == filepath.Dir(newPath) {
+		return movePath(oldPath, newPath)
+	}
+
+	absTarget := filepath.Join(filepath.Dir(oldPath), target)
+	newTarget, err := filepath.Rel(filepath.Dir(newPath), absTarget)
+	if err != nil {
+		newTarget = absTarget
+	}
+	if err := os.Symlink(newTarget, newPath); err != nil {
+		return err
+	}
+	return os.Remove(oldPath)
+}
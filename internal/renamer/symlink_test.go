@@ -0,0 +1,96 @@
+//go:build unix
+
+package renamer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveSymlinkPolicies(t *testing.T) {
+	dir := t.TempDir()
+	downloads := filepath.Join(dir, "downloads")
+	inbox := filepath.Join(dir, "inbox")
+	library := filepath.Join(dir, "library")
+	for _, d := range []string{downloads, inbox, library} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	target := filepath.Join(downloads, "movie.mkv")
+	if err := os.WriteFile(target, []byte("video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Moving the link keeps a relative target pointing to the same file
+	link := filepath.Join(inbox, "movie.mkv")
+	if err := os.Symlink(filepath.Join("..", "downloads", "movie.mkv"), link); err != nil {
+		t.Fatal(err)
+	}
+	r := NewRenamer(false)
+	moved := filepath.Join(library, "Movie (2010).mkv")
+	if err := r.Move(link, moved); err != nil {
+		t.Fatalf("Move() error: %v", err)
+	}
+	if data, err := os.ReadFile(moved); err != nil || string(data) != "video" {
+		t.Errorf("moved link reads %q, %v", data, err)
+	}
+	if _, err := os.Lstat(link); !os.IsNotExist(err) {
+		t.Errorf("old link still exists")
+	}
+
+	// Moving the target removes the link and leaves a regular file
+	r.SetLinkPolicy(LinkPolicyMoveTarget)
+	final := filepath.Join(library, "Movie (2010) final.mkv")
+	if err := r.Move(moved, final); err != nil {
+		t.Fatalf("Move() error: %v", err)
+	}
+	info, err := os.Lstat(final)
+	if err != nil || info.Mode()&os.ModeSymlink != 0 {
+		t.Errorf("moved target is not a regular file: %v, %v", info, err)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("target still in downloads")
+	}
+	if _, err := os.Lstat(moved); !os.IsNotExist(err) {
+		t.Errorf("link not removed")
+	}
+}
+
+func TestMoveTargetAcrossFilesystems(t *testing.T) {
+	crossFilesystem(t)
+	dir := t.TempDir()
+	target := filepath.Join(dir, "movie.mkv")
+	if err := os.WriteFile(target, []byte("video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.mkv")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRenamer(false)
+	r.SetLinkPolicy(LinkPolicyMoveTarget)
+	moved := filepath.Join(dir, "Movie (2010).mkv")
+	if err := r.Move(link, moved); err != nil {
+		t.Fatalf("Move() error: %v", err)
+	}
+	if info, err := os.Lstat(moved); err != nil || !info.Mode().IsRegular() {
+		t.Fatalf("moved target = %v, %v, want a regular file", info, err)
+	}
+	for _, old := range []string{target, link} {
+		if _, err := os.Lstat(old); !os.IsNotExist(err) {
+			t.Errorf("%s still exists: %v", old, err)
+		}
+	}
+}
+
+func TestParseLinkPolicy(t *testing.T) {
+	if policy, err := ParseLinkPolicy(""); err != nil || policy != LinkPolicyMoveLink {
+		t.Errorf("ParseLinkPolicy(\"\") = %q, %v", policy, err)
+	}
+	if _, err := ParseLinkPolicy("copy"); err == nil {
+		t.Error("ParseLinkPolicy(\"copy\") accepted")
+	}
+}
@@ -0,0 +1,29 @@
+package scanner
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// airDatePattern matches the broadcast date of daily shows named by date instead of season and
+// episode, like "The.Daily.Show.2024.03.14" or "Show 2024-03-14"
+var airDatePattern = regexp.MustCompile(`(?:^|[\s._\-\[(])((?:19|20)\d{2})[.\-_ ](\d{2})[.\-_ ](\d{2})(?:[\s._\-\])]|$)`)
+
+// extractAirDate returns the broadcast date in a filename as "YYYY-MM-DD", or "" when there is none
+func extractAirDate(name string) string {
+	matches := airDatePattern.FindStringSubmatch(name)
+	if matches == nil {
+		return ""
+	}
+	date := fmt.Sprintf("%s-%s-%s", matches[1], matches[2], matches[3])
+	if _, err := time.Parse(time.DateOnly, date); err != nil {
+		return ""
+	}
+	return date
+}
+
+// removeAirDate removes the broadcast date from a filename
+func removeAirDate(name string) string {
+	return airDatePattern.ReplaceAllString(name, " ")
+}
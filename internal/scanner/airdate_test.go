@@ -0,0 +1,44 @@
+package scanner
+
+import "testing"
+
+func TestExtractAirDate(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"The.Daily.Show.2024.03.14.720p.WEB.h264", "2024-03-14"},
+		{"Last Week Tonight 2023-11-05", "2023-11-05"},
+		{"Jeopardy_2019_12_31", "2019-12-31"},
+		{"Show [2024.03.14]", "2024-03-14"},
+		{"Show.2024.13.14", ""},
+		{"Show.2024.02.30", ""},
+		{"The.Matrix.1999.1080p", ""},
+		{"Blade.Runner.2049.2017", ""},
+	}
+	for _, tt := range tests {
+		if got := extractAirDate(tt.name); got != tt.want {
+			t.Errorf("extractAirDate(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyDailyEpisode(t *testing.T) {
+	s := NewScanner(t.TempDir())
+	var file MediaFile
+	s.classify(&file, "The.Daily.Show.2024.03.14.720p.WEB")
+
+	if !file.IsSeries || file.AirDate != "2024-03-14" || file.Season != 0 || file.Episode != 0 {
+		t.Errorf("classify() = %+v, want a daily episode of 2024-03-14", file)
+	}
+	if file.CleanName != "The Daily Show WEB" {
+		t.Errorf("CleanName = %q", file.CleanName)
+	}
+
+	// Season and episode numbers win over a date
+	file = MediaFile{}
+	s.classify(&file, "Show.S02E05.2024.03.14")
+	if file.Season != 2 || file.Episode != 5 || file.AirDate != "" {
+		t.Errorf("classify() = %+v, want S02E05 without air date", file)
+	}
+}
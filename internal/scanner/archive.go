@@ -0,0 +1,59 @@
+package scanner
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"kodi-renamer/internal/archive"
+)
+
+// SetScanArchives enables or disables listing the videos inside RAR and ZIP archive sets
+func (s *Scanner) SetScanArchives(enabled bool) {
+	s.scanArchives = enabled
+}
+
+// archivedMediaFiles lists the archive sets among the volume names of a directory and returns
+// the videos they contain as media files; samples and other extras inside archives are left out
+func (s *Scanner) archivedMediaFiles(dir string, volumeNames []string) []MediaFile {
+	var mediaFiles []MediaFile
+
+	for _, set := range archive.FindSets(dir, volumeNames) {
+		entries, err := set.List()
+		if err != nil {
+			s.addSkipped(set.Volumes[0], fmt.Sprintf("unreadable archive: %v", err))
+			continue
+		}
+
+		for _, entry := range entries {
+			filename := path.Base(entry.Name)
+			ext := filepath.Ext(filename)
+			if !isVideoFile(strings.ToLower(ext)) {
+				continue
+			}
+			if classifyExtraByName(strings.TrimSuffix(filename, ext)) != ExtraNone {
+				continue
+			}
+
+			memberPath := set.Volumes[0] + "/" + entry.Name
+			if s.filter.minFileSize > 0 && entry.Size < s.filter.minFileSize {
+				s.addSkipped(memberPath, fmt.Sprintf("smaller than minimum size (%d < %d bytes)", entry.Size, s.filter.minFileSize))
+				continue
+			}
+
+			mediaFile := MediaFile{
+				Path:      set.Volumes[0],
+				Name:      filename,
+				Extension: ext,
+				ParentDir: filepath.Base(dir),
+				FileSize:  entry.Size,
+				Archive:   &archive.Member{Set: set, Entry: entry},
+			}
+			s.classify(&mediaFile, strings.TrimSuffix(filename, ext))
+			mediaFiles = append(mediaFiles, mediaFile)
+		}
+	}
+
+	return mediaFiles
+}
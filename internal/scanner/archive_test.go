@@ -0,0 +1,53 @@
+package scanner
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanArchivedVideos(t *testing.T) {
+	root := t.TempDir()
+	release := filepath.Join(root, "Movie.2010.1080p-GRP")
+	if err := os.MkdirAll(release, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Create(filepath.Join(release, "movie.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, name := range []string{"Movie.2010.1080p-GRP.mkv", "Movie.2010.1080p-GRP-sample.mkv", "movie.nfo"} {
+		w, _ := zw.Create(name)
+		w.Write([]byte("data"))
+	}
+	zw.Close()
+	f.Close()
+
+	s := NewScanner(root)
+	s.SetProbeFiles(false)
+	s.SetHashFiles(false)
+
+	files, err := s.ScanDirectory()
+	if err != nil {
+		t.Fatalf("ScanDirectory() error: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("got %d files: %+v", len(files), files)
+	}
+
+	file := files[0]
+	if file.Archive == nil || file.Archive.Entry.Name != "Movie.2010.1080p-GRP.mkv" || !file.Archive.Entry.Extractable {
+		t.Fatalf("Archive = %+v", file.Archive)
+	}
+	if file.Path != filepath.Join(release, "movie.zip") || !file.IsMovie || file.Year != 2010 || file.FileSize != 4 {
+		t.Errorf("file = %+v", file)
+	}
+
+	s.SetScanArchives(false)
+	if files, _ := s.ScanDirectory(); len(files) != 0 {
+		t.Errorf("archives scanned while disabled: %+v", files)
+	}
+}
@@ -0,0 +1,56 @@
+package scanner
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"kodi-renamer/internal/disc"
+)
+
+// genericNamePattern matches cleaned folder and file names that say nothing about the movie,
+// such as "BDMV", "VIDEO_TS", "Disc 1" or a bare number
+var genericNamePattern = regexp.MustCompile(`(?i)^(?:bdmv|video[\s_]?ts|dvd(?:[\s_]?video)?|blu[\s-]?ray|bd|dis[ck]|cd|movie|untitled|new folder|image)?[\s_]*\d*$`)
+
+// isGenericName reports whether a cleaned name is useless as a search query
+func isGenericName(cleanName string) bool {
+	return genericNamePattern.MatchString(strings.TrimSpace(cleanName))
+}
+
+// readDiscTitle returns the title found in the metadata of a Blu-ray or DVD folder, or in the
+// volume label of a disc image; it is empty when the disc has none
+func readDiscTitle(mediaFile *MediaFile, mainVideoFile string) string {
+	var info disc.Info
+	var err error
+
+	switch {
+	case mediaFile.IsBluRay:
+		info, err = disc.ReadBluray(mediaFile.Path)
+	case mediaFile.IsDVD:
+		info, err = disc.ReadDVD(mediaFile.Path)
+	}
+	if err == nil && info.Query() != "" {
+		return info.Query()
+	}
+
+	if strings.EqualFold(filepath.Ext(mainVideoFile), ".iso") {
+		if info, err := disc.ReadImage(mainVideoFile); err == nil {
+			return info.Query()
+		}
+	}
+	return ""
+}
+
+// applyDiscTitle reads the disc title of a disc folder or image and uses it as the search name
+// when the folder or file name is generic
+func (s *Scanner) applyDiscTitle(mediaFile *MediaFile, mainVideoFile string) {
+	mediaFile.DiscTitle = readDiscTitle(mediaFile, mainVideoFile)
+	if mediaFile.DiscTitle == "" || !mediaFile.IsMovie || !isGenericName(mediaFile.CleanName) {
+		return
+	}
+
+	mediaFile.CleanName = s.cleanMovieName(mediaFile.DiscTitle)
+	if mediaFile.Year == 0 {
+		mediaFile.Year = s.extractYear(mediaFile.DiscTitle)
+	}
+}
@@ -0,0 +1,53 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsGenericName(t *testing.T) {
+	for _, name := range []string{"", "BDMV", "VIDEO_TS", "Disc 1", "DVD", "12", "Untitled"} {
+		if !isGenericName(name) {
+			t.Errorf("isGenericName(%q) = false", name)
+		}
+	}
+	for _, name := range []string{"The Matrix", "Disco Inferno", "Inception"} {
+		if isGenericName(name) {
+			t.Errorf("isGenericName(%q) = true", name)
+		}
+	}
+}
+
+func TestBlurayFolderWithGenericName(t *testing.T) {
+	root := t.TempDir()
+	discDir := filepath.Join(root, "DISC1")
+	metaDir := filepath.Join(discDir, "BDMV", "META", "DL")
+	if err := os.MkdirAll(metaDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	index := make([]byte, 120)
+	copy(index, "INDX0200")
+	binary.BigEndian.PutUint32(index[8:], 78)
+	if err := os.WriteFile(filepath.Join(discDir, "BDMV", "index.bdmv"), index, 0644); err != nil {
+		t.Fatal(err)
+	}
+	bdmt := `<disclib xmlns:di="urn:BDA:bdmv;discinfo"><di:discinfo><di:title><di:name>Inception</di:name></di:title></di:discinfo></disclib>`
+	if err := os.WriteFile(filepath.Join(metaDir, "bdmt_eng.xml"), []byte(bdmt), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScanner(root)
+	s.SetProbeFiles(false)
+	s.SetHashFiles(false)
+
+	movie, ok := s.parseMovieFolder(discDir)
+	if !ok || !movie.IsBluRay {
+		t.Fatalf("parseMovieFolder() = %+v, %v", movie, ok)
+	}
+	if movie.DiscTitle != "Inception" || movie.CleanName != "Inception" {
+		t.Errorf("DiscTitle = %q, CleanName = %q", movie.DiscTitle, movie.CleanName)
+	}
+}
@@ -0,0 +1,152 @@
+package scanner
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"kodi-renamer/internal/probe"
+)
+
+// editionPattern associates a filename pattern with the normalized edition label it denotes
+type editionPattern struct {
+	pattern *regexp.Regexp
+	label   string
+}
+
+var (
+	// customEditionPattern matches explicit Plex/Kodi style edition tags such as {edition-Director's Cut}
+	customEditionPattern = regexp.MustCompile(`(?i)\{edition-([^}]+)\}`)
+
+	// editionPatterns lists well-known edition markers, in the order their labels are combined
+	editionPatterns = []editionPattern{
+		{regexp.MustCompile(`(?i)\bdirector'?s[\s._-]*cut\b`), "Director's Cut"},
+		{regexp.MustCompile(`(?i)\bfinal[\s._-]*cut\b`), "Final Cut"},
+		{regexp.MustCompile(`(?i)\bultimate[\s._-]*(?:cut|edition)\b`), "Ultimate Edition"},
+		{regexp.MustCompile(`(?i)\bextended(?:[\s._-]*(?:cut|edition|version))?\b`), "Extended"},
+		{regexp.MustCompile(`(?i)\btheatrical(?:[\s._-]*(?:cut|edition|version))?\b`), "Theatrical"},
+		{regexp.MustCompile(`(?i)\bunrated\b`), "Unrated"},
+		{regexp.MustCompile(`(?i)\bimax\b`), "IMAX"},
+		{regexp.MustCompile(`(?i)\bremastered\b`), "Remastered"},
+	}
+
+	// resolutionPattern matches resolution markers used to tell versions of the same movie apart
+	resolutionPattern = regexp.MustCompile(`(?i)\b(2160p|4k|uhd|1080p|720p|576p|480p)\b`)
+
+	// versionFlagPatterns matches other version differentiators (3D, HDR) in filenames
+	versionFlagPatterns = []editionPattern{
+		{regexp.MustCompile(`(?i)\b3d\b`), "3D"},
+		{regexp.MustCompile(`(?i)\b(?:hdr10\+?|hdr|dv|dovi|dolby[\s._-]*vision)\b`), "HDR"},
+	}
+)
+
+// MovieVersion describes a single video file of a movie that exists in several versions
+type MovieVersion struct {
+	Path    string // Full path to the video file
+	Edition string // Edition label such as "Director's Cut"
+	Version string // Version differentiator such as "2160p HDR"
+}
+
+// Label returns the combined edition and version label used in multi-version filenames
+func (v MovieVersion) Label() string {
+	return strings.TrimSpace(strings.Join([]string{v.Edition, v.Version}, " "))
+}
+
+// extractEdition returns the normalized edition label found in a filename, or an empty string
+func extractEdition(name string) string {
+	if matches := customEditionPattern.FindStringSubmatch(name); len(matches) > 1 {
+		return strings.TrimSpace(matches[1])
+	}
+
+	var labels []string
+	for _, ep := range editionPatterns {
+		if ep.pattern.MatchString(name) {
+			labels = append(labels, ep.label)
+		}
+	}
+	return strings.Join(labels, " ")
+}
+
+// extractVersion returns the version differentiator (resolution, 3D, HDR) found in a filename
+func extractVersion(name string) string {
+	var parts []string
+
+	if matches := resolutionPattern.FindStringSubmatch(name); len(matches) > 1 {
+		resolution := strings.ToLower(matches[1])
+		if resolution == "4k" || resolution == "uhd" {
+			resolution = "2160p"
+		}
+		parts = append(parts, resolution)
+	}
+
+	for _, vp := range versionFlagPatterns {
+		if vp.pattern.MatchString(name) {
+			parts = append(parts, vp.label)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// extractVersionWithInfo returns the version differentiator of a file, trusting the
+// resolution and HDR format read from the container over the filename when available
+func extractVersionWithInfo(name string, info *probe.Info) string {
+	if info == nil || info.Resolution() == "" {
+		return extractVersion(name)
+	}
+
+	parts := []string{info.Resolution()}
+	for _, vp := range versionFlagPatterns {
+		if vp.pattern.MatchString(name) || (vp.label == "HDR" && info.HDR != "") {
+			parts = append(parts, vp.label)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// removeEditionMarkers strips edition tags from a name so they don't pollute API searches
+func removeEditionMarkers(name string) string {
+	name = customEditionPattern.ReplaceAllString(name, " ")
+	for _, ep := range editionPatterns {
+		name = ep.pattern.ReplaceAllString(name, " ")
+	}
+	return name
+}
+
+// parseMovieVersion builds the MovieVersion information for a single video file
+func parseMovieVersion(path, nameWithoutExt string, info *probe.Info) MovieVersion {
+	return MovieVersion{
+		Path:    path,
+		Edition: extractEdition(nameWithoutExt),
+		Version: extractVersionWithInfo(nameWithoutExt, info),
+	}
+}
+
+// AssignVersionLabels returns a unique, non-empty label for each version so that
+// every file can live side by side in the same movie folder
+func AssignVersionLabels(versions []MovieVersion) []string {
+	labels := make([]string, len(versions))
+	counts := make(map[string]int)
+
+	for i, v := range versions {
+		labels[i] = v.Label()
+		counts[strings.ToLower(labels[i])]++
+	}
+
+	seen := make(map[string]int)
+	for i, label := range labels {
+		key := strings.ToLower(label)
+		if label != "" && counts[key] == 1 {
+			continue
+		}
+		seen[key]++
+		if label == "" {
+			labels[i] = fmt.Sprintf("Version %d", seen[key])
+		} else {
+			labels[i] = fmt.Sprintf("%s %d", label, seen[key])
+		}
+	}
+
+	return labels
+}
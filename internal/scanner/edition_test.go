@@ -0,0 +1,126 @@
+package scanner
+
+import "testing"
+
+func TestExtractEdition(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "No edition",
+			input:    "Blade.Runner.1982.1080p.BluRay",
+			expected: "",
+		},
+		{
+			name:     "Director's cut with dots",
+			input:    "Blade.Runner.1982.Directors.Cut.1080p",
+			expected: "Director's Cut",
+		},
+		{
+			name:     "Extended edition",
+			input:    "The Lord of the Rings (2001) Extended Edition",
+			expected: "Extended",
+		},
+		{
+			name:     "Explicit edition tag",
+			input:    "Blade Runner (1982) {edition-The Final Cut}",
+			expected: "The Final Cut",
+		},
+		{
+			name:     "Combined editions",
+			input:    "Aliens.1986.Unrated.Remastered.720p",
+			expected: "Unrated Remastered",
+		},
+		{
+			name:     "IMAX",
+			input:    "Dune (2021) IMAX 2160p",
+			expected: "IMAX",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := extractEdition(tt.input)
+			if result != tt.expected {
+				t.Errorf("extractEdition(%q) = %q; want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExtractVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "No version",
+			input:    "Blade Runner (1982)",
+			expected: "",
+		},
+		{
+			name:     "1080p",
+			input:    "Blade.Runner.1982.1080p.BluRay",
+			expected: "1080p",
+		},
+		{
+			name:     "4K normalized",
+			input:    "Blade.Runner.1982.4K.HDR",
+			expected: "2160p HDR",
+		},
+		{
+			name:     "3D",
+			input:    "Avatar.2009.3D.1080p",
+			expected: "1080p 3D",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := extractVersion(tt.input)
+			if result != tt.expected {
+				t.Errorf("extractVersion(%q) = %q; want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCleanMovieNameRemovesEdition(t *testing.T) {
+	s := NewScanner(".")
+	result := s.cleanMovieName("Blade.Runner.1982.Directors.Cut.1080p")
+	if result != "Blade Runner" {
+		t.Errorf("cleanMovieName() = %q; want %q", result, "Blade Runner")
+	}
+}
+
+func TestAssignVersionLabels(t *testing.T) {
+	versions := []MovieVersion{
+		{Path: "a.mkv", Edition: "Director's Cut", Version: "1080p"},
+		{Path: "b.mkv", Edition: "", Version: "1080p"},
+		{Path: "c.mkv", Edition: "", Version: "1080p"},
+		{Path: "d.mkv"},
+	}
+
+	expected := []string{"Director's Cut 1080p", "1080p 1", "1080p 2", "Version 1"}
+	labels := AssignVersionLabels(versions)
+
+	for i := range expected {
+		if labels[i] != expected[i] {
+			t.Errorf("AssignVersionLabels()[%d] = %q; want %q", i, labels[i], expected[i])
+		}
+	}
+}
+
+func TestGetMovieVersionFilename(t *testing.T) {
+	m := &MediaFile{IsMovie: true, Extension: ".mkv", Edition: "Director's Cut"}
+
+	if got := m.GetMovieFilename("Blade Runner", "1982"); got != "Blade Runner (1982) - Director's Cut.mkv" {
+		t.Errorf("GetMovieFilename() = %q", got)
+	}
+	if got := m.GetMovieVersionFilename("Blade Runner", "1982", "2160p", ".mp4"); got != "Blade Runner (1982) - 2160p.mp4" {
+		t.Errorf("GetMovieVersionFilename() = %q", got)
+	}
+}
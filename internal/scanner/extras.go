@@ -0,0 +1,209 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ExtraType identifies the kind of bonus content a video file holds
+type ExtraType string
+
+const (
+	ExtraNone            ExtraType = ""
+	ExtraSample          ExtraType = "sample"
+	ExtraTrailer         ExtraType = "trailer"
+	ExtraFeaturette      ExtraType = "featurette"
+	ExtraBehindTheScenes ExtraType = "behindthescenes"
+	ExtraDeletedScene    ExtraType = "deleted"
+	ExtraInterview       ExtraType = "interview"
+	ExtraScene           ExtraType = "scene"
+	ExtraShort           ExtraType = "short"
+	ExtraOther           ExtraType = "other"
+)
+
+// DefaultSampleThreshold is the size below which a secondary video in a movie folder is considered a sample
+const DefaultSampleThreshold int64 = 100 * 1024 * 1024
+
+// ExtraFile represents a bonus video (trailer, featurette, sample...) belonging to a movie
+type ExtraFile struct {
+	Path string
+	Type ExtraType
+	Size int64
+}
+
+// KodiFolder returns the Kodi/Jellyfin recognised subfolder the extra should live in
+func (e ExtraFile) KodiFolder() string {
+	if e.Type == ExtraTrailer {
+		return "trailers"
+	}
+	return "extras"
+}
+
+var (
+	// extraSuffixPattern matches Kodi/Jellyfin extras suffixes such as "Movie (2010)-trailer"
+	extraSuffixPattern = regexp.MustCompile(`(?i)-(trailer|featurette|behindthescenes|deleted|interview|scene|short|sample|other)\d*$`)
+
+	// extraNamePatterns matches files whose whole name designates an extra
+	extraNamePatterns = map[ExtraType]*regexp.Regexp{
+		ExtraSample:  regexp.MustCompile(`(?i)(?:^|[\s._-])sample(?:[\s._-]?\d+)?$|^sample[\s._-]`),
+		ExtraTrailer: regexp.MustCompile(`(?i)(?:^|[\s._-])trailer(?:[\s._-]?\d+)?$`),
+	}
+
+	// extraFolders maps lowercase folder names to the kind of extras they hold
+	extraFolders = map[string]ExtraType{
+		"sample":            ExtraSample,
+		"samples":           ExtraSample,
+		"trailer":           ExtraTrailer,
+		"trailers":          ExtraTrailer,
+		"featurette":        ExtraFeaturette,
+		"featurettes":       ExtraFeaturette,
+		"behind the scenes": ExtraBehindTheScenes,
+		"behindthescenes":   ExtraBehindTheScenes,
+		"deleted scenes":    ExtraDeletedScene,
+		"deletedscenes":     ExtraDeletedScene,
+		"interviews":        ExtraInterview,
+		"scenes":            ExtraScene,
+		"shorts":            ExtraShort,
+		"extras":            ExtraOther,
+		"extra":             ExtraOther,
+		"bonus":             ExtraOther,
+		"other":             ExtraOther,
+	}
+)
+
+// classifyExtraByName returns the extra type designated by a video filename (without extension)
+func classifyExtraByName(nameWithoutExt string) ExtraType {
+	if matches := extraSuffixPattern.FindStringSubmatch(nameWithoutExt); len(matches) > 1 {
+		return ExtraType(strings.ToLower(matches[1]))
+	}
+
+	for _, extraType := range []ExtraType{ExtraSample, ExtraTrailer} {
+		if extraNamePatterns[extraType].MatchString(nameWithoutExt) {
+			return extraType
+		}
+	}
+
+	return ExtraNone
+}
+
+// classifyExtraByFolder returns the extra type designated by a folder name
+func classifyExtraByFolder(dirName string) ExtraType {
+	return extraFolders[strings.ToLower(strings.TrimSpace(dirName))]
+}
+
+// isExtraFolder checks if a directory name designates an extras folder
+func isExtraFolder(dirName string) bool {
+	return classifyExtraByFolder(dirName) != ExtraNone
+}
+
+// collectFolderExtras lists all video files of an extras subfolder
+func collectFolderExtras(dirPath string, extraType ExtraType) []ExtraFile {
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return nil
+	}
+
+	var extras []ExtraFile
+	for _, entry := range entries {
+		if entry.IsDir() || !isVideoFile(strings.ToLower(filepath.Ext(entry.Name()))) {
+			continue
+		}
+		extras = append(extras, ExtraFile{
+			Path: filepath.Join(dirPath, entry.Name()),
+			Type: extraType,
+			Size: entrySize(entry),
+		})
+	}
+
+	return extras
+}
+
+// entrySize returns the size of a directory entry, or 0 if it cannot be determined
+func entrySize(entry os.DirEntry) int64 {
+	info, err := entry.Info()
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// sizedVideo is a candidate main feature with its size
+type sizedVideo struct {
+	path string
+	size int64
+}
+
+// splitSamplesBySize separates small videos (samples) from feature candidates.
+// Sizes only matter when at least one video is above the threshold, so that
+// folders of small files (e.g. low-resolution rips) are not all flagged as samples.
+func splitSamplesBySize(videos []sizedVideo, threshold int64) (features []sizedVideo, samples []ExtraFile) {
+	if len(videos) < 2 || threshold <= 0 {
+		return videos, nil
+	}
+
+	hasLarge := false
+	for _, v := range videos {
+		if v.size >= threshold {
+			hasLarge = true
+			break
+		}
+	}
+	if !hasLarge {
+		return videos, nil
+	}
+
+	for _, v := range videos {
+		if v.size < threshold {
+			samples = append(samples, ExtraFile{Path: v.path, Type: ExtraSample, Size: v.size})
+		} else {
+			features = append(features, v)
+		}
+	}
+
+	return features, samples
+}
+
+// sortBySizeDesc orders feature candidates so that the largest (the main feature) comes first
+func sortBySizeDesc(videos []sizedVideo) []string {
+	sort.SliceStable(videos, func(i, j int) bool {
+		return videos[i].size > videos[j].size
+	})
+
+	paths := make([]string, 0, len(videos))
+	for _, v := range videos {
+		paths = append(paths, v.path)
+	}
+	return paths
+}
+
+// attachStandaloneExtras assigns extras found next to standalone movie files to the movie
+// whose name they start with; extras without a matching movie are dropped from the results
+func attachStandaloneExtras(mediaFiles []MediaFile, extras []ExtraFile) []MediaFile {
+	for _, extra := range extras {
+		extraDir := filepath.Dir(extra.Path)
+		extraName := strings.ToLower(filepath.Base(extra.Path))
+
+		bestIdx := -1
+		bestLen := 0
+		for i := range mediaFiles {
+			file := &mediaFiles[i]
+			if !file.IsMovie || file.IsMovieFolder || filepath.Dir(file.Path) != extraDir {
+				continue
+			}
+			base := strings.ToLower(strings.TrimSuffix(file.Name, file.Extension))
+			if len(base) > bestLen && strings.HasPrefix(extraName, base) {
+				bestIdx = i
+				bestLen = len(base)
+			}
+		}
+
+		if bestIdx >= 0 {
+			mediaFiles[bestIdx].Extras = append(mediaFiles[bestIdx].Extras, extra)
+		}
+	}
+
+	return mediaFiles
+}
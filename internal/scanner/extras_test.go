@@ -0,0 +1,83 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClassifyExtraByName(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ExtraType
+	}{
+		{"Inception (2010)", ExtraNone},
+		{"Inception (2010)-trailer", ExtraTrailer},
+		{"Inception (2010)-featurette2", ExtraFeaturette},
+		{"inception.2010.1080p-sample", ExtraSample},
+		{"sample-inception.2010.1080p", ExtraSample},
+		{"Trailer", ExtraTrailer},
+		{"Trailer Park Boys The Movie", ExtraNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if result := classifyExtraByName(tt.input); result != tt.expected {
+				t.Errorf("classifyExtraByName(%q) = %q; want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseMovieFolderExtras(t *testing.T) {
+	root := t.TempDir()
+	movieDir := filepath.Join(root, "Inception (2010)")
+	files := map[string]int{
+		"Inception.2010.1080p.mkv":              2048,
+		"sample.mkv":                            10,
+		"Trailer.mp4":                           500,
+		"Behind The Scenes/Making of.mkv":       700,
+		"Featurettes/Dreams.mkv":                300,
+		"Inception.2010.1080p.en.srt":           5,
+		"Inception.2010.1080p-deleted.mkv":      400,
+		"Inception.2010.1080p.Small.Encode.mkv": 20,
+	}
+	for name, size := range files {
+		path := filepath.Join(movieDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewScanner(root)
+	s.SetSampleThreshold(100)
+
+	movie, ok := s.parseMovieFolder(movieDir)
+	if !ok {
+		t.Fatal("expected a movie folder")
+	}
+
+	if len(movie.MovieFiles) != 1 || filepath.Base(movie.MovieFiles[0]) != "Inception.2010.1080p.mkv" {
+		t.Errorf("MovieFiles = %v; want only the main feature", movie.MovieFiles)
+	}
+
+	counts := make(map[ExtraType]int)
+	for _, extra := range movie.Extras {
+		counts[extra.Type]++
+	}
+	expected := map[ExtraType]int{
+		ExtraSample:          2,
+		ExtraTrailer:         1,
+		ExtraBehindTheScenes: 1,
+		ExtraFeaturette:      1,
+		ExtraDeletedScene:    1,
+	}
+	for extraType, count := range expected {
+		if counts[extraType] != count {
+			t.Errorf("extras of type %q = %d; want %d", extraType, counts[extraType], count)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// dirIdentity returns the fully resolved path of a directory on platforms without inode numbers
+func dirIdentity(path string, info os.FileInfo) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
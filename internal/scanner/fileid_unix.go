@@ -0,0 +1,18 @@
+//go:build unix
+
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// dirIdentity returns the device and inode of a directory, which identify it whatever the path it
+// is reached by
+func dirIdentity(path string, info os.FileInfo) string {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return fmt.Sprintf("%d:%d", uint64(stat.Dev), uint64(stat.Ino))
+	}
+	return path
+}
@@ -0,0 +1,44 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// movieHashChunkSize is the size of the head and tail chunks read by the OpenSubtitles hash
+const movieHashChunkSize = 64 * 1024
+
+// MovieHash computes the OpenSubtitles 64-bit movie hash of a file: the file size plus the
+// sum of all little-endian 64-bit words of its first and last 64 KiB, returned as 16 hex digits
+func MovieHash(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to stat file: %w", err)
+	}
+	size := info.Size()
+	if size < movieHashChunkSize {
+		return "", size, fmt.Errorf("file too small to hash: %d bytes", size)
+	}
+
+	hash := uint64(size)
+	buf := make([]byte, movieHashChunkSize)
+
+	for _, offset := range []int64{0, size - movieHashChunkSize} {
+		if _, err := f.ReadAt(buf, offset); err != nil && err != io.EOF {
+			return "", size, fmt.Errorf("failed to read file: %w", err)
+		}
+		for i := 0; i < movieHashChunkSize; i += 8 {
+			hash += binary.LittleEndian.Uint64(buf[i : i+8])
+		}
+	}
+
+	return fmt.Sprintf("%016x", hash), size, nil
+}
@@ -0,0 +1,44 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMovieHash(t *testing.T) {
+	dir := t.TempDir()
+
+	// An all-zero file hashes to its size
+	zeros := filepath.Join(dir, "zeros.mkv")
+	if err := os.WriteFile(zeros, make([]byte, 128*1024), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash, size, err := MovieHash(zeros)
+	if err != nil {
+		t.Fatalf("MovieHash() error: %v", err)
+	}
+	if hash != "0000000000020000" || size != 128*1024 {
+		t.Errorf("MovieHash() = %s, %d", hash, size)
+	}
+
+	// The first and last chunks both contribute their little-endian words
+	data := make([]byte, 200*1024)
+	data[0] = 0x01
+	data[len(data)-8] = 0x02
+	marked := filepath.Join(dir, "marked.mkv")
+	if err := os.WriteFile(marked, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if hash, _, _ := MovieHash(marked); hash != "0000000000032003" {
+		t.Errorf("MovieHash() = %s, want 0000000000032003", hash)
+	}
+
+	small := filepath.Join(dir, "small.mkv")
+	if err := os.WriteFile(small, []byte("tiny"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := MovieHash(small); err == nil {
+		t.Error("expected an error for a file smaller than the hash chunk")
+	}
+}
@@ -0,0 +1,309 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFileName is the gitignore-style file read in every scanned directory
+const IgnoreFileName = ".kodirenamerignore"
+
+// defaultIgnorePatterns lists NAS metadata, trash, incomplete download and tool folders that are
+// never media, in .kodirenamerignore syntax
+var defaultIgnorePatterns = []string{
+	"@eaDir/",
+	".@__thumb/",
+	"#recycle/",
+	"#snapshot/",
+	".Trash-*/",
+	"$RECYCLE.BIN/",
+	"System Volume Information/",
+	"lost+found/",
+	".AppleDouble/",
+	"incomplete/",
+	".incomplete/",
+	"_UNPACK_*/",
+	"_FAILED_*/",
+	"_unsorted/",
+}
+
+// SkippedPath records a file or directory left out of a scan and why
+type SkippedPath struct {
+	Path   string
+	Reason string
+}
+
+// ignoreRule is a single pattern line of an ignore file
+type ignoreRule struct {
+	pattern *regexp.Regexp
+	negate  bool   // Pattern starts with "!": re-includes a previously ignored path
+	dirOnly bool   // Pattern ends with "/": only matches directories
+	base    string // Directory the pattern is relative to
+	source  string // Where the rule comes from, for skip reasons
+}
+
+// parseIgnoreRule compiles one gitignore-style line; blank lines and comments return false
+func parseIgnoreRule(line, base, source string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base, source: source}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+	line = strings.TrimPrefix(line, `\`)
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	// A slash at the start or in the middle anchors the pattern to the ignore file's directory;
+	// otherwise it matches a name at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr := globToRegexp(line)
+	if anchored {
+		expr = "^" + expr + "$"
+	} else {
+		expr = "(?:^|/)" + expr + "$"
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.pattern = re
+	return rule, true
+}
+
+// globToRegexp translates a gitignore glob (*, ?, [...], **) into a regular expression
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				// "**/" matches any number of directories, a trailing "**" everything below
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// loadIgnoreFile reads the rules of the ignore file in dir, if any
+func loadIgnoreFile(dir string) ([]ignoreRule, error) {
+	path := filepath.Join(dir, IgnoreFileName)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	lineScanner := bufio.NewScanner(f)
+	for lineNumber := 1; lineScanner.Scan(); lineNumber++ {
+		source := fmt.Sprintf("%s:%d", path, lineNumber)
+		if rule, ok := parseIgnoreRule(lineScanner.Text(), dir, source); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, lineScanner.Err()
+}
+
+// matchIgnoreRules returns the last rule matching the path, gitignore style, or nil
+func matchIgnoreRules(rules []ignoreRule, path string, isDir bool) *ignoreRule {
+	var matched *ignoreRule
+	for i := range rules {
+		rule := &rules[i]
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		rel, err := filepath.Rel(rule.base, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if rule.pattern.MatchString(filepath.ToSlash(rel)) {
+			matched = rule
+		}
+	}
+	if matched != nil && matched.negate {
+		return nil
+	}
+	return matched
+}
+
+// scanFilter holds the user-configured include/exclude options of a scanner
+type scanFilter struct {
+	includeGlobs  []string
+	excludeGlobs  []string
+	includeRegexp []*regexp.Regexp
+	excludeRegexp []*regexp.Regexp
+	minFileSize   int64
+}
+
+// matchGlob matches a glob against a path relative to the scan root, or against its base name
+// when the glob has no slash; a trailing slash only matches directories
+func matchGlob(glob, relPath string, isDir bool) bool {
+	if strings.HasSuffix(glob, "/") {
+		if !isDir {
+			return false
+		}
+		glob = strings.TrimSuffix(glob, "/")
+	}
+	relPath = filepath.ToSlash(relPath)
+	if !strings.Contains(glob, "/") {
+		relPath = filepath.Base(relPath)
+	}
+	matched, _ := regexp.MatchString("^"+globToRegexp(glob)+"$", relPath)
+	return matched
+}
+
+// AddIncludeGlob restricts scanned video files to those matching at least one include glob or regex
+func (s *Scanner) AddIncludeGlob(glob string) {
+	s.filter.includeGlobs = append(s.filter.includeGlobs, glob)
+}
+
+// AddExcludeGlob skips files and directories matching the glob (base name, or relative path if it contains "/")
+func (s *Scanner) AddExcludeGlob(glob string) {
+	s.filter.excludeGlobs = append(s.filter.excludeGlobs, glob)
+}
+
+// AddIncludeRegex restricts scanned video files to those whose relative path matches at least one include
+func (s *Scanner) AddIncludeRegex(expr string) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("invalid include regex: %w", err)
+	}
+	s.filter.includeRegexp = append(s.filter.includeRegexp, re)
+	return nil
+}
+
+// AddExcludeRegex skips files and directories whose path relative to the scan root matches the regex
+func (s *Scanner) AddExcludeRegex(expr string) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("invalid exclude regex: %w", err)
+	}
+	s.filter.excludeRegexp = append(s.filter.excludeRegexp, re)
+	return nil
+}
+
+// SetMinFileSize skips standalone video files smaller than size bytes (extras such as samples are kept)
+func (s *Scanner) SetMinFileSize(size int64) {
+	s.filter.minFileSize = size
+}
+
+// SetIgnoreFiles enables or disables the built-in junk folder list and .kodirenamerignore files
+func (s *Scanner) SetIgnoreFiles(enabled bool) {
+	s.useIgnoreFiles = enabled
+}
+
+// Skipped returns the files and directories left out by the last scan, with the reason
+func (s *Scanner) Skipped() []SkippedPath {
+	s.skippedMu.Lock()
+	defer s.skippedMu.Unlock()
+	return append([]SkippedPath(nil), s.skipped...)
+}
+
+// defaultIgnoreRules compiles the built-in junk patterns, relative to the scan root
+func (s *Scanner) defaultIgnoreRules() []ignoreRule {
+	rules := make([]ignoreRule, 0, len(defaultIgnorePatterns))
+	for _, pattern := range defaultIgnorePatterns {
+		if rule, ok := parseIgnoreRule(pattern, s.rootPath, "built-in junk list"); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// skipReason returns why a path must be skipped, or an empty string; rules are those of its directory
+func (s *Scanner) skipReason(path string, info os.FileInfo, rules []ignoreRule) string {
+	if rule := matchIgnoreRules(rules, path, info.IsDir()); rule != nil {
+		return fmt.Sprintf("ignored by %s", rule.source)
+	}
+
+	relPath, err := filepath.Rel(s.rootPath, path)
+	if err != nil {
+		relPath = path
+	}
+	slashPath := filepath.ToSlash(relPath)
+
+	for _, glob := range s.filter.excludeGlobs {
+		if matchGlob(glob, relPath, info.IsDir()) {
+			return fmt.Sprintf("excluded by glob %q", glob)
+		}
+	}
+	for _, re := range s.filter.excludeRegexp {
+		if re.MatchString(slashPath) {
+			return fmt.Sprintf("excluded by regex %q", re.String())
+		}
+	}
+
+	if info.IsDir() {
+		return ""
+	}
+
+	if len(s.filter.includeGlobs) > 0 || len(s.filter.includeRegexp) > 0 {
+		included := false
+		for _, glob := range s.filter.includeGlobs {
+			included = included || matchGlob(glob, relPath, false)
+		}
+		for _, re := range s.filter.includeRegexp {
+			included = included || re.MatchString(slashPath)
+		}
+		if !included {
+			return "not matching any include pattern"
+		}
+	}
+
+	return ""
+}
+
+// sizeSkipReason returns why a video file is too small to be scanned, or an empty string
+func (s *Scanner) sizeSkipReason(info os.FileInfo) string {
+	if s.filter.minFileSize > 0 && info.Size() < s.filter.minFileSize {
+		return fmt.Sprintf("smaller than minimum size (%d < %d bytes)", info.Size(), s.filter.minFileSize)
+	}
+	return ""
+}
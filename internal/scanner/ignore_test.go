@@ -0,0 +1,194 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreRuleMatching(t *testing.T) {
+	base := filepath.FromSlash("/media")
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"*.nfo", "a/b/movie.nfo", false, true},
+		{"Old/", "Old", true, true},
+		{"Old/", "Old", false, false},
+		{"/Old", "sub/Old", true, false},
+		{"/Old", "Old", true, true},
+		{"docs/*.mkv", "docs/a.mkv", false, true},
+		{"docs/*.mkv", "docs/x/a.mkv", false, false},
+		{"**/tmp/*.mkv", "a/b/tmp/c.mkv", false, true},
+		{"ep[0-9].mkv", "ep5.mkv", false, true},
+		{"ep?.mkv", "ep10.mkv", false, false},
+	}
+
+	for _, tt := range tests {
+		rule, ok := parseIgnoreRule(tt.pattern, base, "test")
+		if !ok {
+			t.Fatalf("parseIgnoreRule(%q) failed", tt.pattern)
+		}
+		got := matchIgnoreRules([]ignoreRule{rule}, filepath.Join(base, filepath.FromSlash(tt.path)), tt.isDir) != nil
+		if got != tt.want {
+			t.Errorf("pattern %q on %q (dir=%v) = %v, want %v", tt.pattern, tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestIgnoreRuleNegation(t *testing.T) {
+	var rules []ignoreRule
+	for _, line := range []string{"# comment", "", "*.mkv", "!keep.mkv"} {
+		if rule, ok := parseIgnoreRule(line, "/media", "test"); ok {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) != 2 {
+		t.Fatalf("parsed %d rules, want 2", len(rules))
+	}
+	if matchIgnoreRules(rules, "/media/drop.mkv", false) == nil {
+		t.Error("drop.mkv should be ignored")
+	}
+	if matchIgnoreRules(rules, "/media/keep.mkv", false) != nil {
+		t.Error("keep.mkv should be re-included")
+	}
+}
+
+func TestScanDirectorySkips(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]int{
+		"Good.Movie.2010.mkv":                  2048,
+		"Tiny.Movie.2011.mkv":                  10,
+		"@eaDir/Thumb.Movie.2012.mkv":          2048,
+		"_unsorted/Later.Movie.2013.mkv":       2048,
+		"Nested/Other.Movie.2014.mkv":          2048,
+		"Nested/Ignored.Movie.2015.mkv":        2048,
+		"Nested/" + IgnoreFileName:             0,
+		"Excluded/Excluded.Movie.2016.mkv":     2048,
+		"Regex.Excluded.Movie.2017.CAM.mkv":    2048,
+		"Nested/Deep/Deep.Movie.2018.mkv":      2048,
+		"Nested/Deep/Deep.Movie.2018.Cut.avi":  2048,
+		"Nested/Deep/Deep.Movie.2018.Cut2.avi": 2048,
+	}
+	for name, size := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ignore := "Ignored.*\nDeep/*.avi\n"
+	if err := os.WriteFile(filepath.Join(root, "Nested", IgnoreFileName), []byte(ignore), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScanner(root)
+	s.SetProbeFiles(false)
+	s.SetHashFiles(false)
+	s.SetMinFileSize(100)
+	s.AddExcludeGlob("Excluded/")
+	if err := s.AddExcludeRegex(`(?i)\bcam\b`); err != nil {
+		t.Fatal(err)
+	}
+
+	mediaFiles, err := s.ScanDirectory()
+	if err != nil {
+		t.Fatalf("ScanDirectory() error: %v", err)
+	}
+
+	var found []string
+	for _, mf := range mediaFiles {
+		found = append(found, mf.Name)
+		// With its ignored cuts left out, Deep only holds its movie
+		if mf.Name == "Deep" && (!mf.IsMovieFolder || len(mf.MovieFiles) != 1 || filepath.Base(mf.MovieFiles[0]) != "Deep.Movie.2018.mkv") {
+			t.Errorf("Deep = %+v, want a movie folder of Deep.Movie.2018.mkv", mf)
+		}
+	}
+	want := []string{"Good.Movie.2010.mkv", "Other.Movie.2014.mkv", "Deep"}
+	if len(found) != len(want) {
+		t.Fatalf("found %v, want %v", found, want)
+	}
+	for _, name := range want {
+		if !strings.Contains(strings.Join(found, "|"), name) {
+			t.Errorf("%s not found in %v", name, found)
+		}
+	}
+
+	reasons := make(map[string]string)
+	for _, skip := range s.Skipped() {
+		rel, _ := filepath.Rel(root, skip.Path)
+		reasons[filepath.ToSlash(rel)] = skip.Reason
+	}
+	expected := map[string]string{
+		"@eaDir":                              "built-in junk list",
+		"_unsorted":                           "built-in junk list",
+		"Tiny.Movie.2011.mkv":                 "smaller than minimum size",
+		"Nested/Ignored.Movie.2015.mkv":       IgnoreFileName + ":1",
+		"Nested/Deep/Deep.Movie.2018.Cut.avi": IgnoreFileName + ":2",
+		"Excluded":                            "excluded by glob",
+		"Regex.Excluded.Movie.2017.CAM.mkv":   "excluded by regex",
+	}
+	for path, reason := range expected {
+		if !strings.Contains(reasons[path], reason) {
+			t.Errorf("skip reason for %s = %q, want it to mention %q", path, reasons[path], reason)
+		}
+	}
+}
+
+func TestScanMovieFolderSkipsIgnoredVideos(t *testing.T) {
+	root := t.TempDir()
+	folder := filepath.Join(root, "Movie (2010)")
+	files := map[string]int{
+		"Movie.2010.mkv":     2048,
+		"sample.mkv":         8192,
+		"Movie.2010.CAM.mkv": 4096,
+		"Movie.2010.srt":     10,
+		IgnoreFileName:       0,
+	}
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, size := range files {
+		if err := os.WriteFile(filepath.Join(folder, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(folder, IgnoreFileName), []byte("sample.mkv\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScanner(root)
+	s.SetProbeFiles(false)
+	s.SetHashFiles(false)
+	if err := s.AddExcludeRegex(`(?i)\bcam\b`); err != nil {
+		t.Fatal(err)
+	}
+
+	mediaFiles, err := s.ScanDirectory()
+	if err != nil {
+		t.Fatalf("ScanDirectory() error: %v", err)
+	}
+	if len(mediaFiles) != 1 || !mediaFiles[0].IsMovieFolder {
+		t.Fatalf("found %+v, want one movie folder", mediaFiles)
+	}
+	movie := mediaFiles[0]
+	if len(movie.MovieFiles) != 1 || filepath.Base(movie.MovieFiles[0]) != "Movie.2010.mkv" {
+		t.Errorf("MovieFiles = %v, want only Movie.2010.mkv", movie.MovieFiles)
+	}
+	if len(movie.Extras) != 0 || len(movie.Versions) != 0 {
+		t.Errorf("Extras = %v, Versions = %v, want none", movie.Extras, movie.Versions)
+	}
+
+	skipped := make(map[string]bool)
+	for _, skip := range s.Skipped() {
+		skipped[filepath.Base(skip.Path)] = true
+	}
+	if !skipped["sample.mkv"] || !skipped["Movie.2010.CAM.mkv"] {
+		t.Errorf("Skipped() = %v, want sample.mkv and Movie.2010.CAM.mkv", s.Skipped())
+	}
+}
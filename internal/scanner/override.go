@@ -0,0 +1,185 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TypeOverrideFileName is the marker file forcing the media type of a directory and everything below it
+const TypeOverrideFileName = ".kodi-type"
+
+// MediaKind is the kind of media an item is processed as
+type MediaKind string
+
+const (
+	// KindMovie processes the item as a movie
+	KindMovie MediaKind = "movie"
+	// KindSeries processes the item as a TV series episode
+	KindSeries MediaKind = "series"
+)
+
+// looseEpisodePatterns find an episode number in names without an SxxEyy marker, once the user
+// has said the item is an episode: "Show E05", "Show Ep 5", "Show 105" and "Show - 05"
+var looseEpisodePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(?:^|[\s._-])E[Pp]?\.?\s*(\d{1,3})(?:[\s._-]|$)`),
+	regexp.MustCompile(`(?:^|[\s._-])(\d)(\d{2})(?:[\s._-]|$)`),
+	regexp.MustCompile(`\s-\s(\d{1,3})(?:[\s._-]|$)`),
+}
+
+// TypeOverride is a media type forced by a .kodi-type marker file or chosen by the user, with an
+// optional database ID that skips the search
+type TypeOverride struct {
+	Kind   MediaKind
+	Source string // "tvdb" or "tmdb" when an ID is given
+	ID     string
+	Origin string // Marker file the override was read from, empty when chosen interactively
+}
+
+// ParseTypeOverride parses the content of a .kodi-type marker: "movie", "series" (or "tv"),
+// optionally followed by ":source=id" such as "series:tvdb=121361" or "movie:tmdb=603";
+// blank lines and # comments are ignored
+func ParseTypeOverride(content string) (TypeOverride, error) {
+	lineScanner := bufio.NewScanner(strings.NewReader(content))
+	for lineScanner.Scan() {
+		line := strings.TrimSpace(lineScanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		kind, ref, _ := strings.Cut(strings.ToLower(line), ":")
+		var override TypeOverride
+		switch strings.TrimSpace(kind) {
+		case "movie":
+			override.Kind = KindMovie
+		case "series", "tv", "show":
+			override.Kind = KindSeries
+		default:
+			return TypeOverride{}, fmt.Errorf("unknown media type %q (expected movie or series)", kind)
+		}
+
+		if ref = strings.TrimSpace(ref); ref != "" {
+			source, id, ok := strings.Cut(ref, "=")
+			source, id = strings.TrimSpace(source), strings.TrimSpace(id)
+			if !ok || id == "" || (source != "tvdb" && source != "tmdb") {
+				return TypeOverride{}, fmt.Errorf("invalid database reference %q (expected tvdb=ID or tmdb=ID)", ref)
+			}
+			override.Source, override.ID = source, id
+		}
+		return override, nil
+	}
+	return TypeOverride{}, fmt.Errorf("empty %s file", TypeOverrideFileName)
+}
+
+// loadTypeOverride reads the .kodi-type marker of a directory, if any
+func loadTypeOverride(dir string) (*TypeOverride, error) {
+	path := filepath.Join(dir, TypeOverrideFileName)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	override, err := ParseTypeOverride(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	override.Origin = path
+	return &override, nil
+}
+
+// extractLooseEpisode finds an episode number in a name without SxxEyy marker; a three-digit
+// number such as 105 is season 1 episode 5, other numbers are episodes of season 1
+func extractLooseEpisode(name string) (season, episode int, found bool) {
+	for i, pattern := range looseEpisodePatterns {
+		matches := pattern.FindStringSubmatch(name)
+		if matches == nil {
+			continue
+		}
+		if i == 1 {
+			season, _ = strconv.Atoi(matches[1])
+			episode, _ = strconv.Atoi(matches[2])
+			return season, episode, true
+		}
+		episode, _ = strconv.Atoi(matches[1])
+		return 1, episode, true
+	}
+	return 0, 0, false
+}
+
+// Reclassify processes a scanned file as the given kind, overriding the name-based
+// classification; an episode needs an episode number in its name and movie folders cannot be
+// turned into episodes
+func (s *Scanner) Reclassify(mediaFile *MediaFile, kind MediaKind) error {
+	nameWithoutExt := strings.TrimSuffix(mediaFile.Name, mediaFile.Extension)
+
+	switch kind {
+	case KindMovie:
+		if mediaFile.IsMovie {
+			return nil
+		}
+		mediaFile.IsSeries = false
+		mediaFile.IsMovie = true
+		mediaFile.Season, mediaFile.Episode = 0, 0
+		mediaFile.AirDate = ""
+		mediaFile.Year = s.extractYear(nameWithoutExt)
+		mediaFile.CleanName = s.cleanMovieName(nameWithoutExt)
+		mediaFile.Edition = extractEdition(nameWithoutExt)
+		mediaFile.Version = extractVersionWithInfo(nameWithoutExt, mediaFile.MediaInfo)
+		s.applyAltSearchQuery(mediaFile)
+	case KindSeries:
+		if mediaFile.IsSeries {
+			return nil
+		}
+		if mediaFile.IsMovieFolder {
+			return fmt.Errorf("movie folder %s cannot be processed as an episode", mediaFile.Name)
+		}
+		season, episode, found := s.extractSeriesInfo(nameWithoutExt)
+		if !found {
+			season, episode, found = extractLooseEpisode(nameWithoutExt)
+		}
+		airDate := ""
+		if !found {
+			airDate = extractAirDate(nameWithoutExt)
+		}
+		if !found && airDate == "" {
+			return fmt.Errorf("no episode number in %s", mediaFile.Name)
+		}
+		mediaFile.IsMovie = false
+		mediaFile.IsSeries = true
+		mediaFile.Season, mediaFile.Episode = season, episode
+		mediaFile.AirDate = airDate
+		mediaFile.Year = 0
+		mediaFile.CleanName = s.cleanSeriesName(nameWithoutExt)
+		mediaFile.Edition, mediaFile.Version = "", ""
+		mediaFile.AltSearchQuery = ""
+	default:
+		return fmt.Errorf("unknown media kind %q", kind)
+	}
+	return nil
+}
+
+// applyTypeOverrides reclassifies the media files of a directory marked by a .kodi-type file;
+// the files that cannot take the forced type are skipped with the reason
+func (s *Scanner) applyTypeOverrides(mediaFiles []MediaFile, override *TypeOverride) []MediaFile {
+	if override == nil {
+		return mediaFiles
+	}
+
+	kept := mediaFiles[:0]
+	for _, mediaFile := range mediaFiles {
+		if err := s.Reclassify(&mediaFile, override.Kind); err != nil {
+			s.addSkipped(mediaFile.Path, fmt.Sprintf("marked as %s by %s: %v", override.Kind, override.Origin, err))
+			continue
+		}
+		mediaFile.TypeOverride = override
+		kept = append(kept, mediaFile)
+	}
+	return kept
+}
@@ -0,0 +1,103 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseTypeOverride(t *testing.T) {
+	tests := []struct {
+		content string
+		want    TypeOverride
+		wantErr bool
+	}{
+		{content: "movie\n", want: TypeOverride{Kind: KindMovie}},
+		{content: "# inbox marker\nseries:tvdb=121361", want: TypeOverride{Kind: KindSeries, Source: "tvdb", ID: "121361"}},
+		{content: "Movie: tmdb = 603", want: TypeOverride{Kind: KindMovie, Source: "tmdb", ID: "603"}},
+		{content: "tv", want: TypeOverride{Kind: KindSeries}},
+		{content: "documentary", wantErr: true},
+		{content: "series:imdb=tt0944947", wantErr: true},
+		{content: "\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTypeOverride(tt.content)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTypeOverride(%q) error = %v", tt.content, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseTypeOverride(%q) = %+v, want %+v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestExtractLooseEpisode(t *testing.T) {
+	tests := []struct {
+		name            string
+		season, episode int
+		found           bool
+	}{
+		{"Show E05", 1, 5, true},
+		{"Show.Ep.12.720p", 1, 12, true},
+		{"Show 105 Title", 1, 5, true},
+		{"Anime - 07 [1080p]", 1, 7, true},
+		{"Just A Movie", 0, 0, false},
+	}
+	for _, tt := range tests {
+		season, episode, found := extractLooseEpisode(tt.name)
+		if season != tt.season || episode != tt.episode || found != tt.found {
+			t.Errorf("extractLooseEpisode(%q) = %d, %d, %v", tt.name, season, episode, found)
+		}
+	}
+}
+
+func TestScanTypeOverrideMarkers(t *testing.T) {
+	root := t.TempDir()
+	movieDir := filepath.Join(root, "Concert")
+	showDir := filepath.Join(root, "Anime", "Season 1")
+	for _, dir := range []string{movieDir, showDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := map[string]string{
+		filepath.Join(movieDir, TypeOverrideFileName):      "movie:tmdb=603\n",
+		filepath.Join(movieDir, "Live 1920x1080.mkv"):      "",
+		filepath.Join(root, "Anime", TypeOverrideFileName): "series",
+		filepath.Join(showDir, "Anime - 07.mkv"):           "",
+		filepath.Join(showDir, "Opening.mkv"):              "",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewScanner(root)
+	s.SetProbeFiles(false)
+	s.SetHashFiles(false)
+	mediaFiles, err := s.ScanDirectory()
+	if err != nil {
+		t.Fatalf("ScanDirectory() error: %v", err)
+	}
+	if len(mediaFiles) != 2 {
+		t.Fatalf("got %d files: %+v", len(mediaFiles), mediaFiles)
+	}
+
+	episode, movie := mediaFiles[0], mediaFiles[1]
+	if !episode.IsSeries || episode.Season != 1 || episode.Episode != 7 || episode.TypeOverride == nil {
+		t.Errorf("episode = %+v", episode)
+	}
+	if !movie.IsMovie || movie.TypeOverride == nil || movie.TypeOverride.ID != "603" {
+		t.Errorf("movie = %+v", movie)
+	}
+
+	// The episode marker cannot apply to a file without episode number
+	skipped := s.Skipped()
+	if len(skipped) != 1 || skipped[0].Path != filepath.Join(showDir, "Opening.mkv") {
+		t.Errorf("Skipped() = %+v", skipped)
+	}
+}
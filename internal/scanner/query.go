@@ -0,0 +1,38 @@
+package scanner
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// applyAltSearchQuery sets the fallback search query of a movie: the name of the folder holding a
+// standalone file, or the main video name of a movie folder, when it says something different
+func (s *Scanner) applyAltSearchQuery(mediaFile *MediaFile) {
+	mediaFile.AltSearchQuery = ""
+	if !mediaFile.IsMovie || mediaFile.Archive != nil {
+		return
+	}
+
+	var source string
+	switch {
+	case mediaFile.IsMovieFolder:
+		if len(mediaFile.MovieFiles) == 0 {
+			return
+		}
+		source = filepath.Base(mediaFile.MovieFiles[0])
+		source = strings.TrimSuffix(source, filepath.Ext(source))
+	default:
+		dir := filepath.Dir(mediaFile.Path)
+		if filepath.Clean(dir) == filepath.Clean(s.rootPath) {
+			// The inbox itself says nothing about the movie
+			return
+		}
+		source = filepath.Base(dir)
+	}
+
+	alt := s.cleanMovieName(source)
+	if alt == "" || isGenericName(alt) || strings.EqualFold(alt, mediaFile.CleanName) {
+		return
+	}
+	mediaFile.AltSearchQuery = alt
+}
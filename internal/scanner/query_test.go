@@ -0,0 +1,55 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAltSearchQuery(t *testing.T) {
+	root := t.TempDir()
+	files := []string{
+		"Arrival.2016.mkv",
+		filepath.Join("Spirited Away (2001)", "Sen.to.Chihiro.no.Kamikakushi.2001.1080p.mkv"),
+		filepath.Join("Spirited Away (2001)", "Sen.to.Chihiro.no.Kamikakushi.2001.1080p.srt"),
+		filepath.Join("Heat (1995)", "Heat.1995.mkv"),
+		filepath.Join("Heat (1995)", "Heat.1995.srt"),
+	}
+	for _, name := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewScanner(root)
+	s.SetProbeFiles(false)
+	s.SetHashFiles(false)
+	mediaFiles, err := s.ScanDirectory()
+	if err != nil {
+		t.Fatalf("ScanDirectory() error: %v", err)
+	}
+
+	want := map[string]string{
+		// Files directly in the inbox and folders named like their video have no alternative
+		"Arrival.2016.mkv":     "",
+		"Heat (1995)":          "",
+		"Spirited Away (2001)": "Sen to Chihiro no Kamikakushi",
+	}
+	if len(mediaFiles) != len(want) {
+		t.Fatalf("got %d media files: %+v", len(mediaFiles), mediaFiles)
+	}
+	for _, mf := range mediaFiles {
+		alt, ok := want[mf.Name]
+		if !ok {
+			t.Errorf("unexpected media file %q", mf.Name)
+			continue
+		}
+		if mf.AltSearchQuery != alt {
+			t.Errorf("%s: AltSearchQuery = %q, want %q (clean name %q)", mf.Name, mf.AltSearchQuery, alt, mf.CleanName)
+		}
+	}
+}
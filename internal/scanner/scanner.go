@@ -1,13 +1,17 @@
 package scanner
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
+	"kodi-renamer/internal/archive"
+	"kodi-renamer/internal/probe"
 	"kodi-renamer/internal/utils"
 )
 
@@ -16,7 +20,7 @@ var (
 	videoExtensions = []string{".mkv", ".mp4", ".avi", ".mov", ".wmv", ".flv", ".webm", ".m4v", ".iso"}
 
 	// subtitleExtensions lists all supported subtitle file extensions
-	subtitleExtensions = []string{".srt", ".sub", ".ass", ".ssa", ".vtt"}
+	subtitleExtensions = []string{".srt", ".sub", ".idx", ".sup", ".ass", ".ssa", ".vtt"}
 
 	// seriesPatterns contains regular expressions to detect TV series episode numbering
 	seriesPatterns = []*regexp.Regexp{
@@ -39,14 +43,34 @@ type MediaFile struct {
 	IsSeries      bool
 	Season        int
 	Episode       int
+	AirDate       string // Broadcast date ("2024-03-14") of a daily episode named by date, its season and episode then 0 until identified
 	Year          int
 	CleanName     string
-	ParentDir     string   // Parent directory name for series files
-	IsMovieFolder bool     // True if movie is a folder (contains video + subtitles/extras)
-	MovieFiles    []string // All video files in movie folder
-	SubtitleFiles []string // All subtitle files in movie folder
-	IsBluRay      bool     // True if folder contains Blu-ray structure
-	IsDVD         bool     // True if folder contains DVD structure
+	ParentDir     string          // Parent directory name for series files
+	IsMovieFolder bool            // True if movie is a folder (contains video + subtitles/extras)
+	MovieFiles    []string        // All non-extra video files in movie folder, main feature (largest) first
+	SubtitleFiles []string        // All subtitle files in movie folder
+	IsBluRay      bool            // True if folder contains Blu-ray structure
+	IsDVD         bool            // True if folder contains DVD structure
+	Edition       string          // Edition label such as "Director's Cut" (movies only)
+	Version       string          // Version differentiator such as "2160p" (movies only)
+	Versions      []MovieVersion  // Per-file versions when a movie folder holds several cuts or qualities
+	PartFiles     []string        // Ordered video files of a stacked multi-part movie (CD1, CD2...)
+	Extras        []ExtraFile     // Trailers, featurettes, samples and other bonus videos of the movie
+	MediaInfo     *probe.Info     // Container properties (duration, resolution, codecs) of the main video, nil if unknown
+	Hash          string          // OpenSubtitles movie hash of the main video, empty if not computed
+	FileSize      int64           // Size in bytes of the main video
+	Archive       *archive.Member // Archive set holding the video (Path is then its first volume), nil for plain files
+	DiscTitle     string          // Title from Blu-ray metadata or the volume label of a disc image, empty if none
+	TypeOverride  *TypeOverride   // Media type forced by a .kodi-type marker or the user, nil if classified by name
+	// AltSearchQuery is tried when the search query finds nothing: the folder name of a standalone
+	// movie file, or the main video name of a movie folder; empty when it adds nothing
+	AltSearchQuery string
+	// SearchVariant describes the fallback query that found the media, set once it is identified
+	SearchVariant string
+	// RenamedPath is where the next scan finds the media after it was renamed or moved: the new file of
+	// an episode, the movie folder of a movie; empty until then
+	RenamedPath string
 }
 
 // EpisodeRenameTask represents a pending episode rename operation
@@ -73,71 +97,94 @@ type SeriesBatchRename struct {
 
 // Scanner scans directories for media files and extracts metadata
 type Scanner struct {
-	rootPath string
+	rootPath        string
+	sampleThreshold int64
+	probeFiles      bool
+	hashFiles       bool
+	useIgnoreFiles  bool
+	followSymlinks  bool
+	scanArchives    bool
+	filter          scanFilter
+	concurrency     int
+	counters        scanCounters
+	skippedMu       sync.Mutex
+	skipped         []SkippedPath
+	dangling        []DanglingLink
+	realRoot        string // Scan root with symlinks resolved
+	visitedMu       sync.Mutex
+	visited         map[string]string // Directory identity to the first path it was scanned as
 }
 
 // NewScanner creates a new Scanner for the specified root directory path
 func NewScanner(rootPath string) *Scanner {
 	return &Scanner{
-		rootPath: rootPath,
+		rootPath:        rootPath,
+		sampleThreshold: DefaultSampleThreshold,
+		probeFiles:      true,
+		hashFiles:       true,
+		useIgnoreFiles:  true,
+		scanArchives:    true,
+		concurrency:     DefaultScanConcurrency,
 	}
 }
 
-// ScanDirectory recursively scans the root directory and returns all media files found
-func (s *Scanner) ScanDirectory() ([]MediaFile, error) {
-	var mediaFiles []MediaFile
-	processedDirs := make(map[string]bool)
-
-	err := filepath.Walk(s.rootPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+// SetHashFiles enables or disables computing the OpenSubtitles movie hash of video files while scanning
+func (s *Scanner) SetHashFiles(enabled bool) {
+	s.hashFiles = enabled
+}
 
-		// Skip root directory
-		if path == s.rootPath {
-			return nil
-		}
+// hashFile computes the movie hash and size of a video file, returning an empty hash when unavailable
+func (s *Scanner) hashFile(path string) (string, int64) {
+	if !s.hashFiles || path == "" {
+		return "", 0
+	}
+	hash, size, err := MovieHash(path)
+	if err != nil {
+		return "", size
+	}
+	return hash, size
+}
 
-		// Check if this is a directory
-		if info.IsDir() {
-			// Skip if already processed
-			if processedDirs[path] {
-				return filepath.SkipDir
-			}
+// SetProbeFiles enables or disables reading container headers (duration, resolution) while scanning
+func (s *Scanner) SetProbeFiles(enabled bool) {
+	s.probeFiles = enabled
+}
 
-			// Check if this is a movie folder
-			movieFile, isMovieFolder := s.parseMovieFolder(path)
-			if isMovieFolder {
-				mediaFiles = append(mediaFiles, movieFile)
-				processedDirs[path] = true
-				return filepath.SkipDir // Skip processing contents
-			}
-			return nil
-		}
+// probeFile reads the container properties of a video file, returning nil when unavailable
+func (s *Scanner) probeFile(path string) *probe.Info {
+	if !s.probeFiles || path == "" {
+		return nil
+	}
+	info, err := probe.Probe(path)
+	if err != nil {
+		return nil
+	}
+	return info
+}
 
-		// Process individual video files (not in movie folders)
-		ext := strings.ToLower(filepath.Ext(path))
-		if !isVideoFile(ext) {
-			return nil
-		}
+// SetSampleThreshold sets the size in bytes below which secondary videos of a movie folder are treated as samples
+func (s *Scanner) SetSampleThreshold(threshold int64) {
+	s.sampleThreshold = threshold
+}
 
-		// Check if parent directory is already processed as movie folder
-		parentDir := filepath.Dir(path)
-		if processedDirs[parentDir] {
-			return nil
-		}
+// ScanDirectory recursively scans the root directory and returns all media files found,
+// in directory walk order
+func (s *Scanner) ScanDirectory() ([]MediaFile, error) {
+	out := make(chan MediaFile)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.Stream(context.Background(), out)
+	}()
 
-		// This is a standalone video file or series episode
-		mediaFile := s.parseFile(path, info.Name())
+	var mediaFiles []MediaFile
+	for mediaFile := range out {
 		mediaFiles = append(mediaFiles, mediaFile)
-
-		return nil
-	})
-
-	if err != nil {
+	}
+	if err := <-errCh; err != nil {
 		return nil, fmt.Errorf("failed to scan directory: %w", err)
 	}
 
+	sortByWalkOrder(mediaFiles)
 	return mediaFiles, nil
 }
 
@@ -154,8 +201,20 @@ func (s *Scanner) parseFile(path, filename string) MediaFile {
 		Name:      filename,
 		Extension: ext,
 		ParentDir: parentDir,
+		MediaInfo: s.probeFile(path),
 	}
+	mediaFile.Hash, mediaFile.FileSize = s.hashFile(path)
 
+	s.classify(&mediaFile, nameWithoutExt)
+	if strings.EqualFold(ext, ".iso") {
+		s.applyDiscTitle(&mediaFile, path)
+	}
+	s.applyAltSearchQuery(&mediaFile)
+	return mediaFile
+}
+
+// classify sets the movie or TV series fields of a media file from its name without extension
+func (s *Scanner) classify(mediaFile *MediaFile, nameWithoutExt string) {
 	// Check if it's a TV series
 	season, episode, found := s.extractSeriesInfo(nameWithoutExt)
 	if found {
@@ -163,13 +222,17 @@ func (s *Scanner) parseFile(path, filename string) MediaFile {
 		mediaFile.Season = season
 		mediaFile.Episode = episode
 		mediaFile.CleanName = s.cleanSeriesName(nameWithoutExt)
+	} else if airDate := extractAirDate(nameWithoutExt); airDate != "" {
+		mediaFile.IsSeries = true
+		mediaFile.AirDate = airDate
+		mediaFile.CleanName = s.cleanSeriesName(nameWithoutExt)
 	} else {
 		mediaFile.IsMovie = true
 		mediaFile.Year = s.extractYear(nameWithoutExt)
 		mediaFile.CleanName = s.cleanMovieName(nameWithoutExt)
+		mediaFile.Edition = extractEdition(nameWithoutExt)
+		mediaFile.Version = extractVersionWithInfo(nameWithoutExt, mediaFile.MediaInfo)
 	}
-
-	return mediaFile
 }
 
 // extractSeriesInfo attempts to extract season and episode numbers from a filename
@@ -207,6 +270,7 @@ func (s *Scanner) cleanSeriesName(name string) string {
 	for _, pattern := range seriesPatterns {
 		name = pattern.ReplaceAllString(name, "")
 	}
+	name = removeAirDate(name)
 
 	// Remove common artifacts
 	name = removeCommonArtifacts(name)
@@ -235,8 +299,11 @@ func GetSeriesSearchQuery(parentDir string) string {
 	return strings.TrimSpace(name)
 }
 
-// cleanMovieName removes year and artifacts from a movie filename
+// cleanMovieName removes year, edition markers and artifacts from a movie filename
 func (s *Scanner) cleanMovieName(name string) string {
+	// Remove edition markers before separators are normalized
+	name = removeEditionMarkers(name)
+
 	// Remove year
 	name = yearPattern.ReplaceAllString(name, " ")
 
@@ -300,8 +367,14 @@ func (s *Scanner) parseMovieFolder(dirPath string) (MediaFile, bool) {
 	if err != nil {
 		return MediaFile{}, false
 	}
+	entries, _ = s.resolveEntries(dirPath, entries)
+	return s.parseMovieFolderEntries(dirPath, entries)
+}
 
-	var videoFiles []string
+// parseMovieFolderEntries checks if a directory, whose entries have already been read, is a movie folder and parses it
+func (s *Scanner) parseMovieFolderEntries(dirPath string, entries []os.DirEntry) (MediaFile, bool) {
+	var candidates []sizedVideo
+	var extras []ExtraFile
 	var subtitleFiles []string
 	var hasBluRay bool
 	var hasDVD bool
@@ -317,29 +390,50 @@ func (s *Scanner) parseMovieFolder(dirPath string) (MediaFile, bool) {
 		}
 	}
 
-	// Collect video and subtitle files
+	// Collect video, extras and subtitle files
 	for _, entry := range entries {
+		fullPath := filepath.Join(dirPath, entry.Name())
+
 		if entry.IsDir() {
+			if extraType := classifyExtraByFolder(entry.Name()); extraType != ExtraNone {
+				extras = append(extras, collectFolderExtras(fullPath, extraType)...)
+			}
 			continue
 		}
 
 		ext := strings.ToLower(filepath.Ext(entry.Name()))
-		fullPath := filepath.Join(dirPath, entry.Name())
 
 		if isVideoFile(ext) {
-			videoFiles = append(videoFiles, fullPath)
+			nameWithoutExt := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+			if extraType := classifyExtraByName(nameWithoutExt); extraType != ExtraNone {
+				extras = append(extras, ExtraFile{Path: fullPath, Type: extraType, Size: entrySize(entry)})
+				continue
+			}
+			candidates = append(candidates, sizedVideo{path: fullPath, size: entrySize(entry)})
 		} else if isSubtitleFile(ext) {
 			subtitleFiles = append(subtitleFiles, fullPath)
 		}
 	}
 
+	// Small secondary videos are samples; the largest remaining video is the main feature
+	candidates, samples := splitSamplesBySize(candidates, s.sampleThreshold)
+	extras = append(extras, samples...)
+	videoFiles := sortBySizeDesc(candidates)
+
 	// Determine if this is a movie folder:
 	// 1. Has Blu-ray or DVD structure, OR
 	// 2. Has video files + subtitle files, OR
-	// 3. Has exactly one video file and folder name looks like a movie (has year or matches video name)
+	// 3. Has exactly one video file and folder name looks like a movie (has year or matches video name), OR
+	// 4. Has video files that are all parts of one stacked movie (CD1, CD2...), OR
+	// 5. Has several video files that are all versions of the same movie
 	isMovieFolder := false
 	var mainVideoFile string
 
+	var partFiles []string
+	if stacks := groupStackedFiles(videoFiles); len(stacks) == 1 && len(stacks[0]) == len(videoFiles) {
+		partFiles = stacks[0]
+	}
+
 	if hasBluRay || hasDVD {
 		isMovieFolder = true
 		if len(videoFiles) > 0 {
@@ -348,6 +442,9 @@ func (s *Scanner) parseMovieFolder(dirPath string) (MediaFile, bool) {
 	} else if len(videoFiles) > 0 && len(subtitleFiles) > 0 {
 		isMovieFolder = true
 		mainVideoFile = videoFiles[0]
+	} else if len(partFiles) > 0 {
+		isMovieFolder = true
+		mainVideoFile = partFiles[0]
 	} else if len(videoFiles) == 1 {
 		// Check if folder name matches video file or contains year
 		folderName := filepath.Base(dirPath)
@@ -366,6 +463,9 @@ func (s *Scanner) parseMovieFolder(dirPath string) (MediaFile, bool) {
 			isMovieFolder = true
 			mainVideoFile = videoFiles[0]
 		}
+	} else if len(videoFiles) > 1 && s.areVersionsOfSameMovie(videoFiles) {
+		isMovieFolder = true
+		mainVideoFile = videoFiles[0]
 	}
 
 	if !isMovieFolder {
@@ -380,12 +480,36 @@ func (s *Scanner) parseMovieFolder(dirPath string) (MediaFile, bool) {
 	// If no year in folder name, try to get from main video file
 	if year == 0 && mainVideoFile != "" {
 		videoFileName := strings.TrimSuffix(filepath.Base(mainVideoFile), filepath.Ext(mainVideoFile))
+		if len(partFiles) > 0 {
+			videoFileName = stackBaseName(mainVideoFile)
+		}
 		year = s.extractYear(videoFileName)
 		if cleanName == "" || cleanName == filepath.Base(dirPath) {
 			cleanName = s.cleanMovieName(videoFileName)
 		}
 	}
 
+	mediaInfo := s.probeFile(mainVideoFile)
+	hash, fileSize := s.hashFile(mainVideoFile)
+	edition := extractEdition(folderName)
+	version := ""
+	if mainVideoFile != "" {
+		videoFileName := strings.TrimSuffix(filepath.Base(mainVideoFile), filepath.Ext(mainVideoFile))
+		if edition == "" {
+			edition = extractEdition(videoFileName)
+		}
+		version = extractVersionWithInfo(videoFileName, mediaInfo)
+	}
+
+	// Collect per-file versions when the folder holds several cuts or qualities of the movie
+	var versions []MovieVersion
+	if len(videoFiles) > 1 && len(partFiles) == 0 {
+		for _, videoFile := range videoFiles {
+			nameWithoutExt := strings.TrimSuffix(filepath.Base(videoFile), filepath.Ext(videoFile))
+			versions = append(versions, parseMovieVersion(videoFile, nameWithoutExt, s.probeFile(videoFile)))
+		}
+	}
+
 	// Determine extension from main video file
 	ext := ""
 	if mainVideoFile != "" {
@@ -396,7 +520,7 @@ func (s *Scanner) parseMovieFolder(dirPath string) (MediaFile, bool) {
 		ext = ".dvd"
 	}
 
-	return MediaFile{
+	movieFile := MediaFile{
 		Path:          dirPath,
 		Name:          folderName,
 		Extension:     ext,
@@ -410,7 +534,44 @@ func (s *Scanner) parseMovieFolder(dirPath string) (MediaFile, bool) {
 		IsBluRay:      hasBluRay,
 		IsDVD:         hasDVD,
 		ParentDir:     filepath.Base(filepath.Dir(dirPath)),
-	}, true
+		Edition:       edition,
+		Version:       version,
+		Versions:      versions,
+		PartFiles:     partFiles,
+		Extras:        extras,
+		MediaInfo:     s.stackedMediaInfo(mediaInfo, partFiles),
+		Hash:          hash,
+		FileSize:      fileSize,
+	}
+	s.applyDiscTitle(&movieFile, mainVideoFile)
+	s.applyAltSearchQuery(&movieFile)
+	return movieFile, true
+}
+
+// areVersionsOfSameMovie checks if all video files clean up to the same movie name and year
+func (s *Scanner) areVersionsOfSameMovie(videoFiles []string) bool {
+	var firstName string
+	var firstYear int
+
+	for i, videoFile := range videoFiles {
+		nameWithoutExt := strings.TrimSuffix(filepath.Base(videoFile), filepath.Ext(videoFile))
+		cleanName := strings.ToLower(s.cleanMovieName(nameWithoutExt))
+		year := s.extractYear(nameWithoutExt)
+
+		if cleanName == "" {
+			return false
+		}
+		if i == 0 {
+			firstName = cleanName
+			firstYear = year
+			continue
+		}
+		if cleanName != firstName || year != firstYear {
+			return false
+		}
+	}
+
+	return true
 }
 
 // GetSearchQuery returns the clean name suitable for API searches
@@ -445,18 +606,41 @@ func (m *MediaFile) GetEpisodeFilename(seriesName string, season, episode int, e
 	return fmt.Sprintf("%s %s%s%s", cleanSeriesName, seasonStr, episodeStr, m.Extension)
 }
 
-// GetMovieFilename generates a properly formatted filename for a movie
+// GetMovieFilename generates a properly formatted filename for a movie,
+// including the edition label when the file is a specific edition
 func (m *MediaFile) GetMovieFilename(title string, year string) string {
 	if m.IsMovie {
-		cleanTitle := utils.SanitizeFilename(title)
-		if year != "" {
-			return fmt.Sprintf("%s (%s)%s", cleanTitle, year, m.Extension)
+		ext := m.Extension
+		if strings.EqualFold(ext, ".iso") {
+			// Kodi disc image naming: "Title (Year).iso"
+			ext = ".iso"
 		}
-		return fmt.Sprintf("%s%s", cleanTitle, m.Extension)
+		return m.GetMovieVersionFilename(title, year, m.Edition, ext)
 	}
 	return m.Name
 }
 
+// GetMovieVersionFilename generates a Kodi/Jellyfin multi-version filename ("Title (Year) - Label.ext")
+// so that several versions of the same movie can share one movie folder
+func (m *MediaFile) GetMovieVersionFilename(title, year, label, ext string) string {
+	name := m.GetMovieFolderName(title, year)
+	if cleanLabel := utils.SanitizeFilename(label); cleanLabel != "" {
+		name = fmt.Sprintf("%s - %s", name, cleanLabel)
+	}
+	return name + ext
+}
+
+// GetMoviePartFilename generates a Kodi stacking filename ("Title (Year)-cd1.ext") for one part of a multi-part movie
+func (m *MediaFile) GetMoviePartFilename(title, year string, part int, ext string) string {
+	return m.GetMovieVersionPartFilename(title, year, m.Edition, part, ext)
+}
+
+// GetMovieVersionPartFilename generates the stacking filename ("Title (Year) - Label-cd1.ext") for one part
+// of a multi-part movie sharing its folder with other versions
+func (m *MediaFile) GetMovieVersionPartFilename(title, year, label string, part int, ext string) string {
+	return fmt.Sprintf("%s-cd%d%s", m.GetMovieVersionFilename(title, year, label, ""), part, ext)
+}
+
 // GetMovieFolderName generates a properly formatted folder name for a movie
 func (m *MediaFile) GetMovieFolderName(title string, year string) string {
 	if m.IsMovie {
@@ -0,0 +1,182 @@
+package scanner
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"kodi-renamer/internal/probe"
+)
+
+var (
+	// stackPattern matches Kodi-style stacking markers (cd1, dvd2, part1, pt2, disc1, partA...)
+	// capturing the text before the marker, the marker, the part identifier and the text after it
+	stackPattern = regexp.MustCompile(`(?i)^(.*?)[\s._-]*[\[(]?\b(cd|dvd|part|pt|disc|disk)[\s._-]*(\d{1,2}|[a-d])\b[\])]?(.*)$`)
+)
+
+// stackedFile holds the stacking information extracted from a single video file
+type stackedFile struct {
+	path string
+	base string
+	part int
+}
+
+// extractStackPart returns the stack base name (name without the part marker) and part number
+func extractStackPart(nameWithoutExt string) (base string, part int, found bool) {
+	base, _, part, found = parseStackMarker(nameWithoutExt)
+	return base, part, found
+}
+
+// parseStackMarker returns the stack base name, the lower-case marker word ("cd", "part"...) and the part number
+func parseStackMarker(nameWithoutExt string) (base, marker string, part int, found bool) {
+	matches := stackPattern.FindStringSubmatch(nameWithoutExt)
+	if len(matches) < 5 {
+		return "", "", 0, false
+	}
+
+	partID := strings.ToLower(matches[3])
+	if n, err := strconv.Atoi(partID); err == nil {
+		part = n
+	} else {
+		part = int(partID[0]-'a') + 1
+	}
+
+	base = strings.TrimSpace(matches[1] + " " + matches[4])
+	if base == "" {
+		return "", "", 0, false
+	}
+
+	return base, strings.ToLower(matches[2]), part, true
+}
+
+// groupStackedFiles groups video files of the same directory that are parts of one stacked movie.
+// Only groups with at least two distinct parts are returned, each ordered by part number.
+func groupStackedFiles(paths []string) [][]string {
+	groups := make(map[string][]stackedFile)
+	var keys []string
+
+	for _, path := range paths {
+		ext := filepath.Ext(path)
+		nameWithoutExt := strings.TrimSuffix(filepath.Base(path), ext)
+
+		base, marker, part, found := parseStackMarker(nameWithoutExt)
+		if !found {
+			continue
+		}
+
+		key := filepath.Dir(path) + "|" + strings.ToLower(base) + "|" + strings.ToLower(ext)
+		if marker == "part" || marker == "pt" {
+			// "Part 1", "Part 2" also title sequels ("Deathly Hallows Part 1"): only stack them when
+			// the names are identical apart from the marker and either carry a year or are dot-style
+			// release names ("Movie.Part1"), so that differing years or titled sequels stay apart
+			if !yearPattern.MatchString(base) && strings.ContainsRune(nameWithoutExt, ' ') {
+				continue
+			}
+			key = filepath.Dir(path) + "|" + marker + "|" + base + "|" + strings.ToLower(ext)
+		}
+		if _, exists := groups[key]; !exists {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], stackedFile{path: path, base: base, part: part})
+	}
+
+	var stacks [][]string
+	for _, key := range keys {
+		files := groups[key]
+		sort.SliceStable(files, func(i, j int) bool {
+			return files[i].part < files[j].part
+		})
+
+		// A lone "Part 2" is more likely a sequel title than a stacked file
+		distinct := true
+		for i := 1; i < len(files); i++ {
+			if files[i].part == files[i-1].part {
+				distinct = false
+				break
+			}
+		}
+		if len(files) < 2 || !distinct {
+			continue
+		}
+
+		stack := make([]string, 0, len(files))
+		for _, f := range files {
+			stack = append(stack, f.path)
+		}
+		stacks = append(stacks, stack)
+	}
+
+	return stacks
+}
+
+// stackBaseName returns the name of a stacked video file without its part marker and extension
+func stackBaseName(path string) string {
+	nameWithoutExt := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if base, _, found := extractStackPart(nameWithoutExt); found {
+		return base
+	}
+	return nameWithoutExt
+}
+
+// stackedMediaInfo returns the media info of a stacked movie, whose duration is the sum of all its parts
+func (s *Scanner) stackedMediaInfo(first *probe.Info, parts []string) *probe.Info {
+	if first == nil || len(parts) < 2 {
+		return first
+	}
+
+	total := *first
+	for _, part := range parts[1:] {
+		if info := s.probeFile(part); info != nil {
+			total.Duration += info.Duration
+		}
+	}
+	return &total
+}
+
+// stackMovieParts merges standalone movie files that are parts of the same stacked movie
+// into a single MediaFile so that the movie is searched and renamed only once
+func (s *Scanner) stackMovieParts(mediaFiles []MediaFile) []MediaFile {
+	var standalonePaths []string
+	for _, file := range mediaFiles {
+		if file.IsMovie && !file.IsMovieFolder {
+			standalonePaths = append(standalonePaths, file.Path)
+		}
+	}
+
+	stacks := groupStackedFiles(standalonePaths)
+	if len(stacks) == 0 {
+		return mediaFiles
+	}
+
+	stackOf := make(map[string][]string)
+	for _, stack := range stacks {
+		for _, path := range stack {
+			stackOf[path] = stack
+		}
+	}
+
+	result := make([]MediaFile, 0, len(mediaFiles))
+	for _, file := range mediaFiles {
+		stack, stacked := stackOf[file.Path]
+		if !stacked {
+			result = append(result, file)
+			continue
+		}
+
+		// Emit the merged movie once, at the position of its first part
+		if file.Path != stack[0] {
+			continue
+		}
+
+		base := stackBaseName(file.Path)
+		file.CleanName = s.cleanMovieName(base)
+		file.Year = s.extractYear(base)
+		file.PartFiles = stack
+		file.MediaInfo = s.stackedMediaInfo(file.MediaInfo, stack)
+		result = append(result, file)
+	}
+
+	return result
+}
@@ -0,0 +1,131 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractStackPart(t *testing.T) {
+	tests := []struct {
+		input string
+		base  string
+		part  int
+		found bool
+	}{
+		{"Movie.2001.CD1", "Movie.2001", 1, true},
+		{"Movie (2001) part2", "Movie (2001)", 2, true},
+		{"Movie.2001.DVD.B.XviD", "Movie.2001 .XviD", 2, true},
+		{"Movie [pt 3]", "Movie", 3, true},
+		{"Movie.2001.DVDRip", "", 0, false},
+		{"Movie.Script.2001", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			base, part, found := extractStackPart(tt.input)
+			if base != tt.base || part != tt.part || found != tt.found {
+				t.Errorf("extractStackPart(%q) = (%q, %d, %v); want (%q, %d, %v)",
+					tt.input, base, part, found, tt.base, tt.part, tt.found)
+			}
+		})
+	}
+}
+
+func TestScanDirectoryStacksStandaloneParts(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"Movie.2001.CD1.avi", "Movie.2001.CD2.avi", "Harry.Potter.Part.2.2011.mkv"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := NewScanner(root).ScanDirectory()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 2 {
+		t.Fatalf("ScanDirectory() returned %d files; want 2", len(files))
+	}
+
+	var stacked *MediaFile
+	for i := range files {
+		if len(files[i].PartFiles) > 0 {
+			stacked = &files[i]
+		}
+	}
+	if stacked == nil {
+		t.Fatal("expected a stacked movie")
+	}
+	if len(stacked.PartFiles) != 2 || stacked.CleanName != "Movie" || stacked.Year != 2001 {
+		t.Errorf("unexpected stacked movie: parts=%v clean=%q year=%d", stacked.PartFiles, stacked.CleanName, stacked.Year)
+	}
+	if got := stacked.GetMoviePartFilename("Movie", "2001", 2, ".avi"); got != "Movie (2001)-cd2.avi" {
+		t.Errorf("GetMoviePartFilename() = %q", got)
+	}
+}
+
+func TestGroupStackedFilesKeepsSequelsApart(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  int // Files in the stack, 0 when none
+	}{
+		{"sequels without year", []string{
+			"Harry Potter and the Deathly Hallows Part 1.mkv",
+			"Harry Potter and the Deathly Hallows Part 2.mkv",
+		}, 0},
+		{"sequels of different years", []string{
+			"Harry.Potter.and.the.Deathly.Hallows.Part.1.2010.mkv",
+			"Harry.Potter.and.the.Deathly.Hallows.Part.2.2011.mkv",
+		}, 0},
+		{"parts with different names", []string{
+			"Movie.2001.Part1.Director.avi",
+			"movie.2001.part2.director.avi",
+		}, 0},
+		{"parts of one movie", []string{"Movie.2001.Part1.avi", "Movie.2001.Part2.avi"}, 2},
+		{"part parts without year", []string{"Movie.Part1.avi", "Movie.Part2.avi"}, 2},
+		{"pt parts with different names", []string{"Movie.pt1.Director.avi", "movie.pt2.director.avi"}, 0},
+		{"cd parts without year", []string{"Movie.CD1.avi", "Movie.CD2.avi"}, 2},
+		{"pt parts without year", []string{"Movie.pt1.avi", "Movie.pt2.avi"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			for _, file := range tt.files {
+				paths = append(paths, filepath.Join("movies", file))
+			}
+			stacks := groupStackedFiles(paths)
+			switch {
+			case tt.want == 0 && len(stacks) != 0:
+				t.Errorf("groupStackedFiles() = %v, want no stack", stacks)
+			case tt.want > 0 && (len(stacks) != 1 || len(stacks[0]) != tt.want):
+				t.Errorf("groupStackedFiles() = %v, want one stack of %d files", stacks, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanDirectoryKeepsSequelsApart(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"Harry Potter and the Deathly Hallows Part 1.mkv", "Harry Potter and the Deathly Hallows Part 2.mkv"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := NewScanner(root).ScanDirectory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("ScanDirectory() returned %d files; want the 2 sequels", len(files))
+	}
+	for _, file := range files {
+		if len(file.PartFiles) > 0 {
+			t.Errorf("%s stacked with %v", file.Name, file.PartFiles)
+		}
+	}
+}
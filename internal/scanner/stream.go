@@ -0,0 +1,328 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"kodi-renamer/internal/archive"
+)
+
+// DefaultScanConcurrency is the default number of directories read and classified in parallel
+const DefaultScanConcurrency = 8
+
+// ScanProgress is a snapshot of the counters of a running or finished scan
+type ScanProgress struct {
+	Directories int64 // Directories read
+	Files       int64 // Directory entries seen that are not directories
+	MediaFiles  int64 // Media files (or movie folders) emitted
+	Skipped     int64 // Files and directories skipped by ignore rules and filters
+}
+
+// scanCounters holds the live progress counters of a scan, updated from several goroutines
+type scanCounters struct {
+	directories atomic.Int64
+	files       atomic.Int64
+	mediaFiles  atomic.Int64
+	skipped     atomic.Int64
+}
+
+// dirJob is a directory waiting to be read, with the ignore rules of its parent
+type dirJob struct {
+	path     string
+	rules    []ignoreRule
+	viaLink  bool          // Reached through a symlinked directory
+	override *TypeOverride // Media type forced by the nearest .kodi-type marker
+}
+
+// SetConcurrency sets how many directories are read and classified in parallel
+func (s *Scanner) SetConcurrency(n int) {
+	if n < 1 {
+		n = 1
+	}
+	s.concurrency = n
+}
+
+// Progress returns the progress counters of the current or last scan; it is safe to call during Stream
+func (s *Scanner) Progress() ScanProgress {
+	return ScanProgress{
+		Directories: s.counters.directories.Load(),
+		Files:       s.counters.files.Load(),
+		MediaFiles:  s.counters.mediaFiles.Load(),
+		Skipped:     s.counters.skipped.Load(),
+	}
+}
+
+// Stream scans the root directory and sends every media file to out as soon as its directory
+// has been classified, reading up to the configured number of directories in parallel.
+// The order of the files is not deterministic. out is closed when the scan ends; the scan stops
+// early when ctx is cancelled or a directory cannot be read, and the first error is returned.
+func (s *Scanner) Stream(ctx context.Context, out chan<- MediaFile) error {
+	defer close(out)
+
+	s.counters.directories.Store(0)
+	s.counters.files.Store(0)
+	s.counters.mediaFiles.Store(0)
+	s.counters.skipped.Store(0)
+	s.skippedMu.Lock()
+	s.skipped = nil
+	s.dangling = nil
+	s.skippedMu.Unlock()
+	s.visitedMu.Lock()
+	s.visited = make(map[string]string)
+	s.visitedMu.Unlock()
+	s.realRoot, _ = filepath.EvalSymlinks(s.rootPath)
+
+	var rootRules []ignoreRule
+	if s.useIgnoreFiles {
+		ownRules, err := loadIgnoreFile(s.rootPath)
+		if err != nil {
+			return err
+		}
+		rootRules = append(s.defaultIgnoreRules(), ownRules...)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+		slots    = make(chan struct{}, s.concurrency)
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	var visit func(job dirJob)
+	visit = func(job dirJob) {
+		defer wg.Done()
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		subdirs, err := s.scanDir(ctx, job, out)
+		<-slots
+
+		if err != nil {
+			fail(err)
+			return
+		}
+		for _, subdir := range subdirs {
+			wg.Add(1)
+			go visit(subdir)
+		}
+	}
+
+	wg.Add(1)
+	go visit(dirJob{path: s.rootPath, rules: rootRules})
+	wg.Wait()
+
+	s.skippedMu.Lock()
+	sort.Slice(s.skipped, func(i, j int) bool { return s.skipped[i].Path < s.skipped[j].Path })
+	sort.Slice(s.dangling, func(i, j int) bool { return s.dangling[i].Path < s.dangling[j].Path })
+	s.skippedMu.Unlock()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// scanDir reads one directory, emits the media it contains and returns the subdirectories to scan
+func (s *Scanner) scanDir(ctx context.Context, job dirJob, out chan<- MediaFile) ([]dirJob, error) {
+	if reason := s.enterDir(job); reason != "" {
+		s.addSkipped(job.path, reason)
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(job.path)
+	if err != nil {
+		return nil, err
+	}
+	s.counters.directories.Add(1)
+	entries, linkedDirs := s.resolveEntries(job.path, entries)
+
+	rules := job.rules
+	if s.useIgnoreFiles && job.path != s.rootPath {
+		ownRules, err := loadIgnoreFile(job.path)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(append([]ignoreRule(nil), job.rules...), ownRules...)
+	}
+
+	override := job.override
+	if ownOverride, err := loadTypeOverride(job.path); err != nil {
+		s.addSkipped(filepath.Join(job.path, TypeOverrideFileName), err.Error())
+	} else if ownOverride != nil {
+		override = ownOverride
+	}
+
+	// A movie folder is emitted as a whole and not descended into, unless it is marked as series
+	if job.path != s.rootPath && (override == nil || override.Kind == KindMovie) {
+		folderEntries, folderSkipped := s.movieFolderEntries(job.path, entries, rules)
+		if movieFile, isMovieFolder := s.parseMovieFolderEntries(job.path, folderEntries); isMovieFolder {
+			for _, skipped := range folderSkipped {
+				s.addSkipped(skipped.Path, skipped.Reason)
+			}
+			movieFile.TypeOverride = override
+			return nil, s.emit(ctx, out, []MediaFile{movieFile})
+		}
+	}
+
+	var subdirs []dirJob
+	var mediaFiles []MediaFile
+	var standaloneExtras []ExtraFile
+	var archiveVolumes []string
+
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		path := filepath.Join(job.path, entry.Name())
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if !entry.IsDir() {
+			s.counters.files.Add(1)
+		}
+		if !entry.IsDir() && !isVideoFile(ext) {
+			if s.scanArchives && archive.IsVolume(entry.Name()) {
+				archiveVolumes = s.addArchiveVolume(archiveVolumes, path, entry, rules)
+			}
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if reason := s.skipReason(path, info, rules); reason != "" {
+			s.addSkipped(path, reason)
+			continue
+		}
+
+		if entry.IsDir() {
+			// Extras folders outside of a movie folder must not be mistaken for movies
+			if !isExtraFolder(entry.Name()) {
+				subdirs = append(subdirs, dirJob{path: path, rules: rules, viaLink: job.viaLink || linkedDirs[entry.Name()], override: override})
+			}
+			continue
+		}
+
+		// Trailers, samples and other extras are attached to their movie below
+		nameWithoutExt := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if extraType := classifyExtraByName(nameWithoutExt); extraType != ExtraNone {
+			standaloneExtras = append(standaloneExtras, ExtraFile{Path: path, Type: extraType, Size: info.Size()})
+			continue
+		}
+
+		if reason := s.sizeSkipReason(info); reason != "" {
+			s.addSkipped(path, reason)
+			continue
+		}
+
+		// This is a standalone video file or series episode
+		mediaFiles = append(mediaFiles, s.parseFile(path, entry.Name()))
+	}
+
+	// Extras and stacked parts always sit next to their movie, so each directory is complete on its own
+	mediaFiles = attachStandaloneExtras(mediaFiles, standaloneExtras)
+	mediaFiles = append(s.stackMovieParts(mediaFiles), s.archivedMediaFiles(job.path, archiveVolumes)...)
+	return subdirs, s.emit(ctx, out, s.applyTypeOverrides(mediaFiles, override))
+}
+
+// movieFolderEntries drops from a directory listing the subdirectories and videos that ignore rules
+// and filters skip, so that they can neither become the main video of a movie folder nor count as
+// one of its parts or versions; the dropped paths are returned with the reason
+func (s *Scanner) movieFolderEntries(dirPath string, entries []os.DirEntry, rules []ignoreRule) ([]os.DirEntry, []SkippedPath) {
+	kept := make([]os.DirEntry, 0, len(entries))
+	var skipped []SkippedPath
+
+	for _, entry := range entries {
+		if !entry.IsDir() && !isVideoFile(strings.ToLower(filepath.Ext(entry.Name()))) {
+			kept = append(kept, entry)
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dirPath, entry.Name())
+		reason := s.skipReason(path, info, rules)
+		if reason == "" && !entry.IsDir() {
+			// As for standalone files, the minimum size does not apply to extras such as samples
+			nameWithoutExt := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+			if classifyExtraByName(nameWithoutExt) == ExtraNone {
+				reason = s.sizeSkipReason(info)
+			}
+		}
+		if reason != "" {
+			skipped = append(skipped, SkippedPath{Path: path, Reason: reason})
+			continue
+		}
+		kept = append(kept, entry)
+	}
+
+	return kept, skipped
+}
+
+// addArchiveVolume appends an archive volume name to the list unless ignore rules or filters skip it
+func (s *Scanner) addArchiveVolume(volumes []string, path string, entry os.DirEntry, rules []ignoreRule) []string {
+	info, err := entry.Info()
+	if err != nil {
+		return volumes
+	}
+	if reason := s.skipReason(path, info, rules); reason != "" {
+		s.addSkipped(path, reason)
+		return volumes
+	}
+	return append(volumes, entry.Name())
+}
+
+// emit sends media files to the output channel unless the scan is cancelled
+func (s *Scanner) emit(ctx context.Context, out chan<- MediaFile, mediaFiles []MediaFile) error {
+	for _, mediaFile := range mediaFiles {
+		select {
+		case out <- mediaFile:
+			s.counters.mediaFiles.Add(1)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// addSkipped records a skipped path; it is called from several goroutines
+func (s *Scanner) addSkipped(path, reason string) {
+	s.counters.skipped.Add(1)
+	s.skippedMu.Lock()
+	s.skipped = append(s.skipped, SkippedPath{Path: path, Reason: reason})
+	s.skippedMu.Unlock()
+}
+
+// sortByWalkOrder sorts media files the way a sequential directory walk visits them:
+// path component by component, in lexical order
+func sortByWalkOrder(mediaFiles []MediaFile) {
+	sort.SliceStable(mediaFiles, func(i, j int) bool {
+		a := strings.Split(filepath.ToSlash(mediaFiles[i].Path), "/")
+		b := strings.Split(filepath.ToSlash(mediaFiles[j].Path), "/")
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+}
@@ -0,0 +1,117 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeEpisodes creates a series tree with the given number of show folders of ten episodes each
+func writeEpisodes(t *testing.T, root string, shows int) {
+	t.Helper()
+	for show := 1; show <= shows; show++ {
+		dir := filepath.Join(root, fmt.Sprintf("Show %02d", show), "Season 1")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for episode := 1; episode <= 10; episode++ {
+			name := fmt.Sprintf("Show.%02d.S01E%02d.mkv", show, episode)
+			if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	root := t.TempDir()
+	writeEpisodes(t, root, 5)
+
+	s := NewScanner(root)
+	s.SetConcurrency(3)
+	s.SetProbeFiles(false)
+	s.SetHashFiles(false)
+
+	out := make(chan MediaFile)
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.Stream(context.Background(), out) }()
+
+	seen := make(map[string]bool)
+	for mediaFile := range out {
+		if seen[mediaFile.Path] {
+			t.Errorf("%s emitted twice", mediaFile.Path)
+		}
+		seen[mediaFile.Path] = true
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("Stream() error: %v", err)
+	}
+
+	if len(seen) != 50 {
+		t.Errorf("emitted %d files, want 50", len(seen))
+	}
+	progress := s.Progress()
+	if progress.MediaFiles != 50 || progress.Files != 50 || progress.Directories != 11 {
+		t.Errorf("Progress() = %+v", progress)
+	}
+}
+
+func TestStreamCancel(t *testing.T) {
+	root := t.TempDir()
+	writeEpisodes(t, root, 5)
+
+	s := NewScanner(root)
+	s.SetProbeFiles(false)
+	s.SetHashFiles(false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan MediaFile)
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.Stream(ctx, out) }()
+
+	<-out
+	cancel()
+	for range out {
+	}
+
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("Stream() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestScanDirectoryWalkOrder(t *testing.T) {
+	root := t.TempDir()
+	writeEpisodes(t, root, 3)
+
+	s := NewScanner(root)
+	s.SetProbeFiles(false)
+	s.SetHashFiles(false)
+
+	mediaFiles, err := s.ScanDirectory()
+	if err != nil {
+		t.Fatalf("ScanDirectory() error: %v", err)
+	}
+
+	var walked []string
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			walked = append(walked, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mediaFiles) != len(walked) {
+		t.Fatalf("ScanDirectory() returned %d files, want %d", len(mediaFiles), len(walked))
+	}
+	for i := range walked {
+		if mediaFiles[i].Path != walked[i] {
+			t.Errorf("file %d = %s, want %s", i, mediaFiles[i].Path, walked[i])
+		}
+	}
+}
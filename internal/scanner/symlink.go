@@ -0,0 +1,103 @@
+package scanner
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DanglingLink is a symbolic link whose target does not exist
+type DanglingLink struct {
+	Path   string
+	Target string
+}
+
+// SetFollowSymlinks enables descending into symlinked directories; symlinked files are always
+// scanned through their target
+func (s *Scanner) SetFollowSymlinks(enabled bool) {
+	s.followSymlinks = enabled
+}
+
+// DanglingLinks returns the symbolic links with a missing target found by the last scan
+func (s *Scanner) DanglingLinks() []DanglingLink {
+	s.skippedMu.Lock()
+	defer s.skippedMu.Unlock()
+	return append([]DanglingLink(nil), s.dangling...)
+}
+
+// addDangling records a dangling link; it is called from several goroutines
+func (s *Scanner) addDangling(path string) {
+	target, _ := os.Readlink(path)
+	s.skippedMu.Lock()
+	s.dangling = append(s.dangling, DanglingLink{Path: path, Target: target})
+	s.skippedMu.Unlock()
+}
+
+// resolveEntries replaces symbolic links by entries describing their targets (under the link
+// name), so that sizes and directory checks see the real file. Dangling links are reported and
+// dropped; symlinked directories are dropped unless symlinks are followed. The names of the
+// symlinked directories kept are returned.
+func (s *Scanner) resolveEntries(dirPath string, entries []os.DirEntry) ([]os.DirEntry, map[string]bool) {
+	resolved := make([]os.DirEntry, 0, len(entries))
+	linkedDirs := make(map[string]bool)
+
+	for _, entry := range entries {
+		if entry.Type()&os.ModeSymlink == 0 {
+			resolved = append(resolved, entry)
+			continue
+		}
+
+		path := filepath.Join(dirPath, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil {
+			s.addDangling(path)
+			continue
+		}
+		if info.IsDir() {
+			if !s.followSymlinks {
+				s.addSkipped(path, "symlinked directory (symlinks are not followed)")
+				continue
+			}
+			linkedDirs[entry.Name()] = true
+		}
+		resolved = append(resolved, fs.FileInfoToDirEntry(info))
+	}
+
+	return resolved, linkedDirs
+}
+
+// enterDir registers a directory as visited, by device and inode, and returns why it must not be
+// scanned: it was reached through a symlink loop, or is the target of a symlink already scanned.
+// Directories are only tracked while following symlinks.
+func (s *Scanner) enterDir(job dirJob) string {
+	if !s.followSymlinks {
+		return ""
+	}
+
+	info, err := os.Stat(job.path)
+	if err != nil {
+		return ""
+	}
+	id := dirIdentity(job.path, info)
+
+	if job.viaLink {
+		// A link into the scan root would only scan the same files twice
+		if target, err := filepath.EvalSymlinks(job.path); err == nil && s.realRoot != "" && isWithin(target, s.realRoot) {
+			return "symlink to a directory inside the scan root"
+		}
+	}
+
+	s.visitedMu.Lock()
+	defer s.visitedMu.Unlock()
+	if first, seen := s.visited[id]; seen {
+		return "symlink loop: already scanned as " + first
+	}
+	s.visited[id] = job.path
+	return ""
+}
+
+// isWithin reports whether path is dir or a path below it
+func isWithin(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
@@ -0,0 +1,73 @@
+//go:build unix
+
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanFollowsSymlinks(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "inbox")
+	downloads := filepath.Join(base, "downloads")
+	showDir := filepath.Join(downloads, "Show", "Season 1")
+	for _, dir := range []string{root, showDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(showDir, "Show.S01E01.mkv"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	links := map[string]string{
+		filepath.Join(root, "Show"):                 filepath.Join(downloads, "Show"),
+		filepath.Join(showDir, "loop"):              filepath.Join(downloads, "Show"),
+		filepath.Join(root, "Movie.2010.mkv"):       filepath.Join(downloads, "missing.mkv"),
+		filepath.Join(root, "Other.Movie.2011.mkv"): filepath.Join(showDir, "Show.S01E01.mkv"),
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewScanner(root)
+	s.SetProbeFiles(false)
+	s.SetHashFiles(false)
+
+	files, err := s.ScanDirectory()
+	if err != nil {
+		t.Fatalf("ScanDirectory() error: %v", err)
+	}
+	if len(files) != 1 || files[0].Name != "Other.Movie.2011.mkv" {
+		t.Errorf("without following, files = %v", files)
+	}
+	if dangling := s.DanglingLinks(); len(dangling) != 1 || dangling[0].Path != filepath.Join(root, "Movie.2010.mkv") {
+		t.Errorf("DanglingLinks() = %v", dangling)
+	}
+
+	s.SetFollowSymlinks(true)
+	files, err = s.ScanDirectory()
+	if err != nil {
+		t.Fatalf("ScanDirectory() error: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("following symlinks, got %d files: %v", len(files), files)
+	}
+	if files[1].Path != filepath.Join(root, "Show", "Season 1", "Show.S01E01.mkv") {
+		t.Errorf("episode path = %s", files[1].Path)
+	}
+
+	loopSkipped := false
+	for _, skip := range s.Skipped() {
+		if skip.Path == filepath.Join(root, "Show", "Season 1", "loop") {
+			loopSkipped = true
+		}
+	}
+	if !loopSkipped {
+		t.Errorf("symlink loop not reported: %v", s.Skipped())
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package scanstate
+
+import "os"
+
+// inode returns 0 on platforms without inode numbers; size and modification time are compared instead
+func inode(info os.FileInfo) uint64 {
+	return 0
+}
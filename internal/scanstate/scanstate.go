@@ -0,0 +1,252 @@
+package scanstate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Decision is the outcome of the last run for a scanned item
+type Decision string
+
+const (
+	// DecisionRenamed means the item was identified and renamed or moved
+	DecisionRenamed Decision = "renamed"
+	// DecisionSkipped means the user skipped the item (or no match was found)
+	DecisionSkipped Decision = "skipped"
+	// DecisionFailed means processing the item failed
+	DecisionFailed Decision = "failed"
+	// DecisionIgnored means the user asked never to be asked about the item again, even if it changes
+	DecisionIgnored Decision = "ignored"
+)
+
+// Entry is the persisted state of a scanned file or movie folder
+type Entry struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mod_time"`
+	Inode    uint64    `json:"inode,omitempty"`
+	Kind     string    `json:"kind"`
+	Decision Decision  `json:"decision"`
+	Error    string    `json:"error,omitempty"`
+	// SearchVariant describes the fallback query that found the item, empty for the original query
+	SearchVariant string    `json:"search_variant,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Store persists the scan state of every item presented to the user, as a JSON file, so that
+// later runs only present new or changed items
+type Store struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]Entry
+	dirty   bool
+}
+
+// DefaultPath returns the default location of the scan state in the user configuration directory
+func DefaultPath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "kodi-renamer", "scan-state.json")
+}
+
+// Open loads the scan state at path, starting empty if the file does not exist yet
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path:    path,
+		entries: make(map[string]Entry),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scan state: %w", err)
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode scan state: %w", err)
+	}
+	for _, entry := range entries {
+		s.entries[entry.Path] = entry
+	}
+
+	return s, nil
+}
+
+// key returns the absolute form of a path, so that entries do not depend on the working directory
+func key(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// fingerprint fills the size, modification time and inode of an entry from the file system
+func fingerprint(entry *Entry) error {
+	info, err := os.Stat(entry.Path)
+	if err != nil {
+		return err
+	}
+	entry.Size = info.Size()
+	entry.ModTime = info.ModTime()
+	entry.Inode = inode(info)
+	return nil
+}
+
+// Record stores the decision taken for an item; the fingerprint is read from the file system
+// and left empty when the item no longer exists at path (for example after being moved)
+func (s *Store) Record(path, kind string, decision Decision, errMsg string) {
+	path = key(path)
+	entry := Entry{
+		Path:      path,
+		Kind:      kind,
+		Decision:  decision,
+		Error:     errMsg,
+		UpdatedAt: time.Now(),
+	}
+	_ = fingerprint(&entry)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[path] = entry
+	s.dirty = true
+}
+
+// SetSearchVariant notes on the recorded entry of a path which fallback query found it
+func (s *Store) SetSearchVariant(path, variant string) {
+	path = key(path)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[path]
+	if !ok || entry.SearchVariant == variant {
+		return
+	}
+	entry.SearchVariant = variant
+	s.entries[path] = entry
+	s.dirty = true
+}
+
+// Lookup returns the recorded state of a path
+func (s *Store) Lookup(path string) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key(path)]
+	return entry, ok
+}
+
+// Unchanged reports whether the item at the entry's path still has the recorded size, modification time and inode
+func (e Entry) Unchanged() bool {
+	current := Entry{Path: e.Path}
+	if err := fingerprint(&current); err != nil {
+		return false
+	}
+	return current.Size == e.Size && current.ModTime.Equal(e.ModTime) && current.Inode == e.Inode
+}
+
+// ShouldSkip reports whether an item was already handled and must not be presented again:
+// ignored items are always skipped, other decisions only while the item is unchanged
+func (s *Store) ShouldSkip(path string) (Entry, bool) {
+	entry, ok := s.Lookup(path)
+	if !ok {
+		return Entry{}, false
+	}
+	if entry.Decision == DecisionIgnored {
+		return entry, true
+	}
+	return entry, entry.Unchanged()
+}
+
+// Entries returns all recorded entries sorted by path
+func (s *Store) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]Entry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries
+}
+
+// Reset forgets every entry and returns how many were removed
+func (s *Store) Reset() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := len(s.entries)
+	s.entries = make(map[string]Entry)
+	s.dirty = true
+	return count
+}
+
+// Forget removes the entries of a path and everything below it, returning how many were removed
+func (s *Store) Forget(path string) int {
+	path = key(path)
+	prefix := path + string(filepath.Separator)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for entryPath := range s.entries {
+		if entryPath == path || strings.HasPrefix(entryPath, prefix) {
+			delete(s.entries, entryPath)
+			count++
+		}
+	}
+	if count > 0 {
+		s.dirty = true
+	}
+	return count
+}
+
+// Save writes the scan state back to disk if it changed, atomically replacing the previous file
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+
+	entries := make([]Entry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode scan state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create scan state directory: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write scan state: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to replace scan state: %w", err)
+	}
+
+	s.dirty = false
+	return nil
+}
@@ -0,0 +1,125 @@
+package scanstate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreRoundTripAndShouldSkip(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state", "scan-state.json")
+	movie := filepath.Join(dir, "Movie.2010.mkv")
+	writeFile(t, movie, "video")
+
+	store, err := Open(statePath)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if _, skip := store.ShouldSkip(movie); skip {
+		t.Fatal("ShouldSkip() = true for an unknown item")
+	}
+
+	store.Record(movie, "movie", DecisionSkipped, "")
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	reopened, err := Open(statePath)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	entry, skip := reopened.ShouldSkip(movie)
+	if !skip {
+		t.Fatal("ShouldSkip() = false for an unchanged skipped item")
+	}
+	if entry.Kind != "movie" || entry.Decision != DecisionSkipped || entry.Size != 5 {
+		t.Errorf("entry = %+v", entry)
+	}
+
+	// A changed file is presented again
+	writeFile(t, movie, "a longer video")
+	if _, skip := reopened.ShouldSkip(movie); skip {
+		t.Error("ShouldSkip() = true after the file changed")
+	}
+}
+
+func TestShouldSkipIgnoredEvenWhenChanged(t *testing.T) {
+	dir := t.TempDir()
+	movie := filepath.Join(dir, "Movie.mkv")
+	writeFile(t, movie, "video")
+
+	store, _ := Open(filepath.Join(dir, "state.json"))
+	store.Record(movie, "movie", DecisionIgnored, "")
+
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(movie, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, skip := store.ShouldSkip(movie); !skip {
+		t.Error("ShouldSkip() = false for an ignored item")
+	}
+}
+
+func TestForgetAndReset(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := Open(filepath.Join(dir, "state.json"))
+
+	show := filepath.Join(dir, "Show")
+	store.Record(filepath.Join(show, "S01E01.mkv"), "episode", DecisionFailed, "boom")
+	store.Record(filepath.Join(show, "S01E02.mkv"), "episode", DecisionRenamed, "")
+	store.Record(filepath.Join(dir, "Show 2", "S01E01.mkv"), "episode", DecisionSkipped, "")
+	store.Record(filepath.Join(dir, "Movie.mkv"), "movie", DecisionSkipped, "")
+
+	if n := store.Forget(show); n != 2 {
+		t.Errorf("Forget() = %d, want 2", n)
+	}
+	if entries := store.Entries(); len(entries) != 2 {
+		t.Fatalf("Entries() = %d entries, want 2", len(entries))
+	}
+	if n := store.Reset(); n != 2 {
+		t.Errorf("Reset() = %d, want 2", n)
+	}
+	if entries := store.Entries(); len(entries) != 0 {
+		t.Errorf("Entries() after Reset() = %v", entries)
+	}
+}
+
+func TestKeysIndependentOfWorkingDirectory(t *testing.T) {
+	// The working directory is reported with symlinks resolved
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "Movie.mkv"), "video")
+	store, _ := Open(filepath.Join(dir, "state.json"))
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	store.Record("Movie.mkv", "movie", DecisionSkipped, "")
+
+	entries := store.Entries()
+	if len(entries) != 1 || !filepath.IsAbs(entries[0].Path) {
+		t.Fatalf("Entries() = %+v, want one absolute path", entries)
+	}
+	if _, skip := store.ShouldSkip(filepath.Join(dir, "Movie.mkv")); !skip {
+		t.Error("ShouldSkip() = false for the absolute path of an item recorded by a relative one")
+	}
+	if n := store.Forget("."); n != 1 {
+		t.Errorf("Forget(\".\") = %d, want 1", n)
+	}
+}
@@ -1,51 +1,71 @@
 package tmdb
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
-	"time"
+	"strings"
+
+	"kodi-renamer/internal/httpclient"
 )
 
 const (
 	// BaseURL is the base URL for The Movie Database (TMDb) API v3
 	BaseURL = "https://api.themoviedb.org/3"
+	// RequestsPerSecond stays below TMDb's documented limit of around 50 requests per second
+	RequestsPerSecond = 40
+	// RequestBurst is how many requests may be sent at once before the rate limit applies
+	RequestBurst = 20
 )
 
 // Client represents a TMDb API client
 type Client struct {
 	apiKey     string
-	httpClient *http.Client
+	baseURL    string
+	httpClient *httpclient.Client
 }
 
 // NewClient creates a new TMDb API client with the provided API key
 func NewClient(apiKey string) *Client {
+	httpClient := httpclient.New("tmdb", RequestsPerSecond, RequestBurst)
+	httpClient.AddSecret(apiKey)
 	return &Client{
-		apiKey: apiKey,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		apiKey:     apiKey,
+		baseURL:    BaseURL,
+		httpClient: httpClient,
 	}
 }
 
+// SetBaseURL points the client at another TMDb-compatible API, such as a local fake server in tests
+func (c *Client) SetBaseURL(baseURL string) {
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+// HTTP returns the shared HTTP layer of the client, to configure logging and retries
+func (c *Client) HTTP() *httpclient.Client {
+	return c.httpClient
+}
+
 // IsConfigured checks if the client has a valid API key configured
 func (c *Client) IsConfigured() bool {
 	return c.apiKey != ""
 }
 
 // Search performs a multi-search across movies and TV shows on TMDb
-func (c *Client) Search(query string) ([]Proposition, error) {
+func (c *Client) Search(ctx context.Context, query string) ([]Proposition, error) {
 	if c.apiKey == "" {
 		return nil, fmt.Errorf("TMDB API key not configured")
 	}
 
 	encodedQuery := url.QueryEscape(query)
-	searchURL := fmt.Sprintf("%s/search/multi?api_key=%s&query=%s&include_adult=false", BaseURL, c.apiKey, encodedQuery)
+	searchURL := fmt.Sprintf("%s/search/multi?api_key=%s&query=%s&include_adult=false", c.baseURL, c.apiKey, encodedQuery)
 
-	req, err := http.NewRequest("GET", searchURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create search request: %w", err)
 	}
@@ -109,14 +129,73 @@ func (c *Client) resultToProposition(result SearchResult) Proposition {
 }
 
 // GetMovie retrieves detailed information about a movie by TMDb ID
-func (c *Client) GetMovie(movieID int) (*MovieProposition, error) {
+func (c *Client) GetMovie(ctx context.Context, movieID int) (*MovieProposition, error) {
+	movieDetails, err := c.getMovieDetails(ctx, movieID, "")
+	if err != nil {
+		return nil, err
+	}
+	return movieProposition(movieDetails), nil
+}
+
+// metadataAppends are the sub-requests appended to a movie request for its extended metadata
+const metadataAppends = "credits,release_dates,keywords,external_ids"
+
+// GetMovieMetadata retrieves the extended metadata of a movie by TMDb ID, in a single request
+func (c *Client) GetMovieMetadata(ctx context.Context, movieID int) (*MovieMetadata, error) {
+	movieDetails, err := c.getMovieDetails(ctx, movieID, metadataAppends)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &MovieMetadata{
+		MovieProposition: *movieProposition(movieDetails),
+		OriginalTitle:    movieDetails.OriginalTitle,
+		OriginalLanguage: movieDetails.OriginalLanguage,
+		Tagline:          movieDetails.Tagline,
+		Releases:         make(map[string][]ReleaseDate),
+	}
+	if metadata.ImdbID == "" && movieDetails.ExternalIDs != nil {
+		metadata.ImdbID = movieDetails.ExternalIDs.ImdbID
+	}
+	for _, country := range movieDetails.ProductionCountries {
+		metadata.Countries = append(metadata.Countries, country.ISO3166_1)
+	}
+	if credits := movieDetails.Credits; credits != nil {
+		metadata.Cast = credits.Cast
+		for _, crew := range credits.Crew {
+			switch {
+			case crew.Job == "Director":
+				metadata.Directors = append(metadata.Directors, crew.Name)
+			case crew.Department == "Writing" && !slices.Contains(metadata.Writers, crew.Name):
+				metadata.Writers = append(metadata.Writers, crew.Name)
+			}
+		}
+	}
+	if releases := movieDetails.ReleaseDates; releases != nil {
+		for _, country := range releases.Results {
+			metadata.Releases[country.ISO3166_1] = country.ReleaseDates
+		}
+	}
+	if keywords := movieDetails.Keywords; keywords != nil {
+		for _, keyword := range keywords.Keywords {
+			metadata.Keywords = append(metadata.Keywords, keyword.Name)
+		}
+	}
+	return metadata, nil
+}
+
+// getMovieDetails requests a movie by TMDb ID, with the sub-requests of appendTo when not empty
+func (c *Client) getMovieDetails(ctx context.Context, movieID int, appendTo string) (*MovieDetails, error) {
 	if c.apiKey == "" {
 		return nil, fmt.Errorf("TMDB API key not configured")
 	}
 
-	movieURL := fmt.Sprintf("%s/movie/%d?api_key=%s", BaseURL, movieID, c.apiKey)
+	movieURL := fmt.Sprintf("%s/movie/%d?api_key=%s", c.baseURL, movieID, c.apiKey)
+	if appendTo != "" {
+		movieURL += "&append_to_response=" + appendTo
+	}
 
-	req, err := http.NewRequest("GET", movieURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", movieURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create movie request: %w", err)
 	}
@@ -136,7 +215,11 @@ func (c *Client) GetMovie(movieID int) (*MovieProposition, error) {
 	if err := json.NewDecoder(resp.Body).Decode(&movieDetails); err != nil {
 		return nil, fmt.Errorf("failed to decode movie response: %w", err)
 	}
+	return &movieDetails, nil
+}
 
+// movieProposition converts movie details to the proposition shown to the user
+func movieProposition(movieDetails *MovieDetails) *MovieProposition {
 	genres := make([]string, 0, len(movieDetails.Genres))
 	for _, g := range movieDetails.Genres {
 		genres = append(genres, g.Name)
@@ -147,7 +230,7 @@ func (c *Client) GetMovie(movieID int) (*MovieProposition, error) {
 		year = movieDetails.ReleaseDate[:4]
 	}
 
-	return &MovieProposition{
+	movie := &MovieProposition{
 		ID:       movieDetails.ID,
 		Title:    movieDetails.OriginalTitle,
 		Overview: movieDetails.Overview,
@@ -155,18 +238,24 @@ func (c *Client) GetMovie(movieID int) (*MovieProposition, error) {
 		Runtime:  movieDetails.Runtime,
 		Genres:   genres,
 		Source:   "tmdb",
-	}, nil
+		ImdbID:   movieDetails.ImdbID,
+	}
+	if collection := movieDetails.BelongsToCollection; collection != nil {
+		movie.CollectionID = collection.ID
+		movie.CollectionName = collection.Name
+	}
+	return movie
 }
 
 // GetTVShow retrieves detailed information about a TV show by TMDb ID
-func (c *Client) GetTVShow(tvID int) (*SeriesProposition, error) {
+func (c *Client) GetTVShow(ctx context.Context, tvID int) (*SeriesProposition, error) {
 	if c.apiKey == "" {
 		return nil, fmt.Errorf("TMDB API key not configured")
 	}
 
-	tvURL := fmt.Sprintf("%s/tv/%d?api_key=%s", BaseURL, tvID, c.apiKey)
+	tvURL := fmt.Sprintf("%s/tv/%d?api_key=%s&append_to_response=external_ids", c.baseURL, tvID, c.apiKey)
 
-	req, err := http.NewRequest("GET", tvURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", tvURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create tv request: %w", err)
 	}
@@ -206,25 +295,102 @@ func (c *Client) GetTVShow(tvID int) (*SeriesProposition, error) {
 		Status:     tvDetails.Status,
 		Genres:     genres,
 		Source:     "tmdb",
+		ImdbID:     tvDetails.ExternalIDs.ImdbID,
+		TVDBID:     tvDetails.ExternalIDs.TVDBID,
+		Seasons:    tvDetails.Seasons,
 	}, nil
 }
 
 // GetEpisode retrieves information about a specific episode by TV show ID, season, and episode number
-func (c *Client) GetEpisode(tvID, seasonNumber, episodeNumber int) (*EpisodeInfo, error) {
+func (c *Client) GetEpisode(ctx context.Context, tvID, seasonNumber, episodeNumber int) (*EpisodeInfo, error) {
 	if c.apiKey == "" {
 		return nil, fmt.Errorf("TMDB API key not configured")
 	}
 
 	// First get the TV show details for the name
-	tvShow, err := c.GetTVShow(tvID)
+	tvShow, err := c.GetTVShow(ctx, tvID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tv show: %w", err)
+	}
+
+	seasonDetails, err := c.getSeason(ctx, tvID, seasonNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the specific episode
+	for _, ep := range seasonDetails.Episodes {
+		if ep.EpisodeNumber == episodeNumber {
+			return episodeInfo(tvShow, seasonNumber, ep), nil
+		}
+	}
+
+	return nil, fmt.Errorf("episode S%02dE%02d not found", seasonNumber, episodeNumber)
+}
+
+// GetEpisodesByAirDate retrieves the episodes of a TV show that aired on a "YYYY-MM-DD" date, looking
+// in the last season that started by then and, failing that, in the one before
+func (c *Client) GetEpisodesByAirDate(ctx context.Context, tvID int, airDate string) ([]EpisodeInfo, error) {
+	if c.apiKey == "" {
+		return nil, fmt.Errorf("TMDB API key not configured")
+	}
+
+	tvShow, err := c.GetTVShow(ctx, tvID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tv show: %w", err)
 	}
 
-	// Get the season details
-	seasonURL := fmt.Sprintf("%s/tv/%d/season/%d?api_key=%s", BaseURL, tvID, seasonNumber, c.apiKey)
+	var candidates []int
+	for _, season := range tvShow.Seasons {
+		if season.AirDate != "" && season.AirDate <= airDate {
+			candidates = append(candidates, season.SeasonNumber)
+		}
+	}
+	slices.Sort(candidates)
+	slices.Reverse(candidates)
 
-	req, err := http.NewRequest("GET", seasonURL, nil)
+	for _, seasonNumber := range candidates[:min(2, len(candidates))] {
+		seasonDetails, err := c.getSeason(ctx, tvID, seasonNumber)
+		if err != nil {
+			return nil, err
+		}
+		var episodes []EpisodeInfo
+		for _, ep := range seasonDetails.Episodes {
+			if ep.AirDate == airDate {
+				episodes = append(episodes, *episodeInfo(tvShow, seasonNumber, ep))
+			}
+		}
+		if len(episodes) > 0 {
+			return episodes, nil
+		}
+	}
+	return nil, nil
+}
+
+// episodeInfo converts a season episode to the episode details used for renaming
+func episodeInfo(tvShow *SeriesProposition, seasonNumber int, ep Episode) *EpisodeInfo {
+	year := tvShow.Year
+	if ep.AirDate != "" && len(ep.AirDate) >= 4 {
+		year = ep.AirDate[:4]
+	}
+
+	return &EpisodeInfo{
+		SeriesName:    tvShow.Name,
+		SeasonNumber:  seasonNumber,
+		EpisodeNumber: ep.EpisodeNumber,
+		EpisodeName:   ep.Name,
+		Year:          year,
+		AirDate:       ep.AirDate,
+		Runtime:       ep.Runtime,
+		Overview:      ep.Overview,
+	}
+}
+
+// getSeason retrieves the details of a season of a TV show, with its episodes
+func (c *Client) getSeason(ctx context.Context, tvID, seasonNumber int) (*SeasonDetails, error) {
+	seasonURL := fmt.Sprintf("%s/tv/%d/season/%d?api_key=%s", c.baseURL, tvID, seasonNumber, c.apiKey)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", seasonURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create season request: %w", err)
 	}
@@ -244,38 +410,19 @@ func (c *Client) GetEpisode(tvID, seasonNumber, episodeNumber int) (*EpisodeInfo
 	if err := json.NewDecoder(resp.Body).Decode(&seasonDetails); err != nil {
 		return nil, fmt.Errorf("failed to decode season response: %w", err)
 	}
-
-	// Find the specific episode
-	for _, ep := range seasonDetails.Episodes {
-		if ep.EpisodeNumber == episodeNumber {
-			year := tvShow.Year
-			if ep.AirDate != "" && len(ep.AirDate) >= 4 {
-				year = ep.AirDate[:4]
-			}
-
-			return &EpisodeInfo{
-				SeriesName:    tvShow.Name,
-				SeasonNumber:  seasonNumber,
-				EpisodeNumber: episodeNumber,
-				EpisodeName:   ep.Name,
-				Year:          year,
-			}, nil
-		}
-	}
-
-	return nil, fmt.Errorf("episode S%02dE%02d not found", seasonNumber, episodeNumber)
+	return &seasonDetails, nil
 }
 
 // SearchMovie performs a search specifically for movies on TMDb
-func (c *Client) SearchMovie(query string, releaseYear int) ([]Proposition, error) {
+func (c *Client) SearchMovie(ctx context.Context, query string, releaseYear int) ([]Proposition, error) {
 	if c.apiKey == "" {
 		return nil, fmt.Errorf("TMDB API key not configured")
 	}
 
 	encodedQuery := url.QueryEscape(query)
-	searchURL := fmt.Sprintf("%s/search/movie?api_key=%s&query=%s&year=%d&include_adult=false", BaseURL, c.apiKey, encodedQuery, releaseYear)
+	searchURL := fmt.Sprintf("%s/search/movie?api_key=%s&query=%s&year=%d&include_adult=false", c.baseURL, c.apiKey, encodedQuery, releaseYear)
 
-	req, err := http.NewRequest("GET", searchURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create movie search request: %w", err)
 	}
@@ -320,15 +467,15 @@ func (c *Client) SearchMovie(query string, releaseYear int) ([]Proposition, erro
 }
 
 // SearchTV performs a search specifically for TV shows on TMDb
-func (c *Client) SearchTV(query string, releaseYear int) ([]Proposition, error) {
+func (c *Client) SearchTV(ctx context.Context, query string, releaseYear int) ([]Proposition, error) {
 	if c.apiKey == "" {
 		return nil, fmt.Errorf("TMDB API key not configured")
 	}
 
 	encodedQuery := url.QueryEscape(query)
-	searchURL := fmt.Sprintf("%s/search/tv?api_key=%s&query=%s&year=%d&include_adult=false", BaseURL, c.apiKey, encodedQuery, releaseYear)
+	searchURL := fmt.Sprintf("%s/search/tv?api_key=%s&query=%s&year=%d&include_adult=false", c.baseURL, c.apiKey, encodedQuery, releaseYear)
 
-	req, err := http.NewRequest("GET", searchURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create tv search request: %w", err)
 	}
@@ -46,27 +46,108 @@ type MovieDetails struct {
 	VoteCount        int     `json:"vote_count"`
 	ImdbID           string  `json:"imdb_id"`
 	OriginalLanguage string  `json:"original_language"`
+	// BelongsToCollection is the franchise of the movie, nil when it has none
+	BelongsToCollection *Collection `json:"belongs_to_collection"`
+	ProductionCountries []Country   `json:"production_countries"`
+
+	// Appended with append_to_response, nil otherwise
+	Credits      *Credits      `json:"credits"`
+	ReleaseDates *ReleaseDates `json:"release_dates"`
+	Keywords     *Keywords     `json:"keywords"`
+	ExternalIDs  *ExternalIDs  `json:"external_ids"`
+}
+
+// Country is a production country of a movie
+type Country struct {
+	ISO3166_1 string `json:"iso_3166_1"`
+	Name      string `json:"name"`
+}
+
+// Credits lists the cast and crew of a movie, from append_to_response=credits
+type Credits struct {
+	Cast []CastCredit `json:"cast"`
+	Crew []CrewCredit `json:"crew"`
+}
+
+// CastCredit is an actor of a movie and the character they play
+type CastCredit struct {
+	Name      string `json:"name"`
+	Character string `json:"character"`
+	Order     int    `json:"order"`
+}
+
+// CrewCredit is a crew member of a movie, like {Department: "Directing", Job: "Director"}
+type CrewCredit struct {
+	Name       string `json:"name"`
+	Department string `json:"department"`
+	Job        string `json:"job"`
+}
+
+// ReleaseDates lists the releases of a movie by country, from append_to_response=release_dates
+type ReleaseDates struct {
+	Results []CountryReleases `json:"results"`
+}
+
+// CountryReleases are the releases of a movie in one country
+type CountryReleases struct {
+	ISO3166_1    string        `json:"iso_3166_1"`
+	ReleaseDates []ReleaseDate `json:"release_dates"`
+}
+
+// ReleaseDate is one release of a movie; Type is 1 premiere, 2 limited theatrical, 3 theatrical,
+// 4 digital, 5 physical or 6 TV
+type ReleaseDate struct {
+	Certification string `json:"certification"`
+	ReleaseDate   string `json:"release_date"`
+	Type          int    `json:"type"`
+	Note          string `json:"note"`
+}
+
+// Keywords lists the keywords of a movie, from append_to_response=keywords
+type Keywords struct {
+	Keywords []Keyword `json:"keywords"`
+}
+
+// Keyword is a TMDb keyword, like "artificial intelligence"
+type Keyword struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// Collection is a TMDb collection grouping the movies of a franchise
+type Collection struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	PosterPath   string `json:"poster_path"`
+	BackdropPath string `json:"backdrop_path"`
+}
+
+// ExternalIDs holds the IDs of a movie or TV show on other sites, from append_to_response=external_ids
+type ExternalIDs struct {
+	ImdbID string `json:"imdb_id"`
+	TVDBID int    `json:"tvdb_id"`
 }
 
 // TVShowDetails contains comprehensive information about a TV show from TMDb
 type TVShowDetails struct {
-	ID               int      `json:"id"`
-	Name             string   `json:"name"`
-	OriginalName     string   `json:"original_name"`
-	Overview         string   `json:"overview"`
-	FirstAirDate     string   `json:"first_air_date"`
-	LastAirDate      string   `json:"last_air_date"`
-	Status           string   `json:"status"`
-	Type             string   `json:"type"`
-	Genres           []Genre  `json:"genres"`
-	PosterPath       string   `json:"poster_path"`
-	BackdropPath     string   `json:"backdrop_path"`
-	Popularity       float64  `json:"popularity"`
-	VoteAverage      float64  `json:"vote_average"`
-	VoteCount        int      `json:"vote_count"`
-	NumberOfSeasons  int      `json:"number_of_seasons"`
-	NumberOfEpisodes int      `json:"number_of_episodes"`
-	Seasons          []Season `json:"seasons"`
+	ID               int         `json:"id"`
+	Name             string      `json:"name"`
+	OriginalName     string      `json:"original_name"`
+	Overview         string      `json:"overview"`
+	FirstAirDate     string      `json:"first_air_date"`
+	LastAirDate      string      `json:"last_air_date"`
+	Status           string      `json:"status"`
+	Type             string      `json:"type"`
+	Genres           []Genre     `json:"genres"`
+	PosterPath       string      `json:"poster_path"`
+	BackdropPath     string      `json:"backdrop_path"`
+	Popularity       float64     `json:"popularity"`
+	VoteAverage      float64     `json:"vote_average"`
+	VoteCount        int         `json:"vote_count"`
+	NumberOfSeasons  int         `json:"number_of_seasons"`
+	NumberOfEpisodes int         `json:"number_of_episodes"`
+	Seasons          []Season    `json:"seasons"`
+	ExternalIDs      ExternalIDs `json:"external_ids"`
 }
 
 // Season represents a TV show season with basic metadata
@@ -133,6 +214,10 @@ type MovieProposition struct {
 	Runtime  int
 	Genres   []string
 	Source   string
+	ImdbID   string
+	// CollectionID and CollectionName identify the franchise of the movie, 0 and "" when it has none
+	CollectionID   int
+	CollectionName string
 }
 
 // SeriesProposition represents detailed TV series information for user display
@@ -145,6 +230,26 @@ type SeriesProposition struct {
 	Status     string
 	Genres     []string
 	Source     string
+	ImdbID     string
+	TVDBID     int
+	Seasons    []Season
+}
+
+// MovieMetadata is the extended metadata of a movie: credits, release dates by country, keywords
+// and external IDs
+type MovieMetadata struct {
+	MovieProposition
+	OriginalTitle    string
+	OriginalLanguage string
+	Tagline          string
+	// Countries are the ISO 3166-1 codes of the production countries
+	Countries []string
+	Directors []string
+	Writers   []string
+	Cast      []CastCredit
+	Keywords  []string
+	// Releases maps ISO 3166-1 country codes to the releases there
+	Releases map[string][]ReleaseDate
 }
 
 // EpisodeInfo contains specific episode details for renaming purposes
@@ -154,4 +259,7 @@ type EpisodeInfo struct {
 	EpisodeNumber int
 	EpisodeName   string
 	Year          string
+	AirDate       string
+	Runtime       int
+	Overview      string
 }
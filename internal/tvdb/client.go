@@ -2,48 +2,81 @@ package tvdb
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
 	"time"
+
+	"kodi-renamer/internal/httpclient"
 )
 
 const (
 	// BaseURL is the base URL for TheTVDB API v4
 	BaseURL = "https://api4.thetvdb.com/v4"
+	// RequestsPerSecond is the sustained request rate we allow ourselves against TheTVDB
+	RequestsPerSecond = 10
+	// RequestBurst is how many requests may be sent at once before the rate limit applies
+	RequestBurst = 5
 )
 
 // Client represents a TheTVDB API client
 type Client struct {
-	apiKey     string
-	token      string
-	httpClient *http.Client
+	apiKey         string
+	baseURL        string
+	httpClient     *httpclient.Client
+	mu             sync.Mutex
+	loginMu        sync.Mutex // Held while a request logs in, so concurrent requests share one login
+	pin            string
+	token          string
+	tokenExpiry    time.Time
+	tokenCachePath string
 }
 
 // NewClient creates a new TheTVDB API client with the provided API key
 func NewClient(apiKey string) *Client {
+	httpClient := httpclient.New("tvdb", RequestsPerSecond, RequestBurst)
+	httpClient.AddSecret(apiKey)
 	return &Client{
-		apiKey: apiKey,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		apiKey:     apiKey,
+		baseURL:    BaseURL,
+		httpClient: httpClient,
 	}
 }
 
-// Login authenticates with TheTVDB API and stores the authentication token
-func (c *Client) Login() error {
+// SetBaseURL points the client at another TheTVDB-compatible API, such as a local fake server in tests
+func (c *Client) SetBaseURL(baseURL string) {
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+// HTTP returns the shared HTTP layer of the client, to configure logging and retries
+func (c *Client) HTTP() *httpclient.Client {
+	return c.httpClient
+}
+
+// Login authenticates with TheTVDB API and stores the authentication token, caching it on disk when configured
+func (c *Client) Login(ctx context.Context) error {
+	c.mu.Lock()
 	loginData := map[string]string{
 		"apikey": c.apiKey,
 	}
+	if c.pin != "" {
+		loginData["pin"] = c.pin
+	}
+	c.mu.Unlock()
 
 	jsonData, err := json.Marshal(loginData)
 	if err != nil {
 		return fmt.Errorf("failed to marshal login data: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", BaseURL+"/login", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/login", bytes.NewReader(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create login request: %w", err)
 	}
@@ -67,26 +100,82 @@ func (c *Client) Login() error {
 		return fmt.Errorf("failed to decode token response: %w", err)
 	}
 
+	if tokenResp.Data.Token == "" {
+		return fmt.Errorf("login response did not contain a token")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.token = tokenResp.Data.Token
+	c.tokenExpiry = parseTokenExpiry(c.token, time.Now())
+	if err := c.saveCachedToken(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
 	return nil
 }
 
-// Search performs a general search on TheTVDB for the given query
-func (c *Client) Search(query string) ([]Proposition, error) {
-	if c.token == "" {
-		return nil, fmt.Errorf("not authenticated, call Login() first")
+// get sends an authenticated GET request, logging in again and retrying once if the token was rejected
+func (c *Client) get(ctx context.Context, requestURL string) (*http.Response, error) {
+	token := c.currentToken()
+	if token == "" {
+		if err := c.renewToken(ctx, ""); err != nil {
+			return nil, fmt.Errorf("not authenticated: %w", err)
+		}
+		token = c.currentToken()
 	}
 
-	url := fmt.Sprintf("%s/search?query=%s", BaseURL, query)
-	req, err := http.NewRequest("GET", url, nil)
+	resp, err := c.send(ctx, requestURL, token)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	if err := c.renewToken(ctx, token); err != nil {
+		return nil, fmt.Errorf("token rejected and re-authentication failed: %w", err)
+	}
+	return c.send(ctx, requestURL, c.currentToken())
+}
+
+// renewToken logs in to replace a rejected (or missing) token. Requests that hit the same rejected
+// token at once wait for a single login and then reuse the token it obtained.
+func (c *Client) renewToken(ctx context.Context, rejected string) error {
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+
+	if token := c.currentToken(); token != "" && token != rejected {
+		// Another request logged in while we waited
+		return nil
+	}
+	if rejected != "" {
+		c.dropToken(rejected)
+	}
+	return c.Login(ctx)
+}
+
+// currentToken returns the token requests are sent with
+func (c *Client) currentToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// send performs a single GET request with the given bearer token
+func (c *Client) send(ctx context.Context, requestURL, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create search request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	return c.httpClient.Do(req)
+}
+
+// Search performs a general search on TheTVDB for the given query
+func (c *Client) Search(ctx context.Context, query string) ([]Proposition, error) {
+	searchURL := fmt.Sprintf("%s/search?query=%s", c.baseURL, url.QueryEscape(query))
+	resp, err := c.get(ctx, searchURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute search request: %w", err)
 	}
@@ -112,28 +201,33 @@ func (c *Client) Search(query string) ([]Proposition, error) {
 			Year:         item.Year,
 			Type:         item.Type,
 			ImageURL:     item.ImageURL,
+			RemoteIDs:    remoteIDs(item.RemoteIDs),
 		})
 	}
 
 	return propositions, nil
 }
 
-// GetSeries retrieves detailed information about a TV series by ID
-func (c *Client) GetSeries(seriesID string) (*SeriesProposition, error) {
-	if c.token == "" {
-		return nil, fmt.Errorf("not authenticated, call Login() first")
+// remoteIDs keeps the IMDB and TheMovieDB.com IDs of a search result, keyed "imdb" and "tmdb"
+func remoteIDs(ids []RemoteID) map[string]string {
+	out := make(map[string]string)
+	for _, id := range ids {
+		source := strings.ToLower(id.SourceName)
+		switch {
+		case id.ID == "":
+		case strings.Contains(source, "imdb"):
+			out["imdb"] = id.ID
+		case strings.Contains(source, "themoviedb"):
+			out["tmdb"] = id.ID
+		}
 	}
+	return out
+}
 
-	url := fmt.Sprintf("%s/series/%s", BaseURL, seriesID)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create series request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+c.token)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(req)
+// GetSeries retrieves detailed information about a TV series by ID
+func (c *Client) GetSeries(ctx context.Context, seriesID string) (*SeriesProposition, error) {
+	url := fmt.Sprintf("%s/series/%s", c.baseURL, seriesID)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute series request: %w", err)
 	}
@@ -166,21 +260,9 @@ func (c *Client) GetSeries(seriesID string) (*SeriesProposition, error) {
 }
 
 // GetEpisodes retrieves all episodes for a specific season of a series
-func (c *Client) GetEpisodes(seriesID string, season int) ([]Episode, error) {
-	if c.token == "" {
-		return nil, fmt.Errorf("not authenticated, call Login() first")
-	}
-
-	url := fmt.Sprintf("%s/series/%s/episodes/default?season=%d", BaseURL, seriesID, season)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create episodes request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+c.token)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(req)
+func (c *Client) GetEpisodes(ctx context.Context, seriesID string, season int) ([]Episode, error) {
+	url := fmt.Sprintf("%s/series/%s/episodes/default?season=%d", c.baseURL, seriesID, season)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute episodes request: %w", err)
 	}
@@ -199,22 +281,39 @@ func (c *Client) GetEpisodes(seriesID string, season int) ([]Episode, error) {
 	return episodesResp.Data, nil
 }
 
-// GetMovie retrieves detailed information about a movie by ID
-func (c *Client) GetMovie(movieID string) (*MovieProposition, error) {
-	if c.token == "" {
-		return nil, fmt.Errorf("not authenticated, call Login() first")
+// GetEpisodesByAirDate retrieves the episodes of a TV series that aired on a "YYYY-MM-DD" date
+func (c *Client) GetEpisodesByAirDate(ctx context.Context, seriesID, airDate string) ([]Episode, error) {
+	url := fmt.Sprintf("%s/series/%s/episodes/default?airDate=%s", c.baseURL, seriesID, airDate)
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute episodes request: %w", err)
 	}
+	defer resp.Body.Close()
 
-	url := fmt.Sprintf("%s/movies/%s", BaseURL, movieID)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create movie request: %w", err)
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get episodes failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.token)
-	req.Header.Set("Content-Type", "application/json")
+	var episodesResp EpisodesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&episodesResp); err != nil {
+		return nil, fmt.Errorf("failed to decode episodes response: %w", err)
+	}
 
-	resp, err := c.httpClient.Do(req)
+	// Keep only the episodes of that day, should the filter be ignored
+	episodes := make([]Episode, 0, len(episodesResp.Data))
+	for _, ep := range episodesResp.Data {
+		if ep.Aired == airDate {
+			episodes = append(episodes, ep)
+		}
+	}
+	return episodes, nil
+}
+
+// GetMovie retrieves detailed information about a movie by ID
+func (c *Client) GetMovie(ctx context.Context, movieID string) (*MovieProposition, error) {
+	url := fmt.Sprintf("%s/movies/%s", c.baseURL, movieID)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute movie request: %w", err)
 	}
@@ -244,3 +343,77 @@ func (c *Client) GetMovie(movieID string) (*MovieProposition, error) {
 		Genres:   genres,
 	}, nil
 }
+
+// GetMovieMetadata retrieves the extended metadata of a movie by ID
+func (c *Client) GetMovieMetadata(ctx context.Context, movieID string) (*MovieMetadata, error) {
+	url := fmt.Sprintf("%s/movies/%s/extended", c.baseURL, movieID)
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute movie request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get movie metadata failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var movieResp MovieExtendedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&movieResp); err != nil {
+		return nil, fmt.Errorf("failed to decode movie response: %w", err)
+	}
+	data := movieResp.Data
+
+	genres := make([]string, 0, len(data.Genres))
+	for _, g := range data.Genres {
+		genres = append(genres, g.Name)
+	}
+
+	metadata := &MovieMetadata{
+		MovieProposition: MovieProposition{
+			ID:       data.ID,
+			Title:    data.Name,
+			Overview: data.Overview,
+			Year:     data.Year,
+			Runtime:  data.Runtime,
+			Genres:   genres,
+		},
+		OriginalLanguage: data.OriginalLanguage,
+		Certifications:   make(map[string]string),
+		RemoteIDs:        remoteIDs(data.RemoteIDs),
+	}
+	if data.OriginalCountry != "" {
+		metadata.Countries = []string{countryCode(data.OriginalCountry)}
+	}
+
+	characters := slices.Clone(data.Characters)
+	slices.SortStableFunc(characters, func(a, b Character) int { return a.Sort - b.Sort })
+	for _, character := range characters {
+		switch character.PeopleType {
+		case "Director":
+			metadata.Directors = append(metadata.Directors, character.PersonName)
+		case "Writer":
+			metadata.Writers = append(metadata.Writers, character.PersonName)
+		case "Actor", "Guest Star":
+			metadata.Cast = append(metadata.Cast, CastMember{
+				Name:  character.PersonName,
+				Role:  character.Name,
+				Order: len(metadata.Cast),
+			})
+		}
+	}
+	for _, rating := range data.ContentRatings {
+		country := countryCode(rating.Country)
+		if _, ok := metadata.Certifications[country]; !ok && rating.Name != "" {
+			metadata.Certifications[country] = rating.Name
+		}
+	}
+	for _, release := range data.Releases {
+		release.Country = countryCode(release.Country)
+		metadata.Releases = append(metadata.Releases, release)
+	}
+	for _, tag := range data.TagOptions {
+		metadata.Keywords = append(metadata.Keywords, tag.Name)
+	}
+	return metadata, nil
+}
@@ -0,0 +1,23 @@
+package tvdb
+
+import "strings"
+
+// countryCodes maps the ISO 3166-1 alpha-3 codes TheTVDB uses to the alpha-2 codes TMDb uses, for the
+// countries movies most often come from
+var countryCodes = map[string]string{
+	"arg": "AR", "aus": "AU", "aut": "AT", "bel": "BE", "bra": "BR", "can": "CA", "che": "CH",
+	"chn": "CN", "cze": "CZ", "deu": "DE", "dnk": "DK", "esp": "ES", "fin": "FI", "fra": "FR",
+	"gbr": "GB", "grc": "GR", "hkg": "HK", "hun": "HU", "idn": "ID", "ind": "IN", "irl": "IE",
+	"isl": "IS", "isr": "IL", "ita": "IT", "jpn": "JP", "kor": "KR", "mex": "MX", "nld": "NL",
+	"nor": "NO", "nzl": "NZ", "phl": "PH", "pol": "PL", "prt": "PT", "rou": "RO", "rus": "RU",
+	"swe": "SE", "tha": "TH", "tur": "TR", "twn": "TW", "ukr": "UA", "usa": "US", "zaf": "ZA",
+}
+
+// countryCode returns the ISO 3166-1 alpha-2 code of a TheTVDB country, or the upper-cased code
+// itself when it is not in the table
+func countryCode(country string) string {
+	if code, ok := countryCodes[strings.ToLower(country)]; ok {
+		return code
+	}
+	return strings.ToUpper(country)
+}
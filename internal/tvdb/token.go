@@ -0,0 +1,166 @@
+package tvdb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTokenLifetime is how long TheTVDB tokens are assumed to be valid when the token does not say
+	DefaultTokenLifetime = 30 * 24 * time.Hour
+	// tokenRefreshMargin is how long before expiry a cached token is no longer reused
+	tokenRefreshMargin = 24 * time.Hour
+)
+
+// cachedToken is the on-disk form of a TheTVDB token
+type cachedToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// Credentials is a hash of the endpoint, API key and PIN the token was issued for
+	Credentials string `json:"credentials"`
+}
+
+// DefaultTokenCachePath returns the default location of the token cache in the user configuration directory
+func DefaultTokenCachePath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "kodi-renamer", "tvdb-token.json")
+}
+
+// SetPIN sets the subscriber PIN sent with the API key on login, required by user-supported keys
+func (c *Client) SetPIN(pin string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pin = pin
+	c.httpClient.AddSecret(pin)
+}
+
+// SetTokenCache sets the file where the token is kept between runs (empty to disable)
+func (c *Client) SetTokenCache(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokenCachePath = path
+}
+
+// Authenticate reuses a cached token that is still valid for the configured credentials, or logs in
+func (c *Client) Authenticate(ctx context.Context) error {
+	if c.loadCachedToken() {
+		return nil
+	}
+	return c.Login(ctx)
+}
+
+// Authenticated reports whether the client holds a token
+func (c *Client) Authenticated() bool {
+	return c.currentToken() != ""
+}
+
+// TokenExpiry returns when the current token expires, or the zero time without a token
+func (c *Client) TokenExpiry() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokenExpiry
+}
+
+// credentialsHash identifies the endpoint, API key and PIN without storing the secrets in the cache
+func (c *Client) credentialsHash() string {
+	sum := sha256.Sum256([]byte(c.baseURL + "\x00" + c.apiKey + "\x00" + c.pin))
+	return hex.EncodeToString(sum[:])
+}
+
+// loadCachedToken adopts the cached token if it belongs to our credentials and is not about to expire
+func (c *Client) loadCachedToken() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.tokenCachePath == "" {
+		return false
+	}
+	data, err := os.ReadFile(c.tokenCachePath)
+	if err != nil {
+		return false
+	}
+	var cached cachedToken
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return false
+	}
+	if cached.Token == "" || cached.Credentials != c.credentialsHash() {
+		return false
+	}
+	if time.Until(cached.ExpiresAt) < tokenRefreshMargin {
+		return false
+	}
+
+	c.token = cached.Token
+	c.tokenExpiry = cached.ExpiresAt
+	return true
+}
+
+// saveCachedToken writes the current token to the cache file; the caller must hold c.mu
+func (c *Client) saveCachedToken() error {
+	if c.tokenCachePath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(cachedToken{
+		Token:       c.token,
+		ExpiresAt:   c.tokenExpiry,
+		Credentials: c.credentialsHash(),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode token cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.tokenCachePath), 0o700); err != nil {
+		return fmt.Errorf("failed to create token cache directory: %w", err)
+	}
+	tmp := c.tokenCachePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write token cache: %w", err)
+	}
+	if err := os.Rename(tmp, c.tokenCachePath); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace token cache: %w", err)
+	}
+	return nil
+}
+
+// dropToken forgets a token the API rejected, so it is neither reused nor left in the cache
+func (c *Client) dropToken(rejected string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != rejected {
+		// Another request already replaced it
+		return
+	}
+	c.token = ""
+	c.tokenExpiry = time.Time{}
+	if c.tokenCachePath != "" {
+		os.Remove(c.tokenCachePath)
+	}
+}
+
+// parseTokenExpiry reads the exp claim of a JWT, falling back to DefaultTokenLifetime from now
+func parseTokenExpiry(token string, now time.Time) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) == 3 {
+		if payload, err := base64.RawURLEncoding.DecodeString(parts[1]); err == nil {
+			var claims struct {
+				Exp int64 `json:"exp"`
+			}
+			if json.Unmarshal(payload, &claims) == nil && claims.Exp > 0 {
+				return time.Unix(claims.Exp, 0)
+			}
+		}
+	}
+	return now.Add(DefaultTokenLifetime)
+}
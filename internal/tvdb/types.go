@@ -9,14 +9,22 @@ type SearchResult struct {
 
 // SearchItem represents a single search result item from TheTVDB
 type SearchItem struct {
-	ID           string   `json:"tvdb_id"`
-	Name         string   `json:"name"`
-	FirstAired   string   `json:"first_air_time"`
-	Overview     string   `json:"overview"`
-	Type         string   `json:"type"`
-	Year         string   `json:"year"`
-	ImageURL     string   `json:"image_url"`
-	Translations []string `json:"translations"`
+	ID           string     `json:"tvdb_id"`
+	Name         string     `json:"name"`
+	FirstAired   string     `json:"first_air_time"`
+	Overview     string     `json:"overview"`
+	Type         string     `json:"type"`
+	Year         string     `json:"year"`
+	ImageURL     string     `json:"image_url"`
+	Translations []string   `json:"translations"`
+	RemoteIDs    []RemoteID `json:"remote_ids"`
+}
+
+// RemoteID is the ID of a search result on another site, such as IMDB or TheMovieDB.com
+type RemoteID struct {
+	ID         string `json:"id"`
+	Type       int    `json:"type"`
+	SourceName string `json:"sourceName"`
 }
 
 // SeriesResponse represents the response from a TheTVDB series API call
@@ -88,6 +96,50 @@ type MovieData struct {
 	Image        string      `json:"image"`
 }
 
+// MovieExtendedResponse represents the response from a TheTVDB extended movie API call
+type MovieExtendedResponse struct {
+	Data MovieExtendedData `json:"data"`
+}
+
+// MovieExtendedData contains a movie with its people, content ratings, releases and remote IDs
+type MovieExtendedData struct {
+	MovieData
+	OriginalCountry  string          `json:"originalCountry"`
+	OriginalLanguage string          `json:"originalLanguage"`
+	Characters       []Character     `json:"characters"`
+	ContentRatings   []ContentRating `json:"contentRatings"`
+	Releases         []Release       `json:"releases"`
+	RemoteIDs        []RemoteID      `json:"remoteIds"`
+	TagOptions       []TagOption     `json:"tagOptions"`
+}
+
+// Character is a person credited on a movie; PeopleType is "Actor", "Director", "Writer"...
+type Character struct {
+	Name       string `json:"name"`
+	PersonName string `json:"personName"`
+	PeopleType string `json:"peopleType"`
+	Sort       int    `json:"sort"`
+}
+
+// ContentRating is the certification of a movie in a country, like {Name: "R", Country: "usa"}
+type ContentRating struct {
+	Name    string `json:"name"`
+	Country string `json:"country"`
+}
+
+// Release is the release of a movie in a country
+type Release struct {
+	Country string `json:"country"`
+	Date    string `json:"date"`
+	Detail  string `json:"detail"`
+}
+
+// TagOption is a tag of a movie, like {TagName: "Keywords", Name: "dystopia"}
+type TagOption struct {
+	Name    string `json:"name"`
+	TagName string `json:"tagName"`
+}
+
 // Translation contains translated names in different languages
 type Translation struct {
 	Eng string `json:"eng"`
@@ -126,6 +178,8 @@ type Proposition struct {
 	Year         string
 	Type         string
 	ImageURL     string
+	// RemoteIDs maps "imdb" and "tmdb" to the IDs of the result on those sites, when TheTVDB knows them
+	RemoteIDs map[string]string
 }
 
 // SeriesProposition represents detailed TV series information for user display
@@ -149,6 +203,30 @@ type MovieProposition struct {
 	Genres   []string
 }
 
+// MovieMetadata is the extended metadata of a movie: people, certifications, releases by country
+// and remote IDs. Countries are ISO 3166-1 alpha-2 codes when known, TheTVDB's alpha-3 codes otherwise.
+type MovieMetadata struct {
+	MovieProposition
+	OriginalLanguage string
+	Countries        []string
+	Directors        []string
+	Writers          []string
+	Cast             []CastMember
+	Keywords         []string
+	// Certifications maps country codes to the certification there, like "US": "R"
+	Certifications map[string]string
+	Releases       []Release
+	// RemoteIDs maps "imdb" and "tmdb" to the IDs of the movie on those sites
+	RemoteIDs map[string]string
+}
+
+// CastMember is an actor of a movie and the character they play
+type CastMember struct {
+	Name  string
+	Role  string
+	Order int
+}
+
 // EpisodeInfo contains specific episode details for renaming purposes
 type EpisodeInfo struct {
 	SeriesName    string
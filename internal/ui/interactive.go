@@ -3,6 +3,7 @@ package ui
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -24,22 +25,33 @@ const (
 	TITLE_LIST_COMPILATION_TAB_LARGE = 5
 )
 
+// IgnoreForever is returned by the selection tables when the user never wants to be asked about the item again
+const IgnoreForever = -2
+
+// ShowMore is returned by the selection tables when the user asks for the rows left out of the table
+const ShowMore = -3
+
 // MovieOption represents a movie option for selection with detailed information
 type MovieOption struct {
-	Title   string
-	Year    string
-	Runtime int
-	Genres  []string
-	Source  string
+	Title        string
+	Year         string
+	Runtime      int
+	RuntimeMatch bool // True when the runtime matches the duration of the file being renamed
+	Genres       []string
+	Source       string
+	Score        float64 // Ranking score between 0 and 1
+	ScoreDetail  string  // Explanation of the score, shown next to it when set
 }
 
 // SeriesOption represents a TV series option for selection with detailed information
 type SeriesOption struct {
-	Name   string
-	Year   string
-	Status string
-	Genres []string
-	Source string
+	Name        string
+	Year        string
+	Status      string
+	Genres      []string
+	Source      string
+	Score       float64 // Ranking score between 0 and 1
+	ScoreDetail string  // Explanation of the score, shown next to it when set
 }
 
 // Interactive provides interactive user interface functionality for user prompts and selections
@@ -49,13 +61,25 @@ type Interactive struct {
 
 // NewInteractive creates a new Interactive instance for user interaction
 func NewInteractive() *Interactive {
+	return NewInteractiveWithInput(os.Stdin)
+}
+
+// NewInteractiveWithInput creates an Interactive instance reading the answers from input, for scripted runs and tests
+func NewInteractiveWithInput(input io.Reader) *Interactive {
 	return &Interactive{
-		reader: bufio.NewReader(os.Stdin),
+		reader: bufio.NewReader(input),
 	}
 }
 
 // SelectMovieFromList displays a table of movie options and prompts the user to select one, returning -1 if skipped
+// and IgnoreForever if the item must never be presented again
 func (i *Interactive) SelectMovieFromList(title string, movies []MovieOption) (int, error) {
+	return i.SelectMovieFromListWithMore(title, movies, 0)
+}
+
+// SelectMovieFromListWithMore is SelectMovieFromList offering to show more results when more is
+// positive, in which case ShowMore may be returned
+func (i *Interactive) SelectMovieFromListWithMore(title string, movies []MovieOption, more int) (int, error) {
 	if len(movies) == 0 {
 		return -1, fmt.Errorf("no options available")
 	}
@@ -67,7 +91,7 @@ func (i *Interactive) SelectMovieFromList(title string, movies []MovieOption) (i
 	// Calculate column widths
 	maxTitle := 20
 	maxYear := 4
-	maxRuntime := 7
+	maxRuntime := 9
 	maxGenres := 30
 	maxSource := 6
 
@@ -78,19 +102,33 @@ func (i *Interactive) SelectMovieFromList(title string, movies []MovieOption) (i
 		if len(strings.Join(movie.Genres, ", ")) > maxGenres {
 			maxGenres = len(strings.Join(movie.Genres, ", "))
 		}
+		maxSource = max(maxSource, len(movie.Source))
+	}
+
+	showScores := false
+	for _, movie := range movies {
+		showScores = showScores || movie.ScoreDetail != ""
 	}
 
 	// Print header
 	header := fmt.Sprintf("%-3s  %-*s  %-*s  %-*s  %-*s  %-*s",
 		"#", maxTitle, "Title", maxYear, "Year", maxRuntime, "Runtime", maxGenres, "Genres", maxSource, "Source")
+	if showScores {
+		header += scoreHeader()
+	}
 	fmt.Println(header)
 	fmt.Println(strings.Repeat("-", len(header)))
 
 	// Print movie rows
+	hasRuntimeMatch := false
 	for idx, movie := range movies {
 		runtimeStr := "-"
 		if movie.Runtime > 0 {
 			runtimeStr = fmt.Sprintf("%d min", movie.Runtime)
+			if movie.RuntimeMatch {
+				runtimeStr += " *"
+				hasRuntimeMatch = true
+			}
 		}
 		genresStr := "-"
 		if len(movie.Genres) > 0 {
@@ -104,12 +142,26 @@ func (i *Interactive) SelectMovieFromList(title string, movies []MovieOption) (i
 			titleStr = titleStr[:maxTitle-3] + "..."
 		}
 
-		fmt.Printf("%-3d  %-*s  %-*s  %-*s  %-*s  %-*s\n",
+		row := fmt.Sprintf("%-3d  %-*s  %-*s  %-*s  %-*s  %-*s",
 			idx+1, maxTitle, titleStr, maxYear, movie.Year, maxRuntime, runtimeStr, maxGenres, genresStr, maxSource, movie.Source)
+		if showScores {
+			row += scoreColumns(movie.Score, movie.ScoreDetail)
+		}
+		fmt.Println(row)
+	}
+
+	// Print skip options
+	fmt.Printf("%-3d  Skip / None\n", len(movies)+1)
+	fmt.Printf("%-3s  Ignore forever (never ask again)\n", "i")
+	if more > 0 {
+		fmt.Printf("%-3s  Show %d more result(s)\n", "m", more)
 	}
+	fmt.Println()
 
-	// Print skip option
-	fmt.Printf("%-3d  Skip / None\n\n", len(movies)+1)
+	if hasRuntimeMatch {
+		fmt.Println("* runtime matches the file duration")
+		fmt.Println()
+	}
 
 	for {
 		fmt.Print("Select an option (number): ")
@@ -119,6 +171,12 @@ func (i *Interactive) SelectMovieFromList(title string, movies []MovieOption) (i
 		}
 
 		input = strings.TrimSpace(input)
+		if strings.EqualFold(input, "i") {
+			return IgnoreForever, nil
+		}
+		if more > 0 && strings.EqualFold(input, "m") {
+			return ShowMore, nil
+		}
 		choice, err := strconv.Atoi(input)
 		if err != nil {
 			fmt.Println("Invalid input. Please enter a number.")
@@ -139,7 +197,14 @@ func (i *Interactive) SelectMovieFromList(title string, movies []MovieOption) (i
 }
 
 // SelectSeriesFromList displays a table of TV series options and prompts the user to select one, returning -1 if skipped
+// and IgnoreForever if the item must never be presented again
 func (i *Interactive) SelectSeriesFromList(title string, series []SeriesOption) (int, error) {
+	return i.SelectSeriesFromListWithMore(title, series, 0)
+}
+
+// SelectSeriesFromListWithMore is SelectSeriesFromList offering to show more results when more is
+// positive, in which case ShowMore may be returned
+func (i *Interactive) SelectSeriesFromListWithMore(title string, series []SeriesOption, more int) (int, error) {
 	if len(series) == 0 {
 		return -1, fmt.Errorf("no options available")
 	}
@@ -165,11 +230,20 @@ func (i *Interactive) SelectSeriesFromList(title string, series []SeriesOption)
 		if len(strings.Join(s.Genres, ", ")) > maxGenres {
 			maxGenres = len(strings.Join(s.Genres, ", "))
 		}
+		maxSource = max(maxSource, len(s.Source))
+	}
+
+	showScores := false
+	for _, s := range series {
+		showScores = showScores || s.ScoreDetail != ""
 	}
 
 	// Print header
 	header := fmt.Sprintf("%-3s  %-*s  %-*s  %-*s  %-*s  %-*s",
 		"#", maxName, "Name", maxYear, "Year", maxStatus, "Status", maxGenres, "Genres", maxSource, "Source")
+	if showScores {
+		header += scoreHeader()
+	}
 	fmt.Println(header)
 	fmt.Println(strings.Repeat("-", len(header)))
 
@@ -191,12 +265,21 @@ func (i *Interactive) SelectSeriesFromList(title string, series []SeriesOption)
 			nameStr = nameStr[:maxName-3] + "..."
 		}
 
-		fmt.Printf("%-3d  %-*s  %-*s  %-*s  %-*s  %-*s\n",
+		row := fmt.Sprintf("%-3d  %-*s  %-*s  %-*s  %-*s  %-*s",
 			idx+1, maxName, nameStr, maxYear, s.Year, maxStatus, statusStr, maxGenres, genresStr, maxSource, s.Source)
+		if showScores {
+			row += scoreColumns(s.Score, s.ScoreDetail)
+		}
+		fmt.Println(row)
 	}
 
-	// Print skip option
-	fmt.Printf("%-3d  Skip / None\n\n", len(series)+1)
+	// Print skip options
+	fmt.Printf("%-3d  Skip / None\n", len(series)+1)
+	fmt.Printf("%-3s  Ignore forever (never ask again)\n", "i")
+	if more > 0 {
+		fmt.Printf("%-3s  Show %d more result(s)\n", "m", more)
+	}
+	fmt.Println()
 
 	for {
 		fmt.Print("Select an option (number): ")
@@ -206,6 +289,12 @@ func (i *Interactive) SelectSeriesFromList(title string, series []SeriesOption)
 		}
 
 		input = strings.TrimSpace(input)
+		if strings.EqualFold(input, "i") {
+			return IgnoreForever, nil
+		}
+		if more > 0 && strings.EqualFold(input, "m") {
+			return ShowMore, nil
+		}
 		choice, err := strconv.Atoi(input)
 		if err != nil {
 			fmt.Println("Invalid input. Please enter a number.")
@@ -225,6 +314,16 @@ func (i *Interactive) SelectSeriesFromList(title string, series []SeriesOption)
 	}
 }
 
+// scoreHeader returns the headers of the match score columns of the selection tables
+func scoreHeader() string {
+	return fmt.Sprintf("  %-5s  %s", "Match", "Why")
+}
+
+// scoreColumns formats a ranking score as a percentage followed by its explanation
+func scoreColumns(score float64, detail string) string {
+	return fmt.Sprintf("  %-5s  %s", fmt.Sprintf("%.0f%%", score*100), detail)
+}
+
 // SelectFromList displays a list of options and prompts the user to select one, returning -1 if skipped
 func (i *Interactive) SelectFromList(title string, options []string) (int, error) {
 	if len(options) == 0 {
@@ -273,6 +372,73 @@ func (i *Interactive) SelectFromList(title string, options []string) (int, error
 	}
 }
 
+// MediaTypeItem is a scanned item of a mixed inbox with the type it will be processed as
+type MediaTypeItem struct {
+	Name   string
+	Type   string // "movie" or "series"
+	Forced bool   // Type set by a .kodi-type marker
+}
+
+// ReviewMediaTypes lists how the items of a mixed inbox were classified and returns the indices
+// of the items the user wants to switch between movie and series (numbers and ranges such as "2,5-7")
+func (i *Interactive) ReviewMediaTypes(items []MediaTypeItem) []int {
+	fmt.Println("\nDetected media types")
+	fmt.Println(strings.Repeat("=", 20))
+	for idx, item := range items {
+		marker := ""
+		if item.Forced {
+			marker = " (marker)"
+		}
+		fmt.Printf("%3d.  %-7s%-10s %s\n", idx+1, item.Type, marker, item.Name)
+	}
+
+	for {
+		fmt.Print("\nNumbers to switch between movie and series (e.g. 2,5-7), or Enter to continue: ")
+		input, err := i.reader.ReadString('\n')
+		if err != nil {
+			return nil
+		}
+
+		indices, err := parseSelection(strings.TrimSpace(input), len(items))
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		return indices
+	}
+}
+
+// parseSelection parses a list of 1-based numbers and ranges into 0-based indices
+func parseSelection(input string, count int) ([]int, error) {
+	var indices []int
+	seen := make(map[int]bool)
+
+	for _, field := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == ' ' }) {
+		low, high, isRange := strings.Cut(field, "-")
+		first, err := strconv.Atoi(low)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", field)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(high); err != nil {
+				return nil, fmt.Errorf("invalid range %q", field)
+			}
+		}
+		if first < 1 || last > count || first > last {
+			return nil, fmt.Errorf("%q is out of range 1-%d", field, count)
+		}
+
+		for n := first; n <= last; n++ {
+			if !seen[n] {
+				seen[n] = true
+				indices = append(indices, n-1)
+			}
+		}
+	}
+	return indices, nil
+}
+
 // Confirm prompts the user with a yes/no question and returns true if confirmed
 func (i *Interactive) Confirm(message string) bool {
 	fmt.Printf("%s (y/n): ", message)
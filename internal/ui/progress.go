@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// spinnerFrames are drawn in turn while a Progress runs on a terminal
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// Progress shows a spinner and a done/total counter on one line while work runs in the background.
+// On anything but a terminal only the final count is printed.
+type Progress struct {
+	mu       sync.Mutex
+	out      io.Writer
+	message  string
+	terminal bool
+	frame    int
+	done     int
+	total    int
+	stop     chan struct{}
+	stopped  sync.WaitGroup
+}
+
+// StartProgress starts showing a progress line with message, like "Fetching movie details"
+func (i *Interactive) StartProgress(message string, total int) *Progress {
+	p := &Progress{
+		out:      os.Stdout,
+		message:  message,
+		terminal: isTerminal(os.Stdout),
+		total:    total,
+		stop:     make(chan struct{}),
+	}
+	if p.terminal {
+		p.stopped.Add(1)
+		go p.spin()
+	}
+	return p
+}
+
+// Update records the work done so far; it is safe to call from any goroutine
+func (p *Progress) Update(done, total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done, p.total = done, total
+	if p.terminal {
+		p.draw()
+	}
+}
+
+// Stop stops the spinner and leaves the final count on its own line
+func (p *Progress) Stop() {
+	close(p.stop)
+	p.stopped.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.terminal {
+		fmt.Fprint(p.out, "\r\033[K")
+	}
+	fmt.Fprintf(p.out, "%s... %d/%d\n", p.message, p.done, p.total)
+}
+
+// spin redraws the line with the next spinner frame until Stop is called
+func (p *Progress) spin() {
+	defer p.stopped.Done()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			p.frame = (p.frame + 1) % len(spinnerFrames)
+			p.draw()
+			p.mu.Unlock()
+		}
+	}
+}
+
+// draw prints the current state over the previous one; the caller holds mu
+func (p *Progress) draw() {
+	fmt.Fprintf(p.out, "\r\033[K%s %s... %d/%d", spinnerFrames[p.frame], p.message, p.done, p.total)
+}
+
+// isTerminal reports whether f is a character device, as terminals are
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}